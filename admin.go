@@ -0,0 +1,65 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/metrics"
+)
+
+// adminHandler return the handler of the admin port of serve mode, to
+// diagnose goroutine leaks or memory growth of long running instances:
+//
+//	GET /debug/pprof/   profiles, see net/http/pprof
+//	GET /debug/vars     expvar variables, including runtime.MemStats
+//	GET /debug/metrics  runtime/metrics samples by name, as JSON
+func adminHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /debug/metrics", metricsHandler())
+	return mux
+}
+
+// metricsHandler respond the current value of the runtime metrics, such as
+// /sched/goroutines:goroutines, by name. Histograms are left out.
+func metricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var samples []metrics.Sample
+		for _, desc := range metrics.All() {
+			if desc.Kind != metrics.KindFloat64Histogram {
+				samples = append(samples, metrics.Sample{Name: desc.Name})
+			}
+		}
+		metrics.Read(samples)
+		values := make(map[string]any, len(samples))
+		for _, s := range samples {
+			switch s.Value.Kind() {
+			case metrics.KindUint64:
+				values[s.Name] = s.Value.Uint64()
+			case metrics.KindFloat64:
+				values[s.Name] = s.Value.Float64()
+			}
+		}
+		writeJSON(w, http.StatusOK, values)
+	})
+}
+
+// checkLoopback return an error unless the host of addr is localhost or a
+// loopback address, the admin port exposing the internals of the process.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("admin address %s is not a loopback address", addr)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	srv := httptest.NewServer(adminHandler())
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/debug/pprof/":          "goroutine",
+		"/debug/pprof/goroutine": "",
+		"/debug/vars":            `"memstats"`,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("%s: got %d, want %q in the body", path, resp.StatusCode, want)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/debug/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var values map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&values); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := values["/sched/goroutines:goroutines"].(float64); !ok || n < 1 {
+		t.Errorf("goroutines: got %v", values["/sched/goroutines:goroutines"])
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"localhost:6060": true,
+		"127.0.0.1:6060": true,
+		"[::1]:6060":     true,
+		":6060":          false,
+		"0.0.0.0:6060":   false,
+		"10.0.0.1:6060":  false,
+		"localhost":      false,
+	} {
+		if err := checkLoopback(addr); (err == nil) != ok {
+			t.Errorf("%s: got %v", addr, err)
+		}
+	}
+}
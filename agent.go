@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// reportTimeout bound the time spent sending a report to the aggregator.
+const reportTimeout = 30 * time.Second
+
+// agentReport is the body of the reports agents send to the aggregator.
+type agentReport struct {
+	Agent   string       `json:"agent"`
+	Time    time.Time    `json:"time"`
+	Results []jsonResult `json:"results"`
+}
+
+// runAgent implement the agent subcommand, checking the services
+// periodically and reporting the results to an aggregator, an instance in
+// serve mode, until interrupted, and return the exit code.
+//
+// With -queue, reports are written to a directory first and sent in
+// batches, oldest first, so that those the aggregator could not receive are
+// sent again after each run rather than lost, see reportQueue.
+func runAgent(args []string) int {
+	hostname, _ := os.Hostname()
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck agent -aggregator URL [flags] services.txt")
+		fs.PrintDefaults()
+	}
+	aggregator := fs.String("aggregator", "", "base URL of the aggregator, an instance in serve mode")
+	token := fs.String("token", "", "token sent to the aggregator, see serve -agent-token")
+	name := fs.String("name", hostname, "name of the agent, such as its region, in the aggregated view")
+	interval := fs.Duration("interval", DefaultInterval, "time between two checks of the services")
+	spread := fs.Bool("spread", false, "spread the checks of each run over the interval, each service at an offset of its own, rather than starting them all at once")
+	timeout := fs.Duration("timeout", DefaultTimeout, "time allowed for each request, services may override it with timeout=")
+	retries := fs.Int("retries", DefaultRetries, "number of retries of a failed check, services may override it with retries=")
+	workers := fs.Int("workers", DefaultWorkers, "number of concurrent checks")
+	userAgent := fs.String("user-agent", DefaultUserAgent, "User-Agent header sent with checks")
+	varsFile := fs.String("vars-file", "", "YAML or JSON file of the variables the lines of the services file containing {{ are rendered against, a service per combination of their values")
+	sentryDSN := fs.String("sentry-dsn", os.Getenv("SENTRY_DSN"), "Sentry DSN internal errors are reported to, such as panics, invalid services files and failed reports; defaults to SENTRY_DSN")
+	queueDir := fs.String("queue", "", "directory reports are queued in until the aggregator receives them, disabled when empty")
+	queueSize := fs.Int("queue-size", DefaultQueueSize, "number of queued reports beyond which the oldest are dropped")
+	blackbox := fs.String("blackbox-config", "", "blackbox_exporter configuration file whose http modules services may use with module=")
+	var vault vaultOptions
+	vault.register(fs)
+	location := time.UTC
+	fs.Func("timezone", "time zone of the start of checks in the results, such as Europe/Paris or Local (default UTC)", func(s string) (err error) {
+		location, err = time.LoadLocation(s)
+		return err
+	})
+	var tags []string
+	fs.Func("tags", "comma separated list of tags, only services with one of them are checked", func(s string) error {
+		tags = append(tags, strings.Split(s, ",")...)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	switch {
+	case fs.NArg() < 1:
+		fmt.Fprintln(os.Stderr, "missing file argument")
+		return exitError
+	case *aggregator == "":
+		fmt.Fprintln(os.Stderr, "missing -aggregator")
+		return exitError
+	case *name == "":
+		fmt.Fprintln(os.Stderr, "missing -name")
+		return exitError
+	case *interval <= 0:
+		fmt.Fprintln(os.Stderr, "interval must be positive")
+		return exitError
+	case *queueSize < 1:
+		fmt.Fprintln(os.Stderr, "queue-size must be at least 1")
+		return exitError
+	}
+
+	if err := setupSentry(*sentryDSN); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	services, err := readServices(fs.Arg(0), DefaultMaxLineLength, *varsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	services = filterByTags(services, tags)
+	if err := prepareServices(context.Background(), services, *blackbox, vault.client()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	// Every report holds the results of every service, each replacing the
+	// previous one of the agent in the aggregated view.
+	for _, svc := range services {
+		if svc.Interval != 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s: interval= is not supported by agents, which check every service every -interval\n", fs.Arg(0), svc.key())
+			return exitError
+		}
+	}
+
+	report := func(ctx context.Context, results []Result, at time.Time) error {
+		return pushReport(ctx, *aggregator, *token, *name, results, at)
+	}
+	if *queueDir != "" {
+		q, err := openReportQueue(*queueDir, *queueSize)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		report = func(ctx context.Context, results []Result, at time.Time) error {
+			body, err := json.Marshal(newAgentReport(*name, results, at))
+			if err != nil {
+				return err
+			}
+			if dropped, err := q.push(body); err != nil {
+				return err
+			} else if dropped > 0 {
+				fmt.Fprintf(os.Stderr, "%s: %d reports dropped, the queue being full\n", *queueDir, dropped)
+			}
+			return q.flush(func(batch []byte) error {
+				return postReport(ctx, *aggregator, *token, batch)
+			})
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := []Option{WithTimeout(*timeout), WithRetries(*retries), WithWorkers(*workers), WithUserAgent(*userAgent), WithTimezone(location)}
+	if *spread {
+		opts = append(opts, WithSpread(spreadWindow(*interval)))
+	}
+	c := newChecker(opts...)
+	c.agent(ctx, services, *interval, report)
+	return exitOK
+}
+
+// agent check services every interval until ctx is done and report the
+// results of every run. Failed reports are logged and not retried by agent,
+// the next run reporting fresh results, unless report queues them.
+func (c *checker) agent(ctx context.Context, services []Service, interval time.Duration, report func(context.Context, []Result, time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		results := c.healthCheck(ctx, services, nil)
+		// Runs interrupted by ctx are not reported, their services not
+		// being down.
+		if ctx.Err() != nil {
+			return
+		}
+		if err := report(ctx, results, c.now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "aggregator"})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newAgentReport return the report of the results of agent checked at.
+func newAgentReport(agent string, results []Result, at time.Time) agentReport {
+	report := agentReport{Agent: agent, Time: at, Results: make([]jsonResult, len(results))}
+	for i, res := range results {
+		report.Results[i] = newJSONResult(res)
+	}
+	return report
+}
+
+// pushReport send the results of agent checked at to the aggregator at
+// baseURL. The token, when not empty, is sent as a bearer token.
+func pushReport(ctx context.Context, baseURL, token, agent string, results []Result, at time.Time) error {
+	body, err := json.Marshal(newAgentReport(agent, results, at))
+	if err != nil {
+		return err
+	}
+	return postReport(ctx, baseURL, token, body)
+}
+
+// postReport send body, a JSON report or array of reports, to the
+// aggregator at baseURL.
+func postReport(ctx context.Context, baseURL, token string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, reportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/report", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge {
+			return &rejectedReportError{Status: resp.Status, Msg: string(bytes.TrimSpace(msg))}
+		}
+		return fmt.Errorf("report: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// rejectedReportError report reports the aggregator refused as invalid or
+// too large, which sending again would not change, unlike failures of the
+// network, the token or the aggregator itself.
+type rejectedReportError struct {
+	Status string
+	Msg    string
+}
+
+func (e *rejectedReportError) Error() string {
+	return fmt.Sprintf("report: %s: %s", e.Status, e.Msg)
+}
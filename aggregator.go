@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/exp/slices"
+)
+
+// maxReportBody is the maximum size of an agent report.
+const maxReportBody = 16 << 20
+
+// aggregator keep the last report of each agent, and its recent reports
+// in the order of their time.
+type aggregator struct {
+	limit int
+
+	mu      sync.RWMutex
+	reports map[string]agentReport
+	history map[string][]agentReport
+}
+
+// newAggregator return an aggregator keeping limit reports per agent.
+func newAggregator(limit int) *aggregator {
+	return &aggregator{
+		limit:   max(limit, 1),
+		reports: make(map[string]agentReport),
+		history: make(map[string][]agentReport),
+	}
+}
+
+// add record report in the history of its agent, in the order of their
+// time as agents send queued reports after fresher ones failed, dropping
+// the oldest beyond the limit. It becomes the last report of its agent
+// unless a more recent one was received.
+func (a *aggregator) add(report agentReport) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := a.history[report.Agent]
+	i := len(h)
+	for i > 0 && h[i-1].Time.After(report.Time) {
+		i--
+	}
+	h = slices.Insert(h, i, report)
+	if len(h) > a.limit {
+		h = append(h[:0], h[len(h)-a.limit:]...)
+	}
+	a.history[report.Agent] = h
+	if last, ok := a.reports[report.Agent]; ok && last.Time.After(report.Time) {
+		return
+	}
+	a.reports[report.Agent] = report
+}
+
+// agentHistory return the recorded reports of agent, oldest first, or nil
+// when it never reported.
+func (a *aggregator) agentHistory(agent string) []agentReport {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.history[agent])
+}
+
+// regionalService is the view of a service from every agent.
+type regionalService struct {
+	Key     string                `json:"key"`
+	Up      int                   `json:"up"`
+	Down    int                   `json:"down"`
+	Regions map[string]jsonResult `json:"regions"`
+}
+
+// merge return the view of each service from every agent reporting it,
+// sorted by key. The result of each agent holds the time of its report.
+func (a *aggregator) merge() []regionalService {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	byKey := make(map[string]*regionalService)
+	var keys []string
+	for agent, report := range a.reports {
+		for _, res := range report.Results {
+			key := res.Name
+			if key == "" {
+				key = res.URL
+			}
+			rs, ok := byKey[key]
+			if !ok {
+				rs = &regionalService{Key: key, Regions: make(map[string]jsonResult)}
+				byKey[key] = rs
+				keys = append(keys, key)
+			}
+			at := report.Time
+			res.Time = &at
+			rs.Regions[agent] = res
+			switch res.State {
+			case "up":
+				rs.Up++
+			case "down", "dependency":
+				rs.Down++
+			}
+		}
+	}
+	slices.Sort(keys)
+	merged := make([]regionalService, len(keys))
+	for i, key := range keys {
+		merged[i] = *byKey[key]
+	}
+	return merged
+}
+
+// reportHandler record the reports of agents, a report or an array of
+// reports per request. When token is not empty, agents must send it as a
+// bearer token.
+func reportHandler(a *aggregator, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !authorized(r, token) {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		var body json.RawMessage
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReportBody)).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid report: %w", err))
+			return
+		}
+		var reports []agentReport
+		if bytes.HasPrefix(body, []byte("[")) {
+			if err := json.Unmarshal(body, &reports); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid reports: %w", err))
+				return
+			}
+		} else {
+			var report agentReport
+			if err := json.Unmarshal(body, &report); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid report: %w", err))
+				return
+			}
+			reports = append(reports, report)
+		}
+		for _, report := range reports {
+			if report.Agent == "" {
+				writeJSONError(w, http.StatusBadRequest, errors.New("report without agent"))
+				return
+			}
+		}
+		for _, report := range reports {
+			a.add(report)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// agentReportsHandler respond the recorded reports of the agent named by
+// the agent path value, oldest first.
+func agentReportsHandler(a *aggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent := r.PathValue("agent")
+		reports := a.agentHistory(agent)
+		if reports == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("unknown agent %q", agent))
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Reports []agentReport `json:"reports"`
+		}{reports})
+	})
+}
+
+// regionsHandler respond the view of every service from every agent.
+func regionsHandler(a *aggregator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, struct {
+			Services []regionalService `json:"services"`
+		}{a.merge()})
+	})
+}
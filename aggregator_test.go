@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	s := newServer(newChecker(), 10)
+	s.agentToken = "secret"
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	err := pushReport(ctx, srv.URL+"/", "secret", "eu", []Result{
+		{Url: "https://a.com", Status: 200, Latency: 20 * time.Millisecond},
+		{Name: "api", Url: "https://api.a.com", Status: 200, Latency: 30 * time.Millisecond},
+	}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pushReport(ctx, srv.URL, "secret", "us", []Result{
+		{Url: "https://a.com", Err: errors.New("timeout")},
+	}, at.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pushReport(ctx, srv.URL, "wrong", "asia", nil, at); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("want a 401 error with a wrong token; got %v", err)
+	}
+
+	resp, err := http.Get(srv.URL + "/regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got struct{ Services []regionalService }
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Services) != 2 || got.Services[0].Key != "api" || got.Services[1].Key != "https://a.com" {
+		t.Fatalf("want api and https://a.com; got %+v", got.Services)
+	}
+	a := got.Services[1]
+	if a.Up != 1 || a.Down != 1 || len(a.Regions) != 2 {
+		t.Errorf("want a.com up from 1 region and down from 1; got %+v", a)
+	}
+	if us := a.Regions["us"]; us.State != "down" || us.Error != "timeout" || !us.Time.Equal(at.Add(time.Second)) {
+		t.Errorf("want a.com down from us at the time of its report; got %+v", us)
+	}
+	if eu := a.Regions["eu"]; eu.LatencyMS != 20 {
+		t.Errorf("want a 20ms latency from eu; got %+v", eu)
+	}
+}
+
+func TestAgent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var reports int
+	newChecker().agent(ctx, []Service{{URL: srv.URL}}, time.Millisecond, func(_ context.Context, results []Result, _ time.Time) error {
+		if len(results) != 1 || !results[0].Up() {
+			t.Errorf("want the service up; got %+v", results)
+		}
+		if reports++; reports == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if reports < 2 {
+		t.Errorf("want a report per run; got %d", reports)
+	}
+}
+
+func TestReportAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		open   bool
+		status int
+	}{
+		{"disabled", "", false, http.StatusNotFound},
+		{"open", "", true, http.StatusBadRequest},
+		{"token", "secret", false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(newChecker(), 10)
+			s.agentToken, s.openReports = tt.token, tt.open
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/report", strings.NewReader("{}")))
+			if rec.Code != tt.status {
+				t.Errorf("want %d; got %d %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// WithAdaptiveConcurrency make the number of concurrent checks adapt to
+// the health of the checked services: it is halved when a check times out
+// or gets a 5xx response, and grows back by one for as many healthy checks
+// as run concurrently, up to the number of workers. The checker then eases
+// off services that are struggling rather than adding to their load.
+func WithAdaptiveConcurrency(enabled bool) Option {
+	return func(c *checker) { c.adaptive = enabled }
+}
+
+// aimd limit the number of concurrent checks with additive increase and
+// multiplicative decrease, like TCP congestion control.
+type aimd struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	max      float64
+	inflight int
+	// epoch counts the decreases, so that the checks started before one
+	// don't cause another: a burst of failures halves the limit once.
+	epoch int
+}
+
+func newAIMD(max int) *aimd {
+	a := &aimd{limit: float64(max), max: float64(max)}
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// acquire wait until a check may start and return the epoch it starts in.
+func (a *aimd) acquire() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for a.inflight >= int(a.limit) {
+		a.cond.Wait()
+	}
+	a.inflight++
+	return a.epoch
+}
+
+// release end a check started in epoch, adapting the limit to whether it
+// was overloaded.
+func (a *aimd) release(epoch int, overloaded bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight--
+	switch {
+	case !overloaded:
+		a.limit = min(a.max, a.limit+1/a.limit)
+	case epoch == a.epoch:
+		a.limit = max(1, a.limit/2)
+		a.epoch++
+	}
+	a.cond.Broadcast()
+}
+
+// current return the current limit.
+func (a *aimd) current() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+// overloaded report whether res is that of a service showing overload: a
+// timeout or a 5xx response.
+func overloaded(res Result) bool {
+	if res.Status >= http.StatusInternalServerError {
+		return true
+	}
+	var netErr net.Error
+	return errors.Is(res.Err, context.DeadlineExceeded) || errors.As(res.Err, &netErr) && netErr.Timeout()
+}
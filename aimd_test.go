@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+	a := newAIMD(8)
+	var epochs []int
+	for range 8 {
+		epochs = append(epochs, a.acquire())
+	}
+	// A burst of failures of checks started together halves the limit once.
+	for _, epoch := range epochs[:4] {
+		a.release(epoch, true)
+	}
+	if got := a.current(); got != 4 {
+		t.Errorf("after a burst of failures: got limit %d, want 4", got)
+	}
+	for _, epoch := range epochs[4:] {
+		a.release(epoch, false)
+	}
+	for range 2 {
+		a.release(a.acquire(), true)
+	}
+	if got := a.current(); got != 1 {
+		t.Errorf("after failures of later checks: got limit %d, want 1", got)
+	}
+	for range 100 {
+		a.release(a.acquire(), false)
+	}
+	if got := a.current(); got != 8 {
+		t.Errorf("after recovery: got limit %d, want 8", got)
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var inflight int
+	var concurrency []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inflight++
+		concurrency = append(concurrency, inflight)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	services := make([]Service, 64)
+	for i := range services {
+		services[i] = Service{URL: fmt.Sprintf("%s/%d", srv.URL, i)}
+	}
+	for _, adaptive := range []bool{false, true} {
+		concurrency = nil
+		c := newChecker(WithWorkers(8), WithRetries(0), WithAdaptiveConcurrency(adaptive))
+		c.healthCheck(context.Background(), services, nil)
+		most := 0
+		last := concurrency[len(concurrency)-16:]
+		for _, n := range last {
+			most = max(most, n)
+		}
+		if adaptive && most > 1 {
+			t.Errorf("adaptive: want a single check at once after failures; got %v", last)
+		}
+		if !adaptive && most < 2 {
+			t.Errorf("fixed: want concurrent checks; got %v", last)
+		}
+	}
+}
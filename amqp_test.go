@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAMQP serve on l the handshake of AMQP 0-9-1 clients, with queues as
+// the only queues.
+func fakeAMQP(l net.Listener, queues ...string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			send := func(channel uint16, class, method uint16, args ...any) {
+				var payload bytes.Buffer
+				binary.Write(&payload, binary.BigEndian, []uint16{class, method})
+				for _, arg := range args {
+					if s, ok := arg.(string); ok {
+						payload.WriteByte(byte(len(s)))
+						payload.WriteString(s)
+						continue
+					}
+					binary.Write(&payload, binary.BigEndian, arg)
+				}
+				frame := []byte{1}
+				frame = binary.BigEndian.AppendUint16(frame, channel)
+				frame = binary.BigEndian.AppendUint32(frame, uint32(payload.Len()))
+				conn.Write(append(append(frame, payload.Bytes()...), 0xce))
+			}
+			header := make([]byte, 8)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			// Start: version 0-9, no server properties, PLAIN, en_US.
+			send(0, 10, 10, uint8(0), uint8(9), uint32(0), uint32(5), []byte("PLAIN"), uint32(5), []byte("en_US"))
+			for {
+				head := make([]byte, 7)
+				if _, err := io.ReadFull(conn, head); err != nil {
+					return
+				}
+				payload := make([]byte, binary.BigEndian.Uint32(head[3:])+1)
+				if _, err := io.ReadFull(conn, payload); err != nil || head[0] != 1 {
+					return
+				}
+				channel := binary.BigEndian.Uint16(head[1:])
+				switch class, method := binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:]); {
+				case class == 10 && method == 11: // Start-Ok
+					send(0, 10, 30, uint16(2047), uint32(131072), uint16(0))
+				case class == 10 && method == 40: // Open
+					send(0, 10, 41, "")
+				case class == 10 && method == 50: // Close
+					send(0, 10, 51)
+					return
+				case class == 20 && method == 10: // Channel.Open
+					send(channel, 20, 11, uint32(0))
+				case class == 20 && method == 40: // Channel.Close
+					send(channel, 20, 41)
+				case class == 50 && method == 10: // Queue.Declare
+					name := string(payload[7 : 7+payload[6]])
+					found := false
+					for _, q := range queues {
+						found = found || q == name
+					}
+					if !found {
+						send(channel, 20, 40, uint16(404), "NOT_FOUND - no queue '"+name+"'", uint16(50), uint16(10))
+						continue
+					}
+					send(channel, 50, 11, name, uint32(0), uint32(0))
+				}
+			}
+		}()
+	}
+}
+
+func TestCheckAMQP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeAMQP(l, "orders")
+
+	c := newChecker(WithRetries(0))
+	base := "amqp://guest:guest@" + l.Addr().String() + "/"
+	for _, query := range []string{"", "?queue=orders"} {
+		if res := c.check(context.Background(), Service{URL: base + query}); !res.Up() {
+			t.Errorf("%q: want up; got %v", query, res.Err)
+		}
+	}
+	res := c.check(context.Background(), Service{URL: base + "?queue=payments"})
+	var aerr *amqp.Error
+	if !errors.As(res.Err, &aerr) || aerr.Code != amqp.NotFound {
+		t.Errorf("missing queue: want a not found error; got %v", res.Err)
+	}
+}
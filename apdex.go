@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// apdex count results by Apdex zone for the target threshold T: satisfied
+// up to T, tolerating up to 4T, frustrated beyond or when failed. See
+// https://www.apdex.org.
+type apdex struct {
+	T          time.Duration
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+}
+
+func (a *apdex) add(res Result) {
+	switch {
+	case res.Maintenance:
+	case !res.Up() || res.Latency > 4*a.T:
+		a.Frustrated++
+	case res.Latency > a.T:
+		a.Tolerating++
+	default:
+		a.Satisfied++
+	}
+}
+
+// Score is the Apdex score, from 0 when all are frustrated to 1 when all
+// are satisfied, and 1 without results.
+func (a *apdex) Score() float64 {
+	n := a.Satisfied + a.Tolerating + a.Frustrated
+	if n == 0 {
+		return 1
+	}
+	return (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(n)
+}
+
+func (a *apdex) String() string {
+	return fmt.Sprintf("Apdex(%s): %.2f", a.T, a.Score())
+}
+
+func (a *apdex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		TMS        float64 `json:"t_ms"`
+		Score      float64 `json:"score"`
+		Satisfied  int     `json:"satisfied"`
+		Tolerating int     `json:"tolerating"`
+		Frustrated int     `json:"frustrated"`
+	}{millis(a.T), a.Score(), a.Satisfied, a.Tolerating, a.Frustrated})
+}
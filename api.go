@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Limits of the check requests of the API.
+const (
+	maxCheckBody     = 1 << 20
+	maxCheckServices = 1000
+)
+
+// checkRequest is the body of POST /check: services as lines of a
+// services file, a plain url being the simplest.
+type checkRequest struct {
+	Services []string `json:"services"`
+}
+
+// statusResponse is the body of GET /status.
+type statusResponse struct {
+	Updated *time.Time `json:"updated,omitempty"`
+	Summary tally      `json:"summary"`
+	Results []record   `json:"results"`
+}
+
+// apiOptions are the options of services refused from clients of the API,
+// as they would send the credentials of the server, route its requests or
+// read its files on their behalf.
+var apiOptions = map[string]bool{
+	"header":   true,
+	"auth":     true,
+	"audience": true,
+	"proxy":    true,
+	"module":   true,
+	"scenario": true,
+}
+
+// parseAPIService parse a line of a services file sent by a client of the
+// API. Clients are not trusted: ${VAR} is not expanded, the options of
+// apiOptions are refused and only http and https urls are checked.
+func parseAPIService(line string) (Service, error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return Service{}, err
+	}
+	for _, field := range fields {
+		if key, _, ok := cutOption(field); ok && apiOptions[key] {
+			return Service{}, fmt.Errorf("option %s is not allowed", key)
+		}
+		if envVarPattern.MatchString(field) {
+			return Service{}, fmt.Errorf("environment variables are not allowed")
+		}
+	}
+	svc, err := parseFields(fields, "")
+	if err != nil {
+		return Service{}, err
+	}
+	for _, raw := range append([]string{svc.URL}, svc.Members...) {
+		if u, err := url.Parse(raw); raw != "" && (err != nil || u.Scheme != "http" && u.Scheme != "https") {
+			return Service{}, fmt.Errorf("only http and https urls are allowed, got %q", raw)
+		}
+	}
+	return svc, nil
+}
+
+// authorized report whether r carries token as a bearer token.
+func authorized(r *http.Request, token string) bool {
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) == 1
+}
+
+// ServiceError report an invalid service of a request to the API, by its
+// index in the services of the request.
+type ServiceError struct {
+	Index int
+	Err   error
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("services[%d]: %s", e.Index, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// checkHandler check the services of the request and respond their
+// results, see parseAPIService. Requests must carry token as a bearer
+// token, unless it is empty.
+func checkHandler(c *checker, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !authorized(r, token) {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		var req checkRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCheckBody)).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+		if len(req.Services) == 0 || len(req.Services) > maxCheckServices {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("want 1 to %d services, got %d", maxCheckServices, len(req.Services)))
+			return
+		}
+		services := make([]Service, len(req.Services))
+		for i, line := range req.Services {
+			svc, err := parseAPIService(line)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, &ServiceError{Index: i, Err: err})
+				return
+			}
+			services[i] = svc
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Results []Result `json:"results"`
+		}{c.healthCheck(r.Context(), services, nil)})
+	})
+}
+
+// statusHandler respond the last result of every service in st.
+func statusHandler(st *store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latest, updated := st.latest()
+		resp := statusResponse{Results: latest}
+		if !updated.IsZero() {
+			resp.Updated = &updated
+		}
+		for _, rec := range latest {
+			resp.Summary.add(rec.Result)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// historyHandler respond the records of the service named by the key path
+// value, its name or its path escaped url.
+func historyHandler(st *store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		records := st.records(key)
+		if records == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Errorf("unknown service %q", key))
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			Results []record `json:"results"`
+		}{records})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	var code ErrorCode
+	if coded := CodedError(nil); errors.As(err, &coded) {
+		code = coded.ErrorCode()
+	}
+	writeJSON(w, status, struct {
+		Error     string    `json:"error"`
+		ErrorCode ErrorCode `json:"error_code,omitempty"`
+	}{err.Error(), code})
+}
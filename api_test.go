@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	c := newChecker()
+	c.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	s := newServer(c, 10)
+	s.apiToken = "secret"
+	mux := s.handler()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"ok", `{"services": ["` + srv.URL + ` #api", "name=down ` + closed.URL + `"]}`, http.StatusOK,
+			`{"results":[{"start":"2026-01-01T00:00:00Z","url":"` + srv.URL + `","tags":["api"],"state":"up","status":200,`},
+		{"invalid json", `{"services": `, http.StatusBadRequest, `{"error":"invalid request: unexpected EOF"}`},
+		{"empty", `{"services": []}`, http.StatusBadRequest, `{"error":"want 1 to 1000 services, got 0"}`},
+		{"invalid service", `{"services": ["gopher://a.com"]}`, http.StatusBadRequest, `{"error":"services[0]: invalid url`},
+		{"scenario", `{"services": ["name=s scenario=/etc/passwd"]}`, http.StatusBadRequest, `{"error":"services[0]: option scenario is not allowed","error_code":"HC002"}`},
+		{"header", `{"services": ["https://a.com header=Authorization:x"]}`, http.StatusBadRequest, `{"error":"services[0]: option header is not allowed","error_code":"HC002"}`},
+		{"auth", `{"services": ["https://a.com auth=gcp-id-token"]}`, http.StatusBadRequest, `{"error":"services[0]: option auth is not allowed","error_code":"HC002"}`},
+		{"audience", `{"services": ["https://a.com audience=https://b.a.com"]}`, http.StatusBadRequest, `{"error":"services[0]: option audience is not allowed","error_code":"HC002"}`},
+		{"proxy", `{"services": ["https://a.com proxy=http://p.a.com"]}`, http.StatusBadRequest, `{"error":"services[0]: option proxy is not allowed","error_code":"HC002"}`},
+		{"env", `{"services": ["https://a.com/${HOME}"]}`, http.StatusBadRequest, `{"error":"services[0]: environment variables are not allowed","error_code":"HC002"}`},
+		{"protocol", `{"services": ["redis://a.com:6379"]}`, http.StatusBadRequest, `{"error":"services[0]: only http and https urls are allowed, got \"redis://a.com:6379\"","error_code":"HC002"}`},
+		{"member", `{"services": ["name=c member=postgres://a.com/db"]}`, http.StatusBadRequest, `{"error":"services[0]: `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.status || !strings.HasPrefix(rec.Body.String(), tt.want) {
+				t.Errorf("want %d %s...; got %d %s", tt.status, tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(`{"services": ["name=down `+closed.URL+`"]}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	mux.ServeHTTP(rec, req)
+	var resp struct{ Results []map[string]any }
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if r := resp.Results[0]; r["name"] != "down" || r["state"] != "down" || r["error"] == nil {
+		t.Errorf("want down with an error; got %v", r)
+	}
+}
+
+func TestCheckAPIAccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	body := `{"services": ["` + srv.URL + `"]}`
+	tests := []struct {
+		name          string
+		token, bearer string
+		open          bool
+		status        int
+	}{
+		{"disabled", "", "", false, http.StatusNotFound},
+		{"open", "", "", true, http.StatusOK},
+		{"token", "secret", "secret", false, http.StatusOK},
+		{"missing token", "secret", "", true, http.StatusUnauthorized},
+		{"wrong token", "secret", "guess", false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(newChecker(), 10)
+			s.apiToken, s.openAPI = tt.token, tt.open
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body))
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			s.handler().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("want %d; got %d %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestStatusAndHistoryAPI(t *testing.T) {
+	s := newServer(newChecker(), 10)
+	st, mux := s.store, s.handler()
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	if code, body := get("/status"); code != http.StatusOK || body != `{"summary":{"up":0,"down":0,"dependency_down":0,"maintenance":0},"results":[]}` {
+		t.Errorf("want an empty status; got %d %s", code, body)
+	}
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.add([]Result{
+		{Url: "https://a.com/x", Status: 200, Latency: 1500 * time.Microsecond},
+		{Name: "db", Url: "https://db.a.com", Err: errors.New("refused")},
+	}, t0)
+	st.add([]Result{
+		{Url: "https://a.com/x", Status: 200, Latency: 2 * time.Millisecond},
+		{Name: "db", Url: "https://db.a.com", Err: errors.New("refused")},
+	}, t0.Add(time.Minute))
+
+	code, body := get("/status")
+	want := `{"updated":"2026-01-01T00:01:00Z","summary":{"up":1,"down":1,"dependency_down":0,"maintenance":0,"health_score":50},"results":[` +
+		`{"time":"2026-01-01T00:01:00Z","url":"https://a.com/x","state":"up","status":200,"latency_ms":2},` +
+		`{"time":"2026-01-01T00:01:00Z","name":"db","url":"https://db.a.com","state":"down","error":"refused","error_code":"HC900"}]}`
+	if code != http.StatusOK || body != want {
+		t.Errorf("want:\n%s\ngot %d:\n%s", want, code, body)
+	}
+
+	code, body = get("/history/" + url.PathEscape("https://a.com/x"))
+	want = `{"results":[` +
+		`{"time":"2026-01-01T00:00:00Z","url":"https://a.com/x","state":"up","status":200,"latency_ms":1.5},` +
+		`{"time":"2026-01-01T00:01:00Z","url":"https://a.com/x","state":"up","status":200,"latency_ms":2}]}`
+	if code != http.StatusOK || body != want {
+		t.Errorf("want:\n%s\ngot %d:\n%s", want, code, body)
+	}
+	if code, _ := get("/history/db"); code != http.StatusOK {
+		t.Errorf("want the history of db by name; got %d", code)
+	}
+	if code, _ := get("/history/nope"); code != http.StatusNotFound {
+		t.Errorf("want 404 for an unknown service; got %d", code)
+	}
+}
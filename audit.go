@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// minHSTSAge is the lowest Strict-Transport-Security max-age not reported
+// as weak: 180 days, as required by most audits.
+const minHSTSAge = 180 * 24 * 60 * 60
+
+// WithSecurityAudit report, with the results of the checks, the security
+// headers their responses miss or set weakly, see securityAudit.
+func WithSecurityAudit(enabled bool) Option {
+	return func(c *checker) { c.securityAudit = enabled }
+}
+
+// securityAudit return the findings about the security headers of resp:
+// Strict-Transport-Security for https, Content-Security-Policy,
+// X-Content-Type-Options and X-Frame-Options, unless replaced by the
+// frame-ancestors directive of the policy.
+func securityAudit(resp *http.Response) []string {
+	var findings []string
+	h := resp.Header
+
+	if resp.Request != nil && resp.Request.URL.Scheme == "https" {
+		switch hsts := h.Get("Strict-Transport-Security"); {
+		case hsts == "":
+			findings = append(findings, "missing Strict-Transport-Security")
+		case hstsMaxAge(hsts) < minHSTSAge:
+			findings = append(findings, "weak Strict-Transport-Security: max-age below 180 days")
+		}
+	}
+
+	csp := h.Get("Content-Security-Policy")
+	directives := parseCSP(csp)
+	if csp == "" {
+		findings = append(findings, "missing Content-Security-Policy")
+	} else {
+		scripts, ok := directives["script-src"]
+		if !ok {
+			scripts = directives["default-src"]
+		}
+		for _, weak := range []string{"'unsafe-inline'", "'unsafe-eval'", "*"} {
+			for _, src := range scripts {
+				if src == weak {
+					findings = append(findings, fmt.Sprintf("weak Content-Security-Policy: scripts allow %s", weak))
+				}
+			}
+		}
+	}
+
+	if v := h.Get("X-Content-Type-Options"); !strings.EqualFold(strings.TrimSpace(v), "nosniff") {
+		if v == "" {
+			findings = append(findings, "missing X-Content-Type-Options")
+		} else {
+			findings = append(findings, fmt.Sprintf("weak X-Content-Type-Options: %q, want nosniff", v))
+		}
+	}
+
+	if _, ok := directives["frame-ancestors"]; !ok {
+		switch v := strings.ToUpper(strings.TrimSpace(h.Get("X-Frame-Options"))); v {
+		case "DENY", "SAMEORIGIN":
+		case "":
+			findings = append(findings, "missing X-Frame-Options")
+		default:
+			findings = append(findings, fmt.Sprintf("weak X-Frame-Options: %q, want DENY or SAMEORIGIN", v))
+		}
+	}
+	return findings
+}
+
+// hstsMaxAge return the max-age of a Strict-Transport-Security header, 0
+// when missing or invalid.
+func hstsMaxAge(hsts string) int {
+	for _, directive := range strings.Split(hsts, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			age, _ := strconv.Atoi(strings.Trim(value, `"`))
+			return age
+		}
+	}
+	return 0
+}
+
+// parseCSP return the sources of the directives of a Content-Security-Policy.
+func parseCSP(csp string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, directive := range strings.Split(csp, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, dup := directives[name]; !dup {
+			directives[name] = fields[1:]
+		}
+	}
+	return directives
+}
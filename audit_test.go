@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSecurityAudit(t *testing.T) {
+	tests := []struct {
+		name   string
+		https  bool
+		header map[string]string
+		want   []string
+	}{
+		{
+			name:  "hardened",
+			https: true,
+			header: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		{
+			name:  "missing",
+			https: true,
+			want: []string{
+				"missing Strict-Transport-Security",
+				"missing Content-Security-Policy",
+				"missing X-Content-Type-Options",
+				"missing X-Frame-Options",
+			},
+		},
+		{
+			name:  "weak",
+			https: true,
+			header: map[string]string{
+				"Strict-Transport-Security": "max-age=3600",
+				"Content-Security-Policy":   "default-src *; script-src 'self' 'unsafe-inline'",
+				"X-Content-Type-Options":    "sniff",
+				"X-Frame-Options":           "ALLOW-FROM https://a.com",
+			},
+			want: []string{
+				"weak Strict-Transport-Security: max-age below 180 days",
+				"weak Content-Security-Policy: scripts allow 'unsafe-inline'",
+				`weak X-Content-Type-Options: "sniff", want nosniff`,
+				`weak X-Frame-Options: "ALLOW-FROM HTTPS://A.COM", want DENY or SAMEORIGIN`,
+			},
+		},
+		{
+			name: "plain http",
+			header: map[string]string{
+				"Content-Security-Policy": "default-src 'self'",
+				"X-Content-Type-Options":  "nosniff",
+				"X-Frame-Options":         "sameorigin",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := "http://a.com/"
+			if tt.https {
+				url = "https://a.com/"
+			}
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			resp := &http.Response{Header: make(http.Header), Request: req}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+			if got := securityAudit(resp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSecurityAudit(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'self'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}))
+	defer srv.Close()
+
+	c := newChecker(WithRetries(0), WithSecurityAudit(true))
+	c.transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	res := c.checkURL(context.Background(), Service{URL: srv.URL})
+	if !res.Up() {
+		t.Fatalf("findings must not fail the check; got %v", res.Err)
+	}
+	if want := []string{"missing Strict-Transport-Security"}; !reflect.DeepEqual(res.Audit, want) {
+		t.Errorf("got %q; want %q", res.Audit, want)
+	}
+}
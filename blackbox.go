@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/exp/slices"
+)
+
+// maxProbeBody is the maximum size of the bodies matched by probes.
+const maxProbeBody = 4 << 20
+
+// blackboxConfig is a blackbox_exporter configuration file. Only the
+// options of the http prober listed in blackboxHTTP are used, others are
+// ignored so that existing files can be reused as is.
+type blackboxConfig struct {
+	Modules map[string]blackboxModule `yaml:"modules"`
+}
+
+type blackboxModule struct {
+	Prober  string        `yaml:"prober"`
+	Timeout time.Duration `yaml:"timeout"`
+	HTTP    blackboxHTTP  `yaml:"http"`
+}
+
+type blackboxHTTP struct {
+	ValidStatusCodes           []int             `yaml:"valid_status_codes"`
+	Method                     string            `yaml:"method"`
+	Headers                    map[string]string `yaml:"headers"`
+	Body                       string            `yaml:"body"`
+	FollowRedirects            *bool             `yaml:"follow_redirects"`
+	NoFollowRedirects          bool              `yaml:"no_follow_redirects"`
+	FailIfSSL                  bool              `yaml:"fail_if_ssl"`
+	FailIfNotSSL               bool              `yaml:"fail_if_not_ssl"`
+	FailIfBodyMatchesRegexp    []string          `yaml:"fail_if_body_matches_regexp"`
+	FailIfBodyNotMatchesRegexp []string          `yaml:"fail_if_body_not_matches_regexp"`
+}
+
+// probe is a blackbox_exporter http module ready to be applied to services.
+type probe struct {
+	name             string
+	timeout          time.Duration
+	validStatus      []int
+	method           string
+	header           http.Header
+	body             string
+	noFollow         bool
+	failIfSSL        bool
+	failIfNotSSL     bool
+	failIfMatches    []*regexp.Regexp
+	failIfNotMatches []*regexp.Regexp
+}
+
+// ProbeError report a response failing a check of a blackbox_exporter
+// module other than its status.
+type ProbeError struct {
+	Module string
+	Reason string
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("module %s: %s", e.Module, e.Reason)
+}
+
+// loadBlackboxConfig read the http modules of the blackbox_exporter
+// configuration file at path.
+func loadBlackboxConfig(path string) (map[string]*probe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config blackboxConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	probes := make(map[string]*probe, len(config.Modules))
+	for name, m := range config.Modules {
+		if m.Prober != "http" {
+			continue
+		}
+		p := &probe{
+			name:         name,
+			timeout:      m.Timeout,
+			validStatus:  m.HTTP.ValidStatusCodes,
+			method:       m.HTTP.Method,
+			body:         m.HTTP.Body,
+			noFollow:     m.HTTP.NoFollowRedirects || (m.HTTP.FollowRedirects != nil && !*m.HTTP.FollowRedirects),
+			failIfSSL:    m.HTTP.FailIfSSL,
+			failIfNotSSL: m.HTTP.FailIfNotSSL,
+		}
+		if len(m.HTTP.Headers) > 0 {
+			p.header = make(http.Header, len(m.HTTP.Headers))
+			for k, v := range m.HTTP.Headers {
+				p.header.Set(k, v)
+			}
+		}
+		for _, expr := range m.HTTP.FailIfBodyMatchesRegexp {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+			p.failIfMatches = append(p.failIfMatches, re)
+		}
+		for _, expr := range m.HTTP.FailIfBodyNotMatchesRegexp {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: module %s: %w", path, name, err)
+			}
+			p.failIfNotMatches = append(p.failIfNotMatches, re)
+		}
+		probes[name] = p
+	}
+	return probes, nil
+}
+
+// applyModules apply to services their module= among probes. Settings of
+// services take precedence over those of their module.
+func applyModules(services []Service, probes map[string]*probe) error {
+	var errs []error
+	for i := range services {
+		svc := &services[i]
+		if svc.Module == "" {
+			continue
+		}
+		p, ok := probes[svc.Module]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown http module %q", svc.key(), svc.Module))
+			continue
+		}
+		svc.probe = p
+		if svc.Timeout == 0 {
+			svc.Timeout = p.timeout
+		}
+		if len(svc.ExpectStatus) == 0 {
+			svc.ExpectStatus = p.validStatus
+		}
+		for k, values := range p.header {
+			if _, set := svc.Header[k]; !set {
+				if svc.Header == nil {
+					svc.Header = make(http.Header)
+				}
+				svc.Header[k] = values
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// useBlackboxConfig apply to services the modules of the blackbox_exporter
+// configuration file at path. Without path, services using a module are an
+// error.
+func useBlackboxConfig(services []Service, path string) error {
+	probes := map[string]*probe{}
+	if path != "" {
+		var err error
+		if probes, err = loadBlackboxConfig(path); err != nil {
+			return err
+		}
+	}
+	return applyModules(services, probes)
+}
+
+// check the response of a service to the probe, expect being the statuses
+// expected by the service. As with blackbox_exporter, any 2xx status is
+// expected when none is set.
+func (p *probe) check(resp *http.Response, expect []int) error {
+	if len(expect) == 0 && resp.StatusCode/100 != 2 {
+		return &StatusError{Status: resp.StatusCode}
+	}
+	if len(expect) > 0 && !slices.Contains(expect, resp.StatusCode) {
+		return &StatusError{Status: resp.StatusCode, Expect: expect}
+	}
+	if p.failIfSSL && resp.TLS != nil {
+		return &ProbeError{Module: p.name, Reason: "ssl used"}
+	}
+	if p.failIfNotSSL && resp.TLS == nil {
+		return &ProbeError{Module: p.name, Reason: "ssl not used"}
+	}
+	if len(p.failIfMatches) == 0 && len(p.failIfNotMatches) == 0 {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	if err != nil {
+		return err
+	}
+	for _, re := range p.failIfMatches {
+		if re.Match(body) {
+			return &ProbeError{Module: p.name, Reason: fmt.Sprintf("body matched %q", re)}
+		}
+	}
+	for _, re := range p.failIfNotMatches {
+		if !re.Match(body) {
+			return &ProbeError{Module: p.name, Reason: fmt.Sprintf("body did not match %q", re)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const blackboxYAML = `modules:
+  http_2xx:
+    prober: http
+    timeout: 5s
+  http_post:
+    prober: http
+    http:
+      method: POST
+      body: '{"ping":true}'
+      headers:
+        X-Probe: blackbox
+      valid_status_codes: [201]
+  no_redirect:
+    prober: http
+    http:
+      no_follow_redirects: true
+      valid_status_codes: [302]
+  ssl:
+    prober: http
+    http:
+      fail_if_not_ssl: true
+  body:
+    prober: http
+    http:
+      fail_if_body_matches_regexp: ["(?i)error"]
+      fail_if_body_not_matches_regexp: ["ok"]
+  icmp:
+    prober: icmp
+`
+
+func TestBlackboxModules(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method == http.MethodPost && string(body) == `{"ping":true}` && r.Header.Get("X-Probe") == "blackbox" {
+				w.WriteHeader(http.StatusCreated)
+			}
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/error":
+			io.WriteString(w, "ok but ERROR")
+		case "/nok":
+			io.WriteString(w, "fine")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "blackbox.yml")
+	if err := os.WriteFile(path, []byte(blackboxYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		up   bool
+	}{
+		{srv.URL + " module=http_2xx", true},
+		{srv.URL + "/missing module=http_2xx", false},
+		{srv.URL + "/missing module=http_2xx expect=404", true},
+		{srv.URL + "/post module=http_post", true},
+		{srv.URL + " module=http_post", false},
+		{srv.URL + "/redirect module=no_redirect", true},
+		{srv.URL + "/redirect module=http_2xx", true},
+		{srv.URL + " module=ssl", false},
+		{srv.URL + " module=body", true},
+		{srv.URL + "/error module=body", false},
+		{srv.URL + "/nok module=body", false},
+	}
+	c := newChecker()
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			svc, err := ParseService(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			services := []Service{svc}
+			if err := useBlackboxConfig(services, path); err != nil {
+				t.Fatal(err)
+			}
+			res := c.checkURL(context.Background(), services[0])
+			if res.Up() != tt.up {
+				t.Errorf("want up %t; got %d %v", tt.up, res.Status, res.Err)
+			}
+		})
+	}
+}
+
+func TestBlackboxUnknownModule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blackbox.yml")
+	if err := os.WriteFile(path, []byte(blackboxYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, module := range []string{"nope", "icmp"} {
+		services := []Service{{URL: "https://a.com", Module: module}}
+		if err := useBlackboxConfig(services, path); err == nil {
+			t.Errorf("module %s: want error", module)
+		}
+	}
+	if err := useBlackboxConfig([]Service{{URL: "https://a.com", Module: "http_2xx"}}, ""); err == nil {
+		t.Error("module without config: want error")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodySize is the number of bytes of a response body read by
+// checks beyond which they fail.
+const DefaultMaxBodySize = 10 << 20
+
+// WithMaxBodySize fail the checks reading more than limit bytes of a
+// response body, so that unexpectedly large responses don't hold workers
+// until the timeout. 0 removes the limit.
+func WithMaxBodySize(limit int64) Option {
+	return func(c *checker) { c.maxBodySize = limit }
+}
+
+// BodySizeError report a response body larger than the limit of the
+// checker.
+type BodySizeError struct {
+	Limit int64
+}
+
+func (e *BodySizeError) Error() string {
+	return fmt.Sprintf("body larger than %d bytes", e.Limit)
+}
+
+// countedBody count the bytes read from a response body as the transferred
+// bytes of a result, failing beyond limit when it is positive.
+type countedBody struct {
+	io.ReadCloser
+	limit  int64
+	result *Result
+}
+
+func (b *countedBody) Read(p []byte) (int, error) {
+	n := b.result.BytesTransferred
+	if b.limit > 0 {
+		if n >= b.limit {
+			// Read a byte past the limit to tell a body of limit bytes
+			// from a larger one.
+			var extra [1]byte
+			if m, _ := io.ReadFull(b.ReadCloser, extra[:]); m > 0 {
+				return 0, &BodySizeError{Limit: b.limit}
+			}
+			return 0, io.EOF
+		}
+		p = p[:min(int64(len(p)), b.limit-n)]
+	}
+	m, err := b.ReadCloser.Read(p)
+	b.result.BytesTransferred += int64(m)
+	return m, err
+}
+
+// countBody make the bytes read from resp.Body counted in result, failing
+// beyond limit when it is positive.
+func countBody(resp *http.Response, limit int64, result *Result) {
+	resp.Body = &countedBody{ReadCloser: resp.Body, limit: limit, result: result}
+}
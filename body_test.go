@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 3000)))
+	}))
+	defer srv.Close()
+	svc := Service{URL: srv.URL}
+
+	res := newChecker(WithRetries(0), WithMaxBodySize(1000)).check(context.Background(), svc)
+	if !res.Up() || res.BytesTransferred != 0 {
+		t.Errorf("body not read: want up; got %v, %d bytes", res.Err, res.BytesTransferred)
+	}
+	res = newChecker(WithRetries(0), WithDownload(1<<20)).check(context.Background(), svc)
+	if !res.Up() || res.BytesTransferred != 3000 {
+		t.Errorf("default limit: want up with 3000 bytes; got %v, %d bytes", res.Err, res.BytesTransferred)
+	}
+	res = newChecker(WithRetries(0), WithDownload(1<<20), WithMaxBodySize(3000)).check(context.Background(), svc)
+	if !res.Up() || res.BytesTransferred != 3000 {
+		t.Errorf("body of the limit: want up; got %v, %d bytes", res.Err, res.BytesTransferred)
+	}
+	var sizeErr *BodySizeError
+	for _, opt := range []Option{WithDownload(1 << 20), WithAcceptEncoding("gzip")} {
+		res = newChecker(WithRetries(0), opt, WithMaxBodySize(1000)).check(context.Background(), svc)
+		if !errors.As(res.Err, &sizeErr) || sizeErr.Limit != 1000 || res.BytesTransferred != 1000 {
+			t.Errorf("larger body: want a BodySizeError after 1000 bytes; got %v, %d bytes", res.Err, res.BytesTransferred)
+		}
+	}
+	drift, err := ParseService(srv.URL + " drift=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res = newChecker(WithRetries(0), WithMaxBodySize(1000)).check(context.Background(), drift); !errors.As(res.Err, &sizeErr) {
+		t.Errorf("drift: want a BodySizeError; got %v", res.Err)
+	}
+}
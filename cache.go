@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cacheHeaders are the response headers describing caching, and the CDN
+// point of presence that answered, recorded with the results.
+var cacheHeaders = []string{
+	"Cache-Control",
+	"Age",
+	"X-Cache",
+	"X-Cache-Hits",
+	"CF-Cache-Status",
+	"CF-Ray",
+	"X-Served-By",
+	"X-Amz-Cf-Pop",
+	"Akamai-Cache-Status",
+}
+
+// recordCache record the cache headers of h in result.
+func recordCache(h http.Header, result *Result) {
+	for _, name := range cacheHeaders {
+		if v := h.Get(name); v != "" {
+			if result.Cache == nil {
+				result.Cache = make(map[string]string)
+			}
+			result.Cache[name] = v
+		}
+	}
+}
+
+// formatCache return the cache headers of result in the order of
+// cacheHeaders, as Name=value pairs.
+func formatCache(cache map[string]string) string {
+	var pairs []string
+	for _, name := range cacheHeaders {
+		if v, ok := cache[name]; ok {
+			pairs = append(pairs, name+"="+v)
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// headerExpectation is a response header a service must have, containing
+// Value when it is set.
+type headerExpectation struct {
+	Name  string
+	Value string
+}
+
+// parseHeaderExpectation parse the value of an expect-header= option, Name
+// or Name=value.
+func parseHeaderExpectation(s string) (headerExpectation, error) {
+	name, value, _ := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " :") {
+		return headerExpectation{}, fmt.Errorf("want Name or Name=value, got %q", s)
+	}
+	return headerExpectation{Name: http.CanonicalHeaderKey(name), Value: strings.TrimSpace(value)}, nil
+}
+
+// HeaderError report a response header missing or without the expected
+// value.
+type HeaderError struct {
+	Name string
+	Want string
+	Got  string
+}
+
+func (e *HeaderError) Error() string {
+	if e.Got == "" {
+		return fmt.Sprintf("missing header %s", e.Name)
+	}
+	return fmt.Sprintf("header %s is %q, want %s", e.Name, e.Got, e.Want)
+}
+
+// checkHeaders return an error unless h meets expect. Values are matched
+// case-insensitively anywhere in the header, so that X-Cache=HIT matches
+// "Hit from cloudfront".
+func checkHeaders(h http.Header, expect []headerExpectation) error {
+	for _, e := range expect {
+		got := strings.Join(h.Values(e.Name), ", ")
+		if got == "" || !strings.Contains(strings.ToLower(got), strings.ToLower(e.Value)) {
+			return &HeaderError{Name: e.Name, Want: e.Value, Got: got}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Header().Set("Age", "12")
+		w.Header().Set("X-Cache", r.URL.Query().Get("cache")+" from cloudfront")
+		w.Header().Set("X-Amz-Cf-Pop", "CDG50-C1")
+	}))
+	defer srv.Close()
+
+	c := newChecker(WithRetries(0))
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{line: "/?cache=Hit expect-header=X-Cache=HIT expect-header=cache-control=max-age"},
+		{line: "/?cache=Miss expect-header=x-cache=hit", err: `header X-Cache is "Miss from cloudfront", want hit`},
+		{line: "/ expect-header=CF-Ray", err: "missing header Cf-Ray"},
+	}
+	for _, tt := range tests {
+		svc, err := ParseService(srv.URL + tt.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := c.check(context.Background(), svc)
+		if tt.err == "" && !res.Up() || tt.err != "" && (res.Err == nil || res.Err.Error() != tt.err) {
+			t.Errorf("%s: want error %q; got %v", tt.line, tt.err, res.Err)
+		}
+		if res.Cache["Age"] != "12" || res.Cache["X-Amz-Cf-Pop"] != "CDG50-C1" {
+			t.Errorf("%s: got cache headers %v", tt.line, res.Cache)
+		}
+	}
+
+	var herr *HeaderError
+	svc, _ := ParseService(srv.URL + "/?cache=Miss expect-header=X-Cache=HIT")
+	res := c.check(context.Background(), svc)
+	if !errors.As(res.Err, &herr) {
+		t.Errorf("want a HeaderError; got %v", res.Err)
+	}
+	var b bytes.Buffer
+	writeTextResult(&b, "", res)
+	if want := "; Cache: Cache-Control=public, max-age=60, Age=12, X-Cache=Miss from cloudfront, X-Amz-Cf-Pop=CDG50-C1;"; !strings.Contains(b.String(), want) {
+		t.Errorf("got %q, want %q in it", b.String(), want)
+	}
+
+	for _, value := range []string{"", "=HIT", "X Cache=HIT"} {
+		if _, err := ParseService("https://a.com expect-header=" + value); err == nil {
+			t.Errorf("expect-header=%s: want an error", value)
+		}
+	}
+}
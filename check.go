@@ -0,0 +1,719 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slices"
+)
+
+// Default settings of a HealthCheck, services may override some of them.
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultRetries    = 0
+	defaultRetryDelay = 500 * time.Millisecond
+)
+
+type Result struct {
+	Name    string
+	Url     string
+	Tags    []string
+	Status  int
+	Err     error
+	Latency time.Duration
+
+	// Start is the time the check started, in the time zone of the
+	// checker, see WithTimezone, to correlate results with server logs.
+	Start time.Time
+
+	// UnicodeURL is the unicode form of Url when its host is an
+	// internationalized domain name.
+	UnicodeURL string
+
+	// Source and Line locate the service in the services file.
+	Source string
+	Line   int
+
+	// Severity is that of the service, see Service.
+	Severity string
+
+	// Maintenance is set when the check was skipped because the service
+	// was in a maintenance window.
+	Maintenance bool
+
+	// Members hold the results of the members of a composite service.
+	Members []Result
+
+	// Steps hold the results of the steps of a scenario.
+	Steps []StepResult
+
+	// Stats describe the samples taken when sampling is enabled, Latency
+	// being then their average.
+	Stats *SampleStats
+
+	// BytesTransferred is the size of the part of the response body read by
+	// the check. ContentEncoding and BytesDecoded are recorded when the
+	// accepted encodings are set, see WithAcceptEncoding.
+	ContentEncoding  string
+	BytesTransferred int64
+	BytesDecoded     int64
+
+	// BodyHash is the SHA-256 of the body of services with drift=true.
+	BodyHash string
+
+	// TLSVersion and TLSCipher are the TLS version and cipher suite
+	// negotiated with https services.
+	TLSVersion string
+	TLSCipher  string
+
+	// Banner is the version announced by the server of protocols with one,
+	// such as SSH.
+	Banner string
+
+	// Offset and Stratum are the offset of the time of NTP servers from the
+	// local clock and their distance from a reference clock.
+	Offset  time.Duration
+	Stratum int
+
+	// CertExpiry is the end of validity of the certificate of the server
+	// of TLS connections.
+	CertExpiry time.Time
+
+	// RequestID is the ID sent with the request of HTTP checks, see
+	// WithRequestID.
+	RequestID string
+
+	// Cache holds the cache and CDN headers of the response of HTTP
+	// checks, see cacheHeaders.
+	Cache map[string]string
+
+	// TTFB is the time to the first byte of the response of HTTP checks,
+	// and TTLB the time to its last byte, see WithDownload.
+	TTFB time.Duration
+	TTLB time.Duration
+
+	// Geo locate the address the service was reached at, see WithGeoIP.
+	Geo *Geo
+
+	// Audit are the findings reported with the result without failing it,
+	// see WithSecurityAudit and WithDomainExpiry.
+	Audit []string
+
+	// BodyPreview is the start of the response body of failed HTTP checks,
+	// see WithBodyPreview.
+	BodyPreview string
+
+	// response is the response of a failed HTTP check to dump, see
+	// WithDumpFailures.
+	response *failedResponse
+}
+
+// Up report whether the service answered as expected. Services in
+// maintenance are not checked and never fail.
+func (r Result) Up() bool {
+	return r.Err == nil
+}
+
+// DependencyDown report whether the service failed while one of its
+// dependencies was down.
+func (r Result) DependencyDown() bool {
+	var depErr *DependencyError
+	return errors.As(r.Err, &depErr)
+}
+
+// Failed report whether the service is down for a reason of its own, which
+// calls for an alert. Failures caused by a dependency are not alerted on.
+func (r Result) Failed() bool {
+	return !r.Up() && !r.DependencyDown()
+}
+
+// StatusError report a response whose status is not the expected one.
+type StatusError struct {
+	Status int
+	Expect []int // any status below 400 when empty
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Expect) == 0 {
+		return fmt.Sprintf("unexpected status %d", e.Status)
+	}
+	want := make([]string, len(e.Expect))
+	for i, status := range e.Expect {
+		want[i] = strconv.Itoa(status)
+	}
+	return fmt.Sprintf("unexpected status %d, want %s", e.Status, strings.Join(want, " or "))
+}
+
+// DependencyError wrap the error of a service whose dependency is down.
+type DependencyError struct {
+	Dependency string
+	Err        error
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("dependency %s down: %s", e.Dependency, e.Err)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Option configure a HealthCheck.
+type Option func(*checker)
+
+// WithTimeout set the time allowed for each request, 0 meaning no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *checker) { c.timeout = d }
+}
+
+// WithRetries set how many times a failed check is retried.
+func WithRetries(n int) Option {
+	return func(c *checker) { c.retries = n }
+}
+
+// WithSamples make each url checked n times in a row, reporting latency
+// statistics and the success rate of the samples. Failed samples are not
+// retried.
+func WithSamples(n int) Option {
+	return func(c *checker) { c.samples = n }
+}
+
+// checker hold the global settings of a HealthCheck.
+type checker struct {
+	client         *http.Client
+	transport      *http.Transport
+	keepAlive      bool
+	freshConns     bool
+	acceptEncoding string
+	download       int64
+	requestID      string
+	maxBodySize    int64
+	bodyPreview    int
+	dumpDir        string
+	maxDumps       int
+	dumps          atomic.Int64
+	cookies        bool
+	userAgent      string
+	hostHeader     string
+	sni            string
+	transports     sync.Map
+	progress       io.Writer
+	har            *HAR
+	idTokens       *idTokenSource
+	sourceIP       net.IP
+	dnsCache       *dnsCache
+	hashes         contentHashes
+	securityAudit  bool
+	minTLS         uint16
+	domainExpiry   time.Duration
+	rdap           *rdapClient
+	geoIP          *geoIP
+	lookupSRV      func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	timeout        time.Duration
+	retries        int
+	retryDelay     time.Duration
+	workers        int
+	adaptive       bool
+	spread         time.Duration
+	spreadSeed     uint64
+	location       *time.Location
+	samples        int
+	warmup         bool
+	warmed         warmups
+	now            func() time.Time
+}
+
+func newChecker(opts ...Option) *checker {
+	c := &checker{
+		keepAlive:   true,
+		timeout:     DefaultTimeout,
+		retries:     DefaultRetries,
+		retryDelay:  defaultRetryDelay,
+		workers:     DefaultWorkers,
+		userAgent:   DefaultUserAgent,
+		now:         time.Now,
+		location:    time.UTC,
+		lookupSRV:   net.DefaultResolver.LookupSRV,
+		idTokens:    newIDTokenSource(),
+		hashes:      contentHashes{m: make(map[string]string)},
+		rdap:        newRDAPClient(DefaultRDAPURL),
+		maxBodySize: DefaultMaxBodySize,
+		bodyPreview: DefaultBodyPreview,
+		maxDumps:    DefaultMaxDumps,
+		requestID:   DefaultRequestIDHeader,
+		dnsCache:    newDNSCache(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.transport = newTransport(c)
+	c.client = &http.Client{Transport: c.transport}
+	if c.cookies {
+		c.client.Jar = newCookieJar()
+	}
+	return c
+}
+
+// HealthCheck report if a list of web service is up and running.
+// Failures of services whose dependency is down are reported as
+// DependencyErrors.
+//
+// Services are checked by a fixed number of workers (see WithWorkers) rather
+// than by a goroutine each, so that goroutines and open connections stay
+// bounded whatever the number of services. Each result is written to the
+// index of its service so that no synchronisation is needed and results keep
+// the order of services. Critical services are checked first, minor ones
+// last, see dispatchOrder, unless checks are spread, see WithSpread.
+func HealthCheck(services []Service, opts ...Option) []Result {
+	return newChecker(opts...).healthCheck(context.Background(), services, nil)
+}
+
+// healthCheck implement HealthCheck. completed, when not nil, is called
+// concurrently with each result as soon as it is known, before dependencies
+// are taken into account. Once ctx is done, checks in flight are canceled
+// and the services not started yet are not checked, their result holding
+// the error of ctx.
+func (c *checker) healthCheck(ctx context.Context, services []Service, completed func(Result)) []Result {
+	results := make([]Result, len(services))
+	started := make([]bool, len(services))
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		order, offsets := dispatchOrder(services), []time.Duration(nil)
+		if c.spread > 0 {
+			order, offsets = c.spreadOrder(services)
+		}
+		start := time.Now()
+		for k, i := range order {
+			if offsets != nil && !sleep(ctx, time.Until(start.Add(offsets[k]))) {
+				return
+			}
+			select {
+			case jobs <- job{i: i, svc: services[i]}:
+				started[i] = true
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	check := func(svc Service) Result { return c.check(ctx, svc) }
+	if c.progress != nil {
+		p := newProgress(c.progress, len(services), c.now)
+		defer p.clear()
+		if completed == nil {
+			completed = p.add
+		} else {
+			next := completed
+			completed = func(res Result) {
+				p.add(res)
+				next(res)
+			}
+		}
+	}
+	done := func(j job, res Result) {
+		results[j.i] = res
+		if completed != nil {
+			completed(res)
+		}
+	}
+	c.pool(jobs, check, done)
+
+	for i, svc := range services {
+		if !started[i] {
+			results[i] = Result{Name: svc.Name, Url: svc.URL, UnicodeURL: svc.UnicodeURL, Tags: svc.Tags, Err: ctx.Err()}
+		}
+	}
+	markDependencies(services, results)
+	return results
+}
+
+// markDependencies wrap the error of failed services having a dependency
+// down in a DependencyError. results[i] is the result of services[i].
+func markDependencies(services []Service, results []Result) {
+	down := make(map[string]bool, 2*len(results))
+	for i, res := range results {
+		if !res.Up() {
+			down[services[i].key()] = true
+			down[services[i].URL] = true
+		}
+	}
+	for i, svc := range services {
+		if results[i].Up() {
+			continue
+		}
+		for _, dep := range svc.Depends {
+			if down[dep] {
+				results[i].Err = &DependencyError{Dependency: dep, Err: results[i].Err}
+				break
+			}
+		}
+	}
+}
+
+// check check a service, fanning out to the members of composite services
+// and running the steps of scenarios.
+func (c *checker) check(ctx context.Context, svc Service) Result {
+	start := c.now().In(c.location)
+	var res Result
+	switch {
+	case len(svc.Members) > 0:
+		res = c.checkQuorum(ctx, svc)
+	case svc.Scenario != nil:
+		res = c.checkScenario(ctx, svc)
+	case strings.HasPrefix(svc.URL, srvScheme+"://"):
+		res = c.checkSRV(ctx, svc)
+	case protocolOf(svc.URL) != nil:
+		res = c.checkProtocol(ctx, svc)
+	default:
+		res = c.checkURL(ctx, svc)
+	}
+	res.Source, res.Line, res.Severity = svc.Source, svc.Line, svc.Severity
+	// Members are checked concurrently, as soon as their service is.
+	res.Start = start
+	for i := range res.Members {
+		res.Members[i].Start = start
+	}
+	return res
+}
+
+// checkURL send a GET request to the service url and report its status and
+// latency, retrying failed attempts. Settings of the service override the
+// global ones. The Url field is always set so that failures can be
+// attributed.
+func (c *checker) checkURL(ctx context.Context, svc Service) Result {
+	ctx, span := tracer.Start(ctx, "checkURL",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("url.full", redactURL(svc.URL)),
+			attribute.String("healthcheck.service", svc.Name),
+			attribute.StringSlice("healthcheck.tags", svc.Tags),
+		),
+	)
+	defer span.End()
+
+	if svc.inMaintenance(c.now()) {
+		result := Result{Name: svc.Name, Url: svc.URL, UnicodeURL: svc.UnicodeURL, Tags: svc.Tags, Maintenance: true}
+		recordResult(ctx, span, result)
+		return result
+	}
+
+	if c.warmup {
+		c.warm(ctx, svc)
+	}
+
+	var result Result
+	if c.samples > 1 {
+		result = c.sample(ctx, svc)
+	} else {
+		result = c.retry(ctx, span, svc, c.attempt)
+	}
+	result = c.checkDrift(svc, result)
+	result = c.checkMinTLS(ctx, svc, result)
+	result = c.checkDomainExpiry(ctx, svc, result)
+	if result.response != nil {
+		// Failed attempts followed by a successful retry are not dumped.
+		if !result.Up() {
+			c.dumpFailure(result)
+		}
+		result.response = nil
+	}
+	recordResult(ctx, span, result)
+	return result
+}
+
+// sample check the service c.samples times in a row. The result is the last
+// failed sample, or the last one when all succeeded, with the average
+// latency.
+func (c *checker) sample(ctx context.Context, svc Service) Result {
+	samples := make([]Result, c.samples)
+	for i := range samples {
+		samples[i] = c.attempt(ctx, svc)
+	}
+
+	result := samples[len(samples)-1]
+	for _, s := range samples {
+		if !s.Up() {
+			result = s
+		}
+	}
+	stats := sampleStats(samples)
+	result.Stats = &stats
+	result.Latency = stats.Avg
+	return result
+}
+
+// retry call attempt until its result is up or the retries of the service
+// are exhausted, and return the last result.
+func (c *checker) retry(ctx context.Context, span trace.Span, svc Service, attempt func(context.Context, Service) Result) Result {
+	retries := c.retries
+	if svc.Retries != nil {
+		retries = *svc.Retries
+	}
+
+	var result Result
+	for i := 0; ; i++ {
+		result = attempt(ctx, svc)
+		if result.Up() || i >= retries {
+			return result
+		}
+		span.AddEvent("retry", trace.WithAttributes(attribute.String("error", result.Err.Error())))
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// withTimeout bound ctx by the request timeout of the service.
+func (c *checker) withTimeout(ctx context.Context, svc Service) (context.Context, context.CancelFunc) {
+	timeout := c.timeout
+	if svc.Timeout > 0 {
+		timeout = svc.Timeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// attempt perform a single request against the service.
+func (c *checker) attempt(ctx context.Context, svc Service) (result Result) {
+	result = Result{Name: svc.Name, Url: svc.URL, UnicodeURL: svc.UnicodeURL, Tags: svc.Tags}
+
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+
+	// Record DNS, connect, TLS and time to first byte as sub-spans.
+	ctx = httptrace.WithClientTrace(ctx, otelhttptrace.NewClientTrace(ctx))
+	// The last connection is that of the final response, after redirects.
+	var remote net.IP
+	if c.geoIP != nil {
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { remote = remoteIP(info.Conn.RemoteAddr()) },
+		})
+	}
+
+	var firstByte time.Time
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	})
+
+	method, body := http.MethodGet, ""
+	if p := svc.probe; p != nil {
+		method, body = cmp.Or(p.method, method), p.body
+	}
+	if svc.CORS != nil {
+		method = http.MethodOptions
+	}
+	if svc.GraphQL != "" {
+		method, body = http.MethodPost, graphQLRequest(svc.GraphQL)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, svc.URL, strings.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if svc.Range != nil {
+		req.Header.Set("Range", svc.Range.String())
+	}
+	if svc.SSE {
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+	}
+	if svc.GraphQL != "" {
+		// Headers of the service may override them.
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	}
+	if c.acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", c.acceptEncoding)
+	}
+	c.setHeaders(req, svc)
+	if c.requestID != "" {
+		if req.Header.Get(c.requestID) == "" {
+			req.Header.Set(c.requestID, newRequestID())
+		}
+		result.RequestID = req.Header.Get(c.requestID)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("healthcheck.request_id", result.RequestID))
+	}
+	if svc.CORS != nil {
+		svc.CORS.setHeaders(req)
+	}
+	if err := c.authorize(req, svc); err != nil {
+		result.Err = err
+		return result
+	}
+
+	client, release := c.httpClient(svc)
+	defer release()
+	if svc.probe != nil && svc.probe.noFollow {
+		noFollow := *client
+		noFollow.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		client = &noFollow
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	// The body must be closed, otherwise the underlying connection leaks.
+	defer resp.Body.Close()
+	countBody(resp, c.maxBodySize, &result)
+	if limit := c.recordLimit(svc, resp); limit > 0 {
+		body := recordBody(resp, limit)
+		defer func() {
+			if result.Err == nil && result.Status < http.StatusInternalServerError {
+				return
+			}
+			// Event streams are never fully read.
+			if !svc.SSE {
+				body.readRest()
+			}
+			if c.bodyPreview > 0 {
+				result.BodyPreview = body.preview(resp.Header, c.bodyPreview)
+			}
+			if c.dumpDir != "" {
+				result.response = c.newFailedResponse(req, resp, body.kept)
+			}
+		}()
+	}
+
+	result.Status = resp.StatusCode
+	if !firstByte.IsZero() {
+		result.TTFB = firstByte.Sub(start)
+	}
+	recordTLS(resp.TLS, &result)
+	if remote != nil {
+		result.Geo = c.geoIP.lookup(remote)
+	}
+	if c.securityAudit {
+		result.Audit = securityAudit(resp)
+	}
+	recordCache(resp.Header, &result)
+	if svc.ExpectRequestID {
+		if err := checkRequestID(resp.Header, c.requestID, result.RequestID); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	if err := checkHeaders(resp.Header, svc.ExpectHeader); err != nil {
+		result.Err = err
+		return result
+	}
+	// Event streams are never fully read, and ranges are read and counted
+	// by their own check.
+	downloads := c.download > 0 && !svc.SSE && svc.Range == nil
+	if downloads {
+		limitBody(resp, c.download)
+	}
+	if c.acceptEncoding != "" && !svc.SSE {
+		if err := measureEncoding(resp, &result); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	if downloads {
+		if err := download(resp, start, &result); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	if svc.Drift {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.BodyHash = bodyHash(body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if svc.probe != nil {
+		result.Err = svc.probe.check(resp, svc.ExpectStatus)
+		return result
+	}
+	if svc.CORS != nil {
+		result.Err = svc.CORS.check(resp, svc.ExpectStatus)
+		return result
+	}
+	if svc.GraphQL != "" {
+		result.Err = checkGraphQL(resp, svc.ExpectStatus)
+		return result
+	}
+	if svc.Range != nil {
+		result.Err = svc.Range.check(resp, svc.ExpectStatus)
+		return result
+	}
+	if svc.SSE {
+		// The latency is that of the first event.
+		if result.Err = checkSSE(resp, svc.ExpectStatus); result.Err == nil {
+			result.Latency = time.Since(start)
+		}
+		return result
+	}
+	if !expectedStatus(resp.StatusCode, svc.ExpectStatus) {
+		result.Err = &StatusError{Status: resp.StatusCode, Expect: svc.ExpectStatus}
+		return result
+	}
+	if svc.Conditional {
+		result.Err = revalidate(ctx, client, resp)
+	}
+	return result
+}
+
+// expectedStatus report whether status is one of expect, or below 400 when
+// expect is empty.
+func expectedStatus(status int, expect []int) bool {
+	if len(expect) == 0 {
+		return status < http.StatusBadRequest
+	}
+	return slices.Contains(expect, status)
+}
+
+// recordResult add the outcome of a check to its span and to the metrics.
+func recordResult(ctx context.Context, span trace.Span, result Result) {
+	outcome := "success"
+	if result.Maintenance {
+		outcome = "maintenance"
+		span.SetAttributes(attribute.Bool("healthcheck.maintenance", true))
+	}
+	if result.Status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.Status))
+	}
+	if result.Err != nil {
+		outcome = "error"
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, result.Err.Error())
+	}
+
+	// Metrics are not broken down by url, whose distinct values, query
+	// strings included, are unbounded.
+	attrs := metric.WithAttributes(
+		attribute.String("healthcheck.service", result.Name),
+		attribute.String("outcome", outcome),
+	)
+	checkCounter.Add(ctx, 1, attrs)
+	if result.Status != 0 || (result.Err == nil && result.Latency > 0) {
+		latencyHistogram.Record(ctx, result.Latency.Seconds(), attrs)
+	}
+}
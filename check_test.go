@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHealthCheck(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	services := []Service{
+		{URL: ok.URL, Tags: []string{"prod"}, Source: "services.txt", Line: 3},
+		{URL: missing.URL},
+		{URL: "http://127.0.0.1:0"},
+		{URL: ok.URL + "/again"},
+	}
+	results := HealthCheck(services)
+	if len(results) != len(services) {
+		t.Fatalf("want %d results; got %d", len(services), len(results))
+	}
+
+	for i, res := range results {
+		if res.Url != services[i].URL {
+			t.Errorf("results[%d].Url: want %s; got %s", i, services[i].URL, res.Url)
+		}
+	}
+	if slices.Compare(results[0].Tags, []string{"prod"}) != 0 {
+		t.Errorf("want tags [prod]; got %v", results[0].Tags)
+	}
+	if results[0].Source != "services.txt" || results[0].Line != 3 {
+		t.Errorf("want services.txt:3; got %s:%d", results[0].Source, results[0].Line)
+	}
+	if results[0].Err != nil || results[0].Status != http.StatusOK {
+		t.Errorf("want status 200; got %d (%v)", results[0].Status, results[0].Err)
+	}
+	var statusErr *StatusError
+	if !errors.As(results[1].Err, &statusErr) || results[1].Status != http.StatusNotFound {
+		t.Errorf("want a status error for 404; got %d (%v)", results[1].Status, results[1].Err)
+	}
+	if results[2].Err == nil {
+		t.Errorf("want an error for an unreachable url")
+	}
+	if results[3].Latency <= 0 {
+		t.Errorf("want a positive latency; got %s", results[3].Latency)
+	}
+}
+
+func TestCheckURLOverrides(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/auth":
+			if r.Header.Get("Authorization") != "Bearer x" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := newChecker(WithTimeout(time.Second), WithRetries(0))
+	c.retryDelay = 0
+	two, zero := 2, 0
+
+	tests := []struct {
+		name string
+		svc  Service
+		up   bool
+	}{
+		{"retries", Service{URL: srv.URL + "/flaky", Retries: &two}, true},
+		{"timeout", Service{URL: srv.URL + "/slow", Timeout: 10 * time.Millisecond}, false},
+		{"expect", Service{URL: srv.URL + "/auth", ExpectStatus: []int{http.StatusUnauthorized}}, true},
+		{"unexpected", Service{URL: srv.URL, ExpectStatus: []int{http.StatusNoContent}}, false},
+		{"header", Service{URL: srv.URL + "/auth", Header: http.Header{"Authorization": {"Bearer x"}}}, true},
+		{"no retries", Service{URL: srv.URL + "/auth", Retries: &zero}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := c.checkURL(context.Background(), tt.svc)
+			if res.Up() != tt.up {
+				t.Errorf("want up %t; got %d (%v)", tt.up, res.Status, res.Err)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	err := &StatusError{Status: 503, Expect: []int{200, 204}}
+	if got, want := err.Error(), "unexpected status 503, want 200 or 204"; got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestResultUp(t *testing.T) {
+	if !(Result{Status: http.StatusOK}).Up() {
+		t.Error("want a result without error to be up")
+	}
+	if (Result{Status: http.StatusNotFound, Err: &StatusError{Status: http.StatusNotFound}}).Up() {
+		t.Error("want a result with an error to be down")
+	}
+}
+
+func TestCheckURLMaintenance(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	window, err := parseMaintenanceWindow("2026-01-01T00:00:00Z/2026-01-01T02:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := Service{URL: srv.URL, Maintenance: []MaintenanceWindow{window}}
+
+	c := newChecker()
+	c.now = func() time.Time { return time.Date(2026, time.January, 1, 1, 0, 0, 0, time.UTC) }
+	if res := c.checkURL(context.Background(), svc); !res.Maintenance || !res.Up() || calls.Load() != 0 {
+		t.Errorf("want the check skipped during maintenance; got %+v", res)
+	}
+
+	c.now = func() time.Time { return time.Date(2026, time.January, 1, 3, 0, 0, 0, time.UTC) }
+	if res := c.checkURL(context.Background(), svc); res.Maintenance || calls.Load() != 1 {
+		t.Errorf("want the service checked outside maintenance; got %+v", res)
+	}
+}
+
+func TestMarkDependencies(t *testing.T) {
+	services := []Service{
+		{Name: "db", URL: "https://db.a.com"},
+		{Name: "api", URL: "https://api.a.com", Depends: []string{"db"}},
+		{URL: "https://front.a.com", Depends: []string{"api"}},
+		{URL: "https://admin.a.com", Depends: []string{"https://db.a.com"}},
+		{URL: "https://blog.a.com", Depends: []string{"front"}},
+	}
+	down := errors.New("down")
+	results := []Result{{Err: down}, {Err: down}, {Err: down}, {Status: 200}, {Err: down}}
+
+	markDependencies(services, results)
+
+	want := []bool{false, true, true, false, false}
+	for i, res := range results {
+		if res.DependencyDown() != want[i] {
+			t.Errorf("%s: want dependency down %t; got %v", services[i].key(), want[i], res.Err)
+		}
+	}
+	if !errors.Is(results[1].Err, down) {
+		t.Errorf("want the original error wrapped; got %v", results[1].Err)
+	}
+	if results[1].Failed() || !results[0].Failed() {
+		t.Error("want only failures of the service itself to be alerted on")
+	}
+}
+
+func BenchmarkCheckURL(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }))
+	defer srv.Close()
+	c := newChecker(WithRetries(0))
+	svc := Service{URL: srv.URL}
+	b.ReportAllocs()
+	for b.Loop() {
+		c.checkURL(context.Background(), svc)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Minutes(), "urls/min")
+}
+
+// BenchmarkHealthCheck measure the whole pipeline, from the services file to
+// the text output. Run with -cpu 1 for the throughput of a single core.
+func BenchmarkHealthCheck(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }))
+	defer srv.Close()
+	var file strings.Builder
+	for range 1000 {
+		fmt.Fprintf(&file, "%s/healthz timeout=2s expect=200\n", srv.URL)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		services, err := ParseServices(strings.NewReader(file.String()), "services.txt")
+		if err != nil {
+			b.Fatal(err)
+		}
+		writeText(io.Discard, HealthCheck(services, WithRetries(0)))
+	}
+	b.ReportMetric(float64(b.N*1000)/b.Elapsed().Minutes(), "urls/min")
+}
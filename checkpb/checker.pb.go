@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: checker.proto
+
+package checkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type State int32
+
+const (
+	State_STATE_UNSPECIFIED State = 0
+	State_STATE_UP          State = 1
+	State_STATE_DOWN        State = 2
+	// The service is down because a service it depends on is down.
+	State_STATE_DEPENDENCY_DOWN State = 3
+	// The service was not checked, being in a maintenance window.
+	State_STATE_MAINTENANCE State = 4
+	// The service of the request could not be parsed, see error.
+	State_STATE_INVALID State = 5
+)
+
+// Enum value maps for State.
+var (
+	State_name = map[int32]string{
+		0: "STATE_UNSPECIFIED",
+		1: "STATE_UP",
+		2: "STATE_DOWN",
+		3: "STATE_DEPENDENCY_DOWN",
+		4: "STATE_MAINTENANCE",
+		5: "STATE_INVALID",
+	}
+	State_value = map[string]int32{
+		"STATE_UNSPECIFIED":     0,
+		"STATE_UP":              1,
+		"STATE_DOWN":            2,
+		"STATE_DEPENDENCY_DOWN": 3,
+		"STATE_MAINTENANCE":     4,
+		"STATE_INVALID":         5,
+	}
+)
+
+func (x State) Enum() *State {
+	p := new(State)
+	*p = x
+	return p
+}
+
+func (x State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (State) Descriptor() protoreflect.EnumDescriptor {
+	return file_checker_proto_enumTypes[0].Descriptor()
+}
+
+func (State) Type() protoreflect.EnumType {
+	return &file_checker_proto_enumTypes[0]
+}
+
+func (x State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use State.Descriptor instead.
+func (State) EnumDescriptor() ([]byte, []int) {
+	return file_checker_proto_rawDescGZIP(), []int{0}
+}
+
+type CheckRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Id is echoed in the result of the request.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Service is a line of a services file, e.g. "https://a.com timeout=2s #prod".
+	Service       string `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_checker_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_checker_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_checker_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+type CheckResult struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Url     string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Tags    []string               `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	State   State                  `protobuf:"varint,5,opt,name=state,proto3,enum=healthcheck.v1.State" json:"state,omitempty"`
+	Status  int32                  `protobuf:"varint,6,opt,name=status,proto3" json:"status,omitempty"`
+	Latency *durationpb.Duration   `protobuf:"bytes,7,opt,name=latency,proto3" json:"latency,omitempty"`
+	Error   string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// Members hold the results of the members of a composite service.
+	Members       []*CheckResult `protobuf:"bytes,9,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResult) Reset() {
+	*x = CheckResult{}
+	mi := &file_checker_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResult) ProtoMessage() {}
+
+func (x *CheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_checker_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResult.ProtoReflect.Descriptor instead.
+func (*CheckResult) Descriptor() ([]byte, []int) {
+	return file_checker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CheckResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CheckResult) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CheckResult) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *CheckResult) GetState() State {
+	if x != nil {
+		return x.State
+	}
+	return State_STATE_UNSPECIFIED
+}
+
+func (x *CheckResult) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *CheckResult) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *CheckResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CheckResult) GetMembers() []*CheckResult {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+var File_checker_proto protoreflect.FileDescriptor
+
+const file_checker_proto_rawDesc = "" +
+	"\n" +
+	"\rchecker.proto\x12\x0ehealthcheck.v1\x1a\x1egoogle/protobuf/duration.proto\"8\n" +
+	"\fCheckRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\"\x9e\x02\n" +
+	"\vCheckResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x12+\n" +
+	"\x05state\x18\x05 \x01(\x0e2\x15.healthcheck.v1.StateR\x05state\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\x05R\x06status\x123\n" +
+	"\alatency\x18\a \x01(\v2\x19.google.protobuf.DurationR\alatency\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x125\n" +
+	"\amembers\x18\t \x03(\v2\x1b.healthcheck.v1.CheckResultR\amembers*\x81\x01\n" +
+	"\x05State\x12\x15\n" +
+	"\x11STATE_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bSTATE_UP\x10\x01\x12\x0e\n" +
+	"\n" +
+	"STATE_DOWN\x10\x02\x12\x19\n" +
+	"\x15STATE_DEPENDENCY_DOWN\x10\x03\x12\x15\n" +
+	"\x11STATE_MAINTENANCE\x10\x04\x12\x11\n" +
+	"\rSTATE_INVALID\x10\x052Q\n" +
+	"\aChecker\x12F\n" +
+	"\x05Check\x12\x1c.healthcheck.v1.CheckRequest\x1a\x1b.healthcheck.v1.CheckResult(\x010\x01B\x1aZ\x18coding-challenge/checkpbb\x06proto3"
+
+var (
+	file_checker_proto_rawDescOnce sync.Once
+	file_checker_proto_rawDescData []byte
+)
+
+func file_checker_proto_rawDescGZIP() []byte {
+	file_checker_proto_rawDescOnce.Do(func() {
+		file_checker_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_checker_proto_rawDesc), len(file_checker_proto_rawDesc)))
+	})
+	return file_checker_proto_rawDescData
+}
+
+var file_checker_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_checker_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_checker_proto_goTypes = []any{
+	(State)(0),                  // 0: healthcheck.v1.State
+	(*CheckRequest)(nil),        // 1: healthcheck.v1.CheckRequest
+	(*CheckResult)(nil),         // 2: healthcheck.v1.CheckResult
+	(*durationpb.Duration)(nil), // 3: google.protobuf.Duration
+}
+var file_checker_proto_depIdxs = []int32{
+	0, // 0: healthcheck.v1.CheckResult.state:type_name -> healthcheck.v1.State
+	3, // 1: healthcheck.v1.CheckResult.latency:type_name -> google.protobuf.Duration
+	2, // 2: healthcheck.v1.CheckResult.members:type_name -> healthcheck.v1.CheckResult
+	1, // 3: healthcheck.v1.Checker.Check:input_type -> healthcheck.v1.CheckRequest
+	2, // 4: healthcheck.v1.Checker.Check:output_type -> healthcheck.v1.CheckResult
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_checker_proto_init() }
+func file_checker_proto_init() {
+	if File_checker_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_checker_proto_rawDesc), len(file_checker_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_checker_proto_goTypes,
+		DependencyIndexes: file_checker_proto_depIdxs,
+		EnumInfos:         file_checker_proto_enumTypes,
+		MessageInfos:      file_checker_proto_msgTypes,
+	}.Build()
+	File_checker_proto = out.File
+	file_checker_proto_goTypes = nil
+	file_checker_proto_depIdxs = nil
+}
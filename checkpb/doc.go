@@ -0,0 +1,5 @@
+// Package checkpb holds the gRPC API of the checker, generated from
+// checker.proto.
+package checkpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative checker.proto
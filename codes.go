@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrorCode is the stable code of a class of errors, reported with failed
+// results in every output so that automation can branch on it rather than
+// on messages, which may change. Codes are never reused for another class.
+type ErrorCode string
+
+// Services files.
+const (
+	CodeInvalidURL  ErrorCode = "HC001" // see URLError
+	CodeInvalidLine ErrorCode = "HC002" // see ParseError
+	CodeLineTooLong ErrorCode = "HC003" // see LineTooLongError
+)
+
+// Network and TLS.
+const (
+	CodeDNS               ErrorCode = "HC101" // name resolution failed
+	CodeTimeout           ErrorCode = "HC102" // timeout or deadline exceeded
+	CodeConnectionRefused ErrorCode = "HC103"
+	CodeConnectionReset   ErrorCode = "HC104" // connection reset or closed early
+	CodeTLSHandshake      ErrorCode = "HC105" // handshake or certificate failure
+	CodeTLSVersion        ErrorCode = "HC106" // see TLSError
+	CodeNetwork           ErrorCode = "HC199" // other network errors
+)
+
+// HTTP checks.
+const (
+	CodeStatus      ErrorCode = "HC201" // see StatusError
+	CodeHeader      ErrorCode = "HC202" // see HeaderError
+	CodeBodySize    ErrorCode = "HC203" // see BodySizeError
+	CodeRequestID   ErrorCode = "HC204" // see RequestIDError
+	CodeCORS        ErrorCode = "HC205" // see CORSError
+	CodeGraphQL     ErrorCode = "HC206" // see GraphQLError
+	CodeRange       ErrorCode = "HC207" // see RangeError
+	CodeConditional ErrorCode = "HC208" // see ConditionalError
+	CodeDrift       ErrorCode = "HC209" // see DriftError
+	CodeProbe       ErrorCode = "HC210" // see ProbeError
+	CodeSSE         ErrorCode = "HC211" // see SSEError
+)
+
+// Services depending on others.
+const (
+	CodeDependency ErrorCode = "HC301" // see DependencyError
+	CodeQuorum     ErrorCode = "HC302" // see QuorumError
+)
+
+// Other protocols.
+const (
+	CodeRedis  ErrorCode = "HC401" // see RedisError
+	CodeKafka  ErrorCode = "HC402" // see KafkaError
+	CodeLDAP   ErrorCode = "HC403" // see LDAPError
+	CodeMQTT   ErrorCode = "HC404" // see MQTTError
+	CodeOffset ErrorCode = "HC405" // see OffsetError
+	CodeSQL    ErrorCode = "HC406" // postgres and mysql, see ProtocolError
+	CodeAMQP   ErrorCode = "HC407" // see ProtocolError
+	CodeSMTP   ErrorCode = "HC408" // see ProtocolError
+	CodeSSH    ErrorCode = "HC409" // see ProtocolError
+	CodeFTP    ErrorCode = "HC410" // ftp and sftp, see ProtocolError
+	CodeNTP    ErrorCode = "HC411" // see ProtocolError
+	CodeS3     ErrorCode = "HC412" // see ProtocolError
+)
+
+// protocolCodes map the url schemes of protocolChecks to the code of their
+// errors of no other class.
+var protocolCodes = map[string]ErrorCode{
+	"postgres":   CodeSQL,
+	"postgresql": CodeSQL,
+	"mysql":      CodeSQL,
+	"redis":      CodeRedis,
+	"rediss":     CodeRedis,
+	"kafka":      CodeKafka,
+	"amqp":       CodeAMQP,
+	"amqps":      CodeAMQP,
+	"s3":         CodeS3,
+	"smtp":       CodeSMTP,
+	"smtps":      CodeSMTP,
+	"ssh":        CodeSSH,
+	"ftp":        CodeFTP,
+	"sftp":       CodeFTP,
+	"ntp":        CodeNTP,
+	"ldap":       CodeLDAP,
+	"ldaps":      CodeLDAP,
+	"mqtt":       CodeMQTT,
+	"mqtts":      CodeMQTT,
+	"mqtt+ws":    CodeMQTT,
+	"mqtt+wss":   CodeMQTT,
+}
+
+// CodeUnknown is the code of the errors of no other class.
+const CodeUnknown ErrorCode = "HC900"
+
+// CodedError is implemented by the errors of the checker with a code of
+// their own.
+type CodedError interface {
+	error
+	ErrorCode() ErrorCode
+}
+
+// ErrorCodeOf return the code of err, that of the first CodedError in its
+// chain or else of its network or TLS cause, CodeUnknown when it has none
+// and "" when err is nil.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var coded CodedError
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	var (
+		urlErr       *url.Error
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		opErr        *net.OpError
+		verifyErr    *tls.CertificateVerificationError
+		alertErr     tls.AlertError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &urlErr) && urlErr.Op == "parse":
+		return CodeInvalidURL
+	case errors.As(err, &dnsErr):
+		return CodeDNS
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		return CodeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CodeConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return CodeConnectionReset
+	case errors.As(err, &verifyErr) || errors.As(err, &alertErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr):
+		return CodeTLSHandshake
+	case errors.As(err, &opErr):
+		return CodeNetwork
+	}
+	return CodeUnknown
+}
+
+// ErrorCode return the code of the error of the line, CodeInvalidLine when
+// it has none.
+func (e *ParseError) ErrorCode() ErrorCode {
+	if code := ErrorCodeOf(e.Err); code != CodeUnknown {
+		return code
+	}
+	return CodeInvalidLine
+}
+
+// ErrorCode return the code of the error of the service, CodeInvalidLine
+// when it has none.
+func (e *ServiceError) ErrorCode() ErrorCode {
+	if code := ErrorCodeOf(e.Err); code != CodeUnknown {
+		return code
+	}
+	return CodeInvalidLine
+}
+
+// ErrorCode return the code of the error of the check, that of the class
+// of its protocol when it has none.
+func (e *ProtocolError) ErrorCode() ErrorCode {
+	if code := ErrorCodeOf(e.Err); code != CodeUnknown {
+		return code
+	}
+	if code, ok := protocolCodes[e.Protocol]; ok {
+		return code
+	}
+	return CodeUnknown
+}
+
+// ErrorCode return the code of the error reading the stream, CodeSSE when
+// it has none.
+func (e *SSEError) ErrorCode() ErrorCode {
+	if code := ErrorCodeOf(e.Err); e.Err != nil && code != CodeUnknown {
+		return code
+	}
+	return CodeSSE
+}
+
+func (e *URLError) ErrorCode() ErrorCode         { return CodeInvalidURL }
+func (e *LineTooLongError) ErrorCode() ErrorCode { return CodeLineTooLong }
+func (e *TLSError) ErrorCode() ErrorCode         { return CodeTLSVersion }
+func (e *StatusError) ErrorCode() ErrorCode      { return CodeStatus }
+func (e *HeaderError) ErrorCode() ErrorCode      { return CodeHeader }
+func (e *BodySizeError) ErrorCode() ErrorCode    { return CodeBodySize }
+func (e *RequestIDError) ErrorCode() ErrorCode   { return CodeRequestID }
+func (e *CORSError) ErrorCode() ErrorCode        { return CodeCORS }
+func (e *GraphQLError) ErrorCode() ErrorCode     { return CodeGraphQL }
+func (e *RangeError) ErrorCode() ErrorCode       { return CodeRange }
+func (e *ConditionalError) ErrorCode() ErrorCode { return CodeConditional }
+func (e *DriftError) ErrorCode() ErrorCode       { return CodeDrift }
+func (e *ProbeError) ErrorCode() ErrorCode       { return CodeProbe }
+func (e *DependencyError) ErrorCode() ErrorCode  { return CodeDependency }
+func (e *QuorumError) ErrorCode() ErrorCode      { return CodeQuorum }
+func (e *RedisError) ErrorCode() ErrorCode       { return CodeRedis }
+func (e *KafkaError) ErrorCode() ErrorCode       { return CodeKafka }
+func (e *LDAPError) ErrorCode() ErrorCode        { return CodeLDAP }
+func (e *MQTTError) ErrorCode() ErrorCode        { return CodeMQTT }
+func (e *OffsetError) ErrorCode() ErrorCode      { return CodeOffset }
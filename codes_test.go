@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	_, parseErr := http.NewRequest(http.MethodGet, "http://a.com/%zz", nil)
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{nil, ""},
+		{errors.New("boom"), CodeUnknown},
+		{parseErr, CodeInvalidURL},
+		{&StatusError{Status: 503}, CodeStatus},
+		{fmt.Errorf("check: %w", &HeaderError{Name: "X"}), CodeHeader},
+		{&DependencyError{Dependency: "db", Err: &StatusError{Status: 503}}, CodeDependency},
+		{&KafkaError{Code: kafkaUnknownTopic}, CodeKafka},
+		{&ParseError{Err: errors.New("unknown option")}, CodeInvalidLine},
+		{&ParseError{Err: &LineTooLongError{Limit: 10}}, CodeLineTooLong},
+		{&ParseError{Err: &URLError{URL: "a.com", Reason: "missing scheme"}}, CodeInvalidURL},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "a.invalid"}}, CodeDNS},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), CodeTimeout},
+		{x509.UnknownAuthorityError{}, CodeTLSHandshake},
+		{&SSEError{Reason: "stream ended without an event"}, CodeSSE},
+		{&SSEError{Reason: "no event", Err: context.DeadlineExceeded}, CodeTimeout},
+		{&ProtocolError{Protocol: "ssh", Err: errors.New("ssh banner: no version line")}, CodeSSH},
+		{&ProtocolError{Protocol: "postgres", Err: errors.New("pq: password authentication failed")}, CodeSQL},
+		{&ProtocolError{Protocol: "ntp", Err: context.DeadlineExceeded}, CodeTimeout},
+		{&ProtocolError{Protocol: "redis", Err: &RedisError{Command: "PING", Reply: "NOAUTH"}}, CodeRedis},
+	}
+	for _, test := range tests {
+		if got := ErrorCodeOf(test.err); got != test.want {
+			t.Errorf("ErrorCodeOf(%v) = %q; want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestErrorCodeOfChecks(t *testing.T) {
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	c := newChecker(WithRetries(0), WithBodyPreview(0))
+	for url, want := range map[string]ErrorCode{
+		tlsSrv.URL: CodeTLSHandshake,
+		closed.URL: CodeConnectionRefused,
+	} {
+		if res := c.check(context.Background(), Service{URL: url}); ErrorCodeOf(res.Err) != want {
+			t.Errorf("%s: got %q (%v); want %q", url, ErrorCodeOf(res.Err), res.Err, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultSlowdown is the latency increase, in percent, beyond which a
+// service is reported slower than in its baseline.
+const DefaultSlowdown = 50
+
+// delta pair the results of a service in two runs, Before or After being
+// nil when the service is only in one of them.
+type delta struct {
+	Key    string
+	Before *jsonResult
+	After  *jsonResult
+}
+
+// latencyChange return the change of latency in percent, and whether it
+// can be computed: when the service answered in both runs.
+func (d delta) latencyChange() (float64, bool) {
+	if d.Before == nil || d.After == nil || d.Before.State != "up" || d.After.State != "up" || d.Before.LatencyMS == 0 {
+		return 0, false
+	}
+	return 100 * (d.After.LatencyMS - d.Before.LatencyMS) / d.Before.LatencyMS, true
+}
+
+// failing report whether the service is down after and was not before.
+func (d delta) failing() bool {
+	return d.After != nil && isDown(d.After.State) && (d.Before == nil || !isDown(d.Before.State))
+}
+
+// recovered report whether the service was down before and is up after.
+func (d delta) recovered() bool {
+	return d.Before != nil && d.After != nil && isDown(d.Before.State) && d.After.State == "up"
+}
+
+func isDown(state string) bool {
+	return state == "down" || state == "dependency"
+}
+
+// diffResults pair the results of before and after by service, in the
+// order of after then of the services only in before.
+func diffResults(before, after []jsonResult) []delta {
+	key := func(j *jsonResult) string {
+		if j.Name != "" {
+			return j.Name
+		}
+		return j.URL
+	}
+	index := make(map[string]*jsonResult, len(before))
+	for i := range before {
+		index[key(&before[i])] = &before[i]
+	}
+	deltas := make([]delta, 0, len(after))
+	seen := make(map[string]bool, len(after))
+	for i := range after {
+		k := key(&after[i])
+		deltas = append(deltas, delta{Key: k, Before: index[k], After: &after[i]})
+		seen[k] = true
+	}
+	for i := range before {
+		if k := key(&before[i]); !seen[k] {
+			deltas = append(deltas, delta{Key: k, Before: &before[i]})
+			seen[k] = true
+		}
+	}
+	return deltas
+}
+
+// readResultsFile read the results of a file written with -format json.
+func readResultsFile(path string) ([]jsonResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report struct {
+		Results []jsonResult `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return report.Results, nil
+}
+
+// writeBaseline print the regressions of deltas, services newly failing
+// or slower by more than slowdown percent, and the recoveries, and return
+// whether there are regressions.
+func writeBaseline(w io.Writer, deltas []delta, slowdown float64) bool {
+	regressed := false
+	for _, d := range deltas {
+		change, ok := d.latencyChange()
+		switch {
+		case d.failing():
+			regressed = true
+			fmt.Fprintf(w, "Regression: %s is down", d.Key)
+			if d.After.Error != "" {
+				fmt.Fprintf(w, "; Error: %s", d.After.Error)
+			}
+			io.WriteString(w, "\n")
+		case ok && change > slowdown:
+			regressed = true
+			fmt.Fprintf(w, "Regression: %s is %.0f%% slower; Latency: %s -> %s\n",
+				d.Key, change, msString(d.Before.LatencyMS), msString(d.After.LatencyMS))
+		case d.recovered():
+			fmt.Fprintf(w, "Recovery: %s is up again\n", d.Key)
+		}
+	}
+	if !regressed {
+		io.WriteString(w, "No regression from the baseline\n")
+	}
+	return regressed
+}
+
+// msString format milliseconds as a duration rounded to the millisecond.
+func msString(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond).String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBaseline(t *testing.T) {
+	ms := time.Millisecond
+	before := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 100 * ms},
+		{Url: "https://b.com", Status: 200, Latency: 100 * ms},
+		{Url: "https://c.com", Status: 503, Err: &StatusError{Status: 503}},
+		{Name: "d", Url: "https://d.com", Status: 503, Err: &StatusError{Status: 503}},
+		{Url: "https://e.com", Status: 200, Latency: 100 * ms},
+		{Url: "https://gone.com", Status: 200},
+	}
+	after := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 140 * ms},
+		{Url: "https://b.com", Status: 200, Latency: 180 * ms},
+		{Url: "https://c.com", Status: 200, Latency: 10 * ms},
+		{Name: "d", Url: "https://d.com", Status: 503, Err: &StatusError{Status: 503}},
+		{Url: "https://e.com", Status: 500, Err: &StatusError{Status: 500}},
+		{Url: "https://new.com", Status: 200},
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONReport(f, before, summarize(before, 0), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	baseline, err := readResultsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := make([]jsonResult, len(after))
+	for i, res := range after {
+		current[i] = newJSONResult(res)
+	}
+
+	deltas := diffResults(baseline, current)
+	if len(deltas) != 7 || deltas[5].Key != "https://new.com" || deltas[5].Before != nil || deltas[6].Key != "https://gone.com" || deltas[6].After != nil {
+		t.Fatalf("got %+v", deltas)
+	}
+
+	var b strings.Builder
+	if !writeBaseline(&b, deltas, 50) {
+		t.Error("want regressions")
+	}
+	want := "Regression: https://b.com is 80% slower; Latency: 100ms -> 180ms\n" +
+		"Recovery: https://c.com is up again\n" +
+		"Regression: https://e.com is down; Error: unexpected status 500\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	b.Reset()
+	if writeBaseline(&b, deltas[:4], 100) || b.String() != "Recovery: https://c.com is up again\nNo regression from the baseline\n" {
+		t.Errorf("want no regression; got:\n%s", b.String())
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	before := []jsonResult{
+		{URL: "https://a.com", State: "up", Status: 200, LatencyMS: 100},
+		{URL: "https://b.com", State: "up", Status: 200, LatencyMS: 100},
+		{Name: "c", URL: "https://c.com", State: "down", Status: 503, Error: "unexpected status 503"},
+		{URL: "https://gone.com", State: "up", Status: 200},
+	}
+	after := []jsonResult{
+		{URL: "https://a.com", State: "up", Status: 200, LatencyMS: 75},
+		{URL: "https://b.com", State: "down", Error: "timeout"},
+		{Name: "c", URL: "https://c.com", State: "up", Status: 200, LatencyMS: 10},
+		{URL: "https://new.com", State: "up", Status: 204},
+	}
+	var b strings.Builder
+	writeDiff(&b, diffResults(before, after))
+	want := "Service: https://a.com; State: up; Latency: 100ms -> 75ms (-25%)\n" +
+		"Service: https://b.com; State: up -> down; Status: 200 -> 0; Error: timeout\n" +
+		"Service: c; State: down -> up; Status: 503 -> 200\n" +
+		"Service: https://new.com; Added; State: up\n" +
+		"Service: https://gone.com; Removed; State: up\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slices"
+)
+
+// QuorumError report a composite service with too few members up.
+type QuorumError struct {
+	Up     int
+	Quorum int
+	Total  int
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("%d of %d members up, want %d", e.Up, e.Total, e.Quorum)
+}
+
+// checkQuorum check every member of a composite service concurrently, at
+// most c.workers at once, and aggregate them in a single result, up when at
+// least svc.Quorum members are up. Members share the settings of the
+// composite service.
+func (c *checker) checkQuorum(ctx context.Context, svc Service) Result {
+	ctx, span := tracer.Start(ctx, "checkQuorum", trace.WithAttributes(
+		attribute.String("healthcheck.service", svc.Name),
+		attribute.Int("healthcheck.quorum", svc.Quorum),
+		attribute.StringSlice("healthcheck.members", svc.Members),
+	))
+	defer span.End()
+
+	result := Result{Name: svc.Name, Tags: svc.Tags}
+	if svc.inMaintenance(c.now()) {
+		result.Maintenance = true
+		recordResult(ctx, span, result)
+		return result
+	}
+
+	result.Members = make([]Result, len(svc.Members))
+	sem := make(chan struct{}, c.workers)
+	var wg sync.WaitGroup
+	wg.Add(len(svc.Members))
+	for i, url := range svc.Members {
+		member := svc
+		member.Name, member.URL, member.Members = "", url, nil
+		sem <- struct{}{}
+		go func(i int, member Service) {
+			defer func() { <-sem; wg.Done() }()
+			result.Members[i] = c.checkURL(ctx, member)
+		}(i, member)
+	}
+	wg.Wait()
+
+	// The quorum is reached when the Quorum-th fastest member answers.
+	var latencies []time.Duration
+	for _, member := range result.Members {
+		if member.Up() {
+			latencies = append(latencies, member.Latency)
+		}
+	}
+	span.SetAttributes(attribute.Int("healthcheck.members_up", len(latencies)))
+	if len(latencies) < svc.Quorum {
+		result.Err = &QuorumError{Up: len(latencies), Quorum: svc.Quorum, Total: len(svc.Members)}
+		return result
+	}
+	slices.Sort(latencies)
+	result.Latency = latencies[svc.Quorum-1]
+	return result
+}
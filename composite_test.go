@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckQuorum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	members := []string{srv.URL + "/fast", srv.URL + "/slow", srv.URL + "/down"}
+	c := newChecker()
+
+	res := c.check(context.Background(), Service{Name: "web", Members: members, Quorum: 2})
+	if !res.Up() || len(res.Members) != 3 {
+		t.Fatalf("want web up with 3 members; got %+v", res)
+	}
+	if res.Members[1].Url != members[1] || res.Members[2].Up() {
+		t.Errorf("want member results in order; got %+v", res.Members)
+	}
+	if res.Latency < 50*time.Millisecond {
+		t.Errorf("want the latency of the second fastest member; got %s", res.Latency)
+	}
+
+	res = c.check(context.Background(), Service{Name: "web", Members: members, Quorum: 3})
+	var quorumErr *QuorumError
+	if !errors.As(res.Err, &quorumErr) || quorumErr.Up != 2 {
+		t.Errorf("want a quorum error with 2 members up; got %v", res.Err)
+	}
+}
+
+func TestCheckQuorumWorkers(t *testing.T) {
+	var inFlight, most atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	var members []string
+	for i := range 8 {
+		members = append(members, fmt.Sprintf("%s/%d", srv.URL, i))
+	}
+	res := newChecker(WithWorkers(2)).check(context.Background(), Service{Name: "web", Members: members, Quorum: len(members)})
+	if !res.Up() {
+		t.Fatal(res.Err)
+	}
+	if n := most.Load(); n > 2 {
+		t.Errorf("want at most 2 members checked at once; got %d", n)
+	}
+}
+
+func TestParseComposite(t *testing.T) {
+	svc, err := ParseService("name=web member=https://a1 member=https://a2 member=https://a3 timeout=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Quorum != 3 || len(svc.Members) != 3 {
+		t.Errorf("want a quorum of every member by default; got %d of %d", svc.Quorum, len(svc.Members))
+	}
+
+	for _, line := range []string{
+		"member=https://a1 member=https://a2",
+		"name=web url=https://a member=https://a1",
+		"name=web quorum=3 member=https://a1 member=https://a2",
+		"https://a quorum=1",
+		"name=web scenario=" + writeScenario(t, `{"steps": [{"url": "https://a1"}]}`) + " member=https://a1",
+	} {
+		if _, err := ParseService(line); err == nil {
+			t.Errorf("ParseService(%q): want an error", line)
+		}
+	}
+}
+
+func TestWriteTextComposite(t *testing.T) {
+	results := []Result{{
+		Name:    "web",
+		Latency: 20 * time.Millisecond,
+		Members: []Result{
+			{Url: "https://a1", Status: 200, Latency: 20 * time.Millisecond},
+			{Url: "https://a2", Err: errors.New("timeout")},
+		},
+	}}
+
+	var b strings.Builder
+	writeText(&b, results)
+
+	want := "Service: web; Latency: 20ms\n" +
+		"  Url: https://a1; Status: 200; Latency: 20ms\n" +
+		"  Url: https://a2; Code: HC900; Error: timeout\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// WithAcceptEncoding send encodings as the Accept-Encoding header of checks
+// and record the Content-Encoding of responses with their transferred and
+// decoded sizes, which requires reading response bodies. Without it, the
+// transport negotiates gzip and decodes responses transparently.
+func WithAcceptEncoding(encodings string) Option {
+	return func(c *checker) { c.acceptEncoding = encodings }
+}
+
+// countingReader count the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// measureEncoding read resp.Body and record its encoding and its
+// transferred and decoded sizes in result. Bodies in an unsupported encoding
+// are only counted as transferred. resp.Body is replaced by the first
+// maxProbeBody decoded bytes, for the checks of the body that follow.
+func measureEncoding(resp *http.Response, result *Result) error {
+	raw := &countingReader{r: resp.Body}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	result.ContentEncoding = encoding
+
+	var decoded io.Reader
+	switch encoding {
+	case "", "identity":
+		decoded = raw
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(raw)
+		if err != nil {
+			return fmt.Errorf("gzip body: %w", err)
+		}
+		defer zr.Close()
+		decoded = zr
+	case "deflate":
+		zr, err := zlib.NewReader(raw)
+		if err != nil {
+			return fmt.Errorf("deflate body: %w", err)
+		}
+		defer zr.Close()
+		decoded = zr
+	case "br":
+		decoded = brotli.NewReader(raw)
+	default:
+		_, err := io.Copy(io.Discard, raw)
+		result.BytesTransferred = raw.n
+		return err
+	}
+
+	var kept bytes.Buffer
+	n, err := io.Copy(&kept, io.LimitReader(decoded, maxProbeBody))
+	if err == nil {
+		var rest int64
+		rest, err = io.Copy(io.Discard, decoded)
+		n += rest
+	}
+	// Drain what the decoder left, such as trailing bytes after the stream.
+	io.Copy(io.Discard, raw)
+	resp.Body = io.NopCloser(&kept)
+	result.BytesTransferred, result.BytesDecoded = raw.n, n
+	if err != nil {
+		return fmt.Errorf("%s body: %w", encoding, err)
+	}
+	return nil
+}
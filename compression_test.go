@@ -0,0 +1,65 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestAcceptEncoding(t *testing.T) {
+	body := strings.Repeat("healthy ", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var enc io.WriteCloser
+		switch accept := r.Header.Get("Accept-Encoding"); {
+		case strings.Contains(accept, "br"):
+			w.Header().Set("Content-Encoding", "br")
+			enc = brotli.NewWriter(w)
+		case strings.Contains(accept, "gzip"):
+			w.Header().Set("Content-Encoding", "gzip")
+			enc = gzip.NewWriter(w)
+		default:
+			io.WriteString(w, body)
+			return
+		}
+		io.WriteString(enc, body)
+		enc.Close()
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		accept   string
+		encoding string
+	}{
+		{"br, gzip", "br"},
+		{"gzip", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		c := newChecker(WithAcceptEncoding(tt.accept))
+		res := c.checkURL(context.Background(), Service{URL: srv.URL})
+		if !res.Up() {
+			t.Fatalf("%s: %v", tt.accept, res.Err)
+		}
+		if res.ContentEncoding != tt.encoding {
+			t.Errorf("%s: want encoding %q; got %q", tt.accept, tt.encoding, res.ContentEncoding)
+		}
+		if res.BytesDecoded != int64(len(body)) {
+			t.Errorf("%s: want %d decoded bytes; got %d", tt.accept, len(body), res.BytesDecoded)
+		}
+		compressed := tt.encoding != ""
+		if compressed != (res.BytesTransferred < res.BytesDecoded) {
+			t.Errorf("%s: unexpected %d transferred bytes", tt.accept, res.BytesTransferred)
+		}
+	}
+
+	res := newChecker().checkURL(context.Background(), Service{URL: srv.URL})
+	if res.BytesTransferred != 0 || res.ContentEncoding != "" {
+		t.Errorf("want nothing recorded without accepted encodings; got %+v", res)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ConditionalError report a response which can't be revalidated, or whose
+// revalidation is not answered with 304 Not Modified.
+type ConditionalError struct {
+	Reason string
+}
+
+func (e *ConditionalError) Error() string {
+	return "conditional request: " + e.Reason
+}
+
+// revalidate request the content of resp again with its validators, its
+// ETag as If-None-Match and its Last-Modified date as If-Modified-Since,
+// and return an error unless the response is 304 Not Modified.
+func revalidate(ctx context.Context, client *http.Client, resp *http.Response) error {
+	etag, modified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && modified == "" {
+		return &ConditionalError{Reason: "no ETag or Last-Modified"}
+	}
+	// The request of resp is the last one, after redirects.
+	req := resp.Request.Clone(ctx)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+	again, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer again.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(again.Body, maxProbeBody))
+	if again.StatusCode != http.StatusNotModified {
+		return &ConditionalError{Reason: fmt.Sprintf("got status %d, want 304", again.StatusCode)}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConditional(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "app.js", time.Time{}, strings.NewReader("app"))
+		case "/modified":
+			http.ServeContent(w, r, "app.js", modified, strings.NewReader("app"))
+		case "/uncached":
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("app"))
+		case "/redirect":
+			http.Redirect(w, r, "/etag", http.StatusFound)
+		default:
+			w.Write([]byte("app"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{path: "/etag"},
+		{path: "/modified"},
+		{path: "/redirect"},
+		{path: "/uncached", err: "conditional request: got status 200, want 304"},
+		{path: "/none", err: "conditional request: no ETag or Last-Modified"},
+	}
+	c := newChecker(WithRetries(0))
+	for _, tt := range tests {
+		svc, err := ParseService(srv.URL + tt.path + " conditional=true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := c.check(context.Background(), svc)
+		if tt.err == "" && !res.Up() || tt.err != "" && (res.Err == nil || res.Err.Error() != tt.err) {
+			t.Errorf("%s: want error %q; got %v", tt.path, tt.err, res.Err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Meta keys of Consul services changing their checks.
+const (
+	consulPathMeta   = "healthcheck-path"   // path requested, "/" by default
+	consulSchemeMeta = "healthcheck-scheme" // http by default
+)
+
+// consulOptions select the Consul catalog services to check.
+type consulOptions struct {
+	addr    string
+	token   string
+	service string
+	tag     string
+}
+
+func (o *consulOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "consul-addr", cmp.Or(os.Getenv("CONSUL_HTTP_ADDR"), "http://127.0.0.1:8500"), "Consul HTTP API address")
+	fs.StringVar(&o.token, "consul-token", os.Getenv("CONSUL_HTTP_TOKEN"), "Consul ACL token")
+	fs.StringVar(&o.service, "consul-service", "", "name of the discovered Consul service, all when empty")
+	fs.StringVar(&o.tag, "consul-tag", "", "tag the discovered Consul services must have")
+}
+
+// consulCatalogService is an instance of a service of the Consul catalog.
+type consulCatalogService struct {
+	Address        string
+	ServiceID      string
+	ServiceName    string
+	ServiceAddress string
+	ServicePort    int
+	ServiceTags    []string
+	ServiceMeta    map[string]string
+}
+
+// discoverer return a function listing the instances of the Consul catalog
+// services selected by o.
+func (o *consulOptions) discoverer() (discoverFunc, error) {
+	addr := o.addr
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return func(ctx context.Context) ([]Service, error) {
+		names := []string{o.service}
+		if o.service == "" {
+			var catalog map[string][]string
+			if err := o.get(ctx, addr, "/v1/catalog/services", &catalog); err != nil {
+				return nil, err
+			}
+			names = names[:0]
+			for name, tags := range catalog {
+				if o.tag == "" || slices.Contains(tags, o.tag) {
+					names = append(names, name)
+				}
+			}
+			slices.Sort(names)
+		}
+
+		var discovered []Service
+		for _, name := range names {
+			var instances []consulCatalogService
+			if err := o.get(ctx, addr, "/v1/catalog/service/"+url.PathEscape(name), &instances); err != nil {
+				return nil, err
+			}
+			for _, inst := range instances {
+				if o.tag != "" && !slices.Contains(inst.ServiceTags, o.tag) {
+					continue
+				}
+				discovered = append(discovered, consulService(inst))
+			}
+		}
+		return discovered, nil
+	}, nil
+}
+
+// consulService return the service checking a Consul catalog instance.
+func consulService(inst consulCatalogService) Service {
+	host := cmp.Or(inst.ServiceAddress, inst.Address)
+	if inst.ServicePort != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(inst.ServicePort))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	p := cmp.Or(inst.ServiceMeta[consulPathMeta], "/")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return Service{
+		Name: "consul/" + inst.ServiceName + "/" + inst.ServiceID,
+		URL:  cmp.Or(inst.ServiceMeta[consulSchemeMeta], "http") + "://" + host + p,
+		Tags: append([]string{"consul"}, inst.ServiceTags...),
+	}
+}
+
+// get send a GET request for path to the Consul API at addr and decode the
+// response into v.
+func (o *consulOptions) get(ctx context.Context, addr, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	if o.token != "" {
+		req.Header.Set("X-Consul-Token", o.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("consul discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("consul discovery: %s %s: %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("consul discovery: %s: %w", path, err)
+	}
+	return nil
+}
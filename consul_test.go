@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestConsulDiscovery(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Consul-Token"); got != "secret" {
+			t.Errorf("want token secret; got %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/catalog/services":
+			w.Write([]byte(`{"consul": [], "web": ["prod", "v2"], "db": ["prod"], "cache": ["dev"]}`))
+		case "/v1/catalog/service/web":
+			w.Write([]byte(`[
+				{"Address": "10.0.0.1", "ServiceID": "web-1", "ServiceName": "web", "ServicePort": 8080, "ServiceTags": ["prod", "v2"],
+				 "ServiceMeta": {"healthcheck-path": "healthz"}},
+				{"Address": "10.0.0.2", "ServiceAddress": "web2.a.com", "ServiceID": "web-2", "ServiceName": "web", "ServicePort": 443, "ServiceTags": ["prod"],
+				 "ServiceMeta": {"healthcheck-scheme": "https"}},
+				{"Address": "10.0.0.3", "ServiceID": "web-3", "ServiceName": "web", "ServicePort": 8080, "ServiceTags": ["canary"]}
+			]`))
+		case "/v1/catalog/service/db":
+			w.Write([]byte(`[{"Address": "fd00::1", "ServiceID": "db-1", "ServiceName": "db", "ServiceTags": ["prod"]}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	opts := consulOptions{addr: api.URL, token: "secret", tag: "prod"}
+	discover, err := opts.discoverer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Service{
+		{Name: "consul/db/db-1", URL: "http://[fd00::1]/", Tags: []string{"consul", "prod"}},
+		{Name: "consul/web/web-1", URL: "http://10.0.0.1:8080/healthz", Tags: []string{"consul", "prod", "v2"}},
+		{Name: "consul/web/web-2", URL: "https://web2.a.com:443/", Tags: []string{"consul", "prod"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d services; got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].URL != want[i].URL || slices.Compare(got[i].Tags, want[i].Tags) != 0 {
+			t.Errorf("want %+v; got %+v", want[i], got[i])
+		}
+	}
+
+	opts = consulOptions{addr: api.URL, token: "secret", service: "web"}
+	discover, _ = opts.discoverer()
+	if got, err := discover(context.Background()); err != nil || len(got) != 3 {
+		t.Errorf("want the 3 instances of web; got %d (%v)", len(got), err)
+	}
+
+	opts.service = "missing"
+	discover, _ = opts.discoverer()
+	if _, err := discover(context.Background()); err == nil {
+		t.Error("want an error when the API fails")
+	}
+}
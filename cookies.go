@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// WithCookies give the run a cookie jar shared by every check, so that
+// cookies set by a response, including along redirects, are sent back by
+// later requests.
+func WithCookies(enabled bool) Option {
+	return func(c *checker) { c.cookies = enabled }
+}
+
+// newCookieJar return an empty cookie jar. cookiejar.New only fails on
+// invalid options.
+func newCookieJar() http.CookieJar {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		panic(err)
+	}
+	return jar
+}
+
+// withCookieJar return a copy of client using a jar of its own.
+func withCookieJar(client *http.Client) *http.Client {
+	cp := *client
+	cp.Jar = newCookieJar()
+	return &cp
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sessionServer redirect /login to /health with a session cookie, /health
+// requiring it.
+func sessionServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			http.Redirect(w, r, "/health", http.StatusFound)
+		case "/health":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "s1" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+}
+
+func TestCookies(t *testing.T) {
+	srv := sessionServer()
+	defer srv.Close()
+	svc := Service{URL: srv.URL + "/login"}
+
+	if res := newChecker().checkURL(context.Background(), svc); res.Up() {
+		t.Error("want the session lost without cookie jar")
+	}
+	if res := newChecker(WithCookies(true)).checkURL(context.Background(), svc); !res.Up() {
+		t.Errorf("want the session kept across the redirect; got %v", res.Err)
+	}
+}
+
+func TestScenarioCookies(t *testing.T) {
+	srv := sessionServer()
+	defer srv.Close()
+
+	path := writeScenario(t, `{"cookies": true, "steps": [
+		{"url": "`+srv.URL+`/login"},
+		{"url": "`+srv.URL+`/health"}
+	]}`)
+	svc, err := ParseService("name=session scenario=" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := newChecker().check(context.Background(), svc); !res.Up() {
+		t.Errorf("want the session kept between steps; got %v", res.Err)
+	}
+}
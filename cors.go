@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// corsPreflight describe the CORS preflight request checking a service, as
+// sent by browsers before cross-origin requests: an OPTIONS request from
+// Origin for Method with Headers.
+type corsPreflight struct {
+	Origin  string
+	Method  string
+	Headers []string
+}
+
+// CORSError report a preflight response which does not allow the request a
+// browser would make.
+type CORSError struct {
+	Origin string
+	Reason string
+}
+
+func (e *CORSError) Error() string {
+	return fmt.Sprintf("cors preflight from %s: %s", e.Origin, e.Reason)
+}
+
+// setHeaders set the headers of the preflight request on req.
+func (p *corsPreflight) setHeaders(req *http.Request) {
+	req.Header.Set("Origin", p.Origin)
+	req.Header.Set("Access-Control-Request-Method", p.method())
+	if len(p.Headers) > 0 {
+		req.Header.Set("Access-Control-Request-Headers", strings.ToLower(strings.Join(p.Headers, ",")))
+	}
+}
+
+// method return the method of the preflighted request, GET by default.
+func (p *corsPreflight) method() string {
+	if p.Method == "" {
+		return http.MethodGet
+	}
+	return p.Method
+}
+
+// check return an error unless resp is a successful preflight response,
+// with a 2xx status or one of expect, allowing the origin, method and
+// headers of the request as browsers require.
+func (p *corsPreflight) check(resp *http.Response, expect []int) error {
+	if len(expect) == 0 && resp.StatusCode/100 != 2 {
+		return &StatusError{Status: resp.StatusCode}
+	}
+	if len(expect) > 0 && !slices.Contains(expect, resp.StatusCode) {
+		return &StatusError{Status: resp.StatusCode, Expect: expect}
+	}
+
+	switch origin := resp.Header.Get("Access-Control-Allow-Origin"); origin {
+	case "":
+		return &CORSError{Origin: p.Origin, Reason: "missing Access-Control-Allow-Origin"}
+	case "*", p.Origin:
+	default:
+		return &CORSError{Origin: p.Origin, Reason: fmt.Sprintf("Access-Control-Allow-Origin is %q", origin)}
+	}
+
+	// Safelisted methods are allowed without being listed.
+	method := p.method()
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+	default:
+		allowed := headerList(resp.Header, "Access-Control-Allow-Methods")
+		if !slices.Contains(allowed, "*") && !slices.Contains(allowed, method) {
+			return &CORSError{Origin: p.Origin, Reason: fmt.Sprintf("method %s not in Access-Control-Allow-Methods", method)}
+		}
+	}
+
+	allowed := headerList(resp.Header, "Access-Control-Allow-Headers")
+	for _, name := range p.Headers {
+		if slices.Contains(allowed, "*") {
+			break
+		}
+		if slices.IndexFunc(allowed, func(s string) bool { return strings.EqualFold(s, name) }) < 0 {
+			return &CORSError{Origin: p.Origin, Reason: fmt.Sprintf("header %s not in Access-Control-Allow-Headers", name)}
+		}
+	}
+	return nil
+}
+
+// headerList return the comma separated values of the header name.
+func headerList(h http.Header, name string) []string {
+	var list []string
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Origin") == "https://app.a.com" {
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		options string
+		reason  string
+	}{
+		{options: "cors-origin=https://app.a.com"},
+		{options: "cors-origin=https://app.a.com/ cors-method=put cors-headers=content-type"},
+		{options: "cors-origin=https://evil.com", reason: "missing Access-Control-Allow-Origin"},
+		{options: "cors-origin=https://app.a.com cors-method=DELETE", reason: "method DELETE not in Access-Control-Allow-Methods"},
+		{options: "cors-origin=https://app.a.com cors-method=POST cors-headers=Content-Type,Authorization", reason: "header Authorization not in Access-Control-Allow-Headers"},
+	}
+	c := newChecker(WithRetries(0))
+	for _, tt := range tests {
+		svc, err := ParseService(srv.URL + " " + tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := c.checkURL(context.Background(), svc)
+		var cerr *CORSError
+		switch {
+		case tt.reason == "" && res.Err != nil:
+			t.Errorf("%s: want up; got %v", tt.options, res.Err)
+		case tt.reason != "" && (!errors.As(res.Err, &cerr) || cerr.Reason != tt.reason):
+			t.Errorf("%s: want %q; got %v", tt.options, tt.reason, res.Err)
+		}
+	}
+}
+
+func TestParseServiceCORS(t *testing.T) {
+	for _, line := range []string{
+		"https://a.com cors-method=PUT",
+		"https://a.com cors-origin=app.a.com",
+		"https://a.com cors-origin=https://app.a.com/path",
+		"https://a.com cors-origin=https://app.a.com cors-headers=a,,b",
+		"https://a.com cors-origin=https://app.a.com module=http_2xx",
+	} {
+		if _, err := ParseService(line); err == nil {
+			t.Errorf("%s: want an error", line)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(dashboardHTML))
+
+// Size of the latency charts of the dashboard, in pixels.
+const (
+	chartWidth  = 240
+	chartHeight = 32
+)
+
+// dashboardPage is the data of the dashboard template.
+type dashboardPage struct {
+	Updated time.Time
+	Summary tally
+	Query   string
+	Tag     string
+	State   string
+	Tags    []string
+	Rows    []dashboardRow
+}
+
+// dashboardRow describe a service in the dashboard table.
+type dashboardRow struct {
+	Key    string
+	State  string
+	Latest record
+	Uptime float64 // percentage of the recorded checks which were up
+	Chart  chart
+}
+
+// chart is the latency history of a service, drawn as a polyline of the
+// successful checks with a mark for each failed one.
+type chart struct {
+	Width, Height int
+	Points        string
+	Failures      []int // x of failed checks
+}
+
+// state return the state of a result shown by the dashboard.
+func state(res Result) string {
+	switch {
+	case res.Maintenance:
+		return "maintenance"
+	case res.Up():
+		return "up"
+	case res.DependencyDown():
+		return "dependency"
+	}
+	return "down"
+}
+
+// dashboardHandler serve the dashboard of the results in st. The table can
+// be filtered by the q (part of the name or url), tag and state query
+// parameters.
+func dashboardHandler(st *store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latest, updated := st.latest()
+		page := dashboardPage{
+			Updated: updated,
+			Query:   r.URL.Query().Get("q"),
+			Tag:     r.URL.Query().Get("tag"),
+			State:   r.URL.Query().Get("state"),
+		}
+		for _, rec := range latest {
+			page.Summary.add(rec.Result)
+			for _, tag := range rec.Tags {
+				if !slices.Contains(page.Tags, tag) {
+					page.Tags = append(page.Tags, tag)
+				}
+			}
+			if !page.matches(rec.Result) {
+				continue
+			}
+			history := st.records(resultKey(rec.Result))
+			page.Rows = append(page.Rows, dashboardRow{
+				Key:    resultKey(rec.Result),
+				State:  state(rec.Result),
+				Latest: rec,
+				Uptime: uptime(history),
+				Chart:  newChart(history, st.limit),
+			})
+		}
+		slices.Sort(page.Tags)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// matches report whether res passes the filters of the page.
+func (p dashboardPage) matches(res Result) bool {
+	q := strings.ToLower(p.Query)
+	switch {
+	case q != "" && !strings.Contains(strings.ToLower(res.Name), q) && !strings.Contains(strings.ToLower(res.Url), q):
+		return false
+	case p.Tag != "" && !slices.Contains(res.Tags, p.Tag):
+		return false
+	case p.State != "" && state(res) != p.State:
+		return false
+	}
+	return true
+}
+
+// uptime return the percentage of records which were up, services in
+// maintenance being left out.
+func uptime(history []record) float64 {
+	var checked, up int
+	for _, rec := range history {
+		if rec.Maintenance {
+			continue
+		}
+		checked++
+		if rec.Up() {
+			up++
+		}
+	}
+	if checked == 0 {
+		return 100
+	}
+	return 100 * float64(up) / float64(checked)
+}
+
+// newChart draw history on a chart holding up to limit records, the most
+// recent on the right.
+func newChart(history []record, limit int) chart {
+	c := chart{Width: chartWidth, Height: chartHeight}
+	var highest time.Duration
+	for _, rec := range history {
+		highest = max(highest, rec.Latency)
+	}
+	step := float64(chartWidth) / float64(max(limit-1, 1))
+	offset := limit - len(history)
+	var points []string
+	for i, rec := range history {
+		x := int(float64(offset+i) * step)
+		switch {
+		case rec.Maintenance:
+		case !rec.Up():
+			c.Failures = append(c.Failures, x)
+		default:
+			y := chartHeight
+			if highest > 0 {
+				y -= int(float64(rec.Latency) / float64(highest) * (chartHeight - 2))
+			}
+			points = append(points, fmt.Sprintf("%d,%d", x, y))
+		}
+	}
+	c.Points = strings.Join(points, " ")
+	return c
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDashboard(t *testing.T) {
+	s := newServer(newChecker(), 10)
+	st := s.store
+	t0 := time.Unix(0, 0)
+	st.add([]Result{
+		{Url: "https://a.com", Tags: []string{"prod"}, Status: 200, Latency: 10 * time.Millisecond},
+		{Name: "checkout", Url: "https://b.com", Tags: []string{"payments"}, Err: errors.New("connection refused")},
+	}, t0)
+	st.add([]Result{
+		{Url: "https://a.com", Tags: []string{"prod"}, Err: &StatusError{Status: 503}, Status: 503},
+		{Name: "checkout", Url: "https://b.com", Tags: []string{"payments"}, Status: 200, Latency: 20 * time.Millisecond},
+	}, t0.Add(time.Minute))
+
+	get := func(query string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: want status 200; got %d", query, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	page := get("")
+	for _, want := range []string{
+		"1 up</span>", "1 down</span>",
+		"https://a.com", "checkout", "unexpected status 503",
+		`<polyline points="240,2"/>`, // checkout: down then up
+		`<line x1="213" x2="213"`,
+		"50.0%",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("want %q in the dashboard", want)
+		}
+	}
+
+	if page := get("?tag=payments"); strings.Contains(page, "https://a.com") || !strings.Contains(page, "checkout") {
+		t.Error("want only checkout with tag payments")
+	}
+	if page := get("?state=down"); strings.Contains(page, "checkout") || !strings.Contains(page, "https://a.com") {
+		t.Error("want only a.com when down")
+	}
+	if page := get("?q=CHECK"); strings.Contains(page, "https://a.com") {
+		t.Error("want only checkout matching check")
+	}
+}
+
+func TestServe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s := newServer(newChecker(), 10)
+	st := s.store
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx, func(context.Context) []Service { return []Service{{URL: srv.URL}} }, 10*time.Millisecond)
+	}()
+	for len(st.records(srv.URL)) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if latest, _ := st.latest(); !latest[0].Up() {
+		t.Errorf("want the service up; got %v", latest[0].Err)
+	}
+}
+
+func TestServeForget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s := newServer(newChecker(), 10)
+	var removed atomic.Bool
+	list := func(context.Context) []Service {
+		if removed.Load() {
+			return []Service{{URL: srv.URL + "/a"}}
+		}
+		return []Service{{URL: srv.URL + "/a"}, {Name: "b", URL: srv.URL + "/b"}}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run(ctx, list, 10*time.Millisecond)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	for len(s.store.records("b")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	removed.Store(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		latest, _ := s.store.latest()
+		if len(latest) == 1 && latest[0].Url == srv.URL+"/a" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want b forgotten once removed; got %+v", latest)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
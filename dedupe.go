@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizeURL return rawURL with a lowercase host, without fragment and
+// without the default port of its scheme. Urls which do not parse are
+// returned unchanged.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+// normalizeURLs return a normalized copy of urls.
+func normalizeURLs(urls []string) []string {
+	if urls == nil {
+		return nil
+	}
+	normalized := make([]string, len(urls))
+	for i, u := range urls {
+		normalized[i] = normalizeURL(u)
+	}
+	return normalized
+}
+
+// dedupe normalize the urls of services and drop the services with the same
+// name and url as a previous one, returning the number dropped.
+func dedupe(services []Service) ([]Service, int) {
+	type key struct{ name, url string }
+	seen := make(map[key]bool, len(services))
+	kept := services[:0:0]
+	for _, svc := range services {
+		svc.URL = normalizeURL(svc.URL)
+		svc.Members = normalizeURLs(svc.Members)
+		// Dependencies given by url must match the normalized urls.
+		svc.Depends = normalizeURLs(svc.Depends)
+		// Composite and scenario services have no url, their name tells
+		// them apart.
+		k := key{svc.Name, svc.URL}
+		if svc.URL != "" && seen[k] {
+			continue
+		}
+		seen[k] = true
+		kept = append(kept, svc)
+	}
+	return kept, len(services) - len(kept)
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"https://A.com", "https://a.com"},
+		{"https://a.com:443/x#top", "https://a.com/x"},
+		{"http://a.com:80/", "http://a.com/"},
+		{"http://a.com:443/", "http://a.com:443/"},
+		{"https://[::1]:443/", "https://[::1]/"},
+		{"https://[::1]:8443/", "https://[::1]:8443/"},
+		{"HTTPS://a.com/Path?Q=1", "https://a.com/Path?Q=1"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.in); got != tt.want {
+			t.Errorf("%s: want %s; got %s", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	services := []Service{
+		{URL: "https://a.com"},
+		{URL: "https://A.com:443#x"},
+		{Name: "a", URL: "https://a.com", Depends: []string{"db", "https://B.com"}},
+		{URL: "https://b.com"},
+		{Name: "web", Members: []string{"https://WEB1.a.com"}},
+		{Name: "web2", Members: []string{"https://web1.a.com"}},
+	}
+	got, n := dedupe(services)
+	if n != 1 || len(got) != 5 {
+		t.Fatalf("want 1 duplicate skipped; got %d %+v", n, got)
+	}
+	if got[4].Members[0] != "https://web1.a.com" || services[4].Members[0] != "https://WEB1.a.com" {
+		t.Errorf("want members normalized on a copy; got %v and %v", got[4].Members, services[4].Members)
+	}
+	if d := got[1].Depends; d[0] != "db" || d[1] != "https://b.com" {
+		t.Errorf("want dependency urls normalized; got %v", d)
+	}
+}
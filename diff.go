@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runDiff implement the diff subcommand, printing the changes between two
+// results files written with -format json, and return the exit code:
+// exitFailed when a service is down after and was not before.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck diff before.json after.json")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+	before, err := readResultsFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	after, err := readResultsFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	deltas := diffResults(before, after)
+	writeDiff(os.Stdout, deltas)
+	for _, d := range deltas {
+		if d.failing() {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// writeDiff print a line per service of deltas: its state, status and
+// latency before and after, or whether it was added or removed.
+func writeDiff(w io.Writer, deltas []delta) {
+	for _, d := range deltas {
+		fmt.Fprintf(w, "Service: %s", d.Key)
+		switch {
+		case d.Before == nil:
+			fmt.Fprintf(w, "; Added; State: %s", d.After.State)
+		case d.After == nil:
+			fmt.Fprintf(w, "; Removed; State: %s", d.Before.State)
+		default:
+			if d.Before.State != d.After.State {
+				fmt.Fprintf(w, "; State: %s -> %s", d.Before.State, d.After.State)
+			} else {
+				fmt.Fprintf(w, "; State: %s", d.After.State)
+			}
+			if d.Before.Status != d.After.Status {
+				fmt.Fprintf(w, "; Status: %d -> %d", d.Before.Status, d.After.Status)
+			}
+			if change, ok := d.latencyChange(); ok {
+				fmt.Fprintf(w, "; Latency: %s -> %s (%+.0f%%)", msString(d.Before.LatencyMS), msString(d.After.LatencyMS), change)
+			}
+			if d.After.Error != "" && d.After.Error != d.Before.Error {
+				fmt.Fprintf(w, "; Error: %s", d.After.Error)
+			}
+		}
+		io.WriteString(w, "\n")
+	}
+}
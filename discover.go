@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// discoverFunc list services registered in a service registry.
+type discoverFunc func(ctx context.Context) ([]Service, error)
+
+// discovery hold the options of service discovery, shared by the commands
+// checking services.
+type discovery struct {
+	source string
+	k8s    k8sOptions
+	consul consulOptions
+	docker dockerOptions
+
+	// sitemap are the options of a sitemap: services file argument.
+	sitemap sitemapOptions
+}
+
+// register define the discovery flags on fs.
+func (d *discovery) register(fs *flag.FlagSet) {
+	fs.StringVar(&d.source, "discover", "", "discover services to check in addition to the services file: k8s, consul or docker")
+	d.k8s.register(fs)
+	d.consul.register(fs)
+	d.docker.register(fs)
+	d.sitemap.register(fs)
+}
+
+// enabled report whether services are discovered.
+func (d *discovery) enabled() bool {
+	return d.source != ""
+}
+
+// discoverer return the function discovering services.
+func (d *discovery) discoverer() (discoverFunc, error) {
+	switch d.source {
+	case "k8s":
+		return d.k8s.discoverer()
+	case "consul":
+		return d.consul.discoverer()
+	case "docker":
+		return d.docker.discoverer()
+	}
+	return nil, fmt.Errorf("unknown discovery %q", d.source)
+}
+
+// loadServices return the services of path, when not empty, and the discovered
+// ones, filtered by tags. Lines of path longer than maxLine bytes are
+// invalid and templates are rendered against the variables of varsFile, see
+// readServices. Services which cannot be discovered are reported on stderr
+// and left out.
+func loadServices(ctx context.Context, path string, maxLine int, varsFile string, discover discoverFunc, tags []string) ([]Service, error) {
+	var all []Service
+	if path != "" {
+		listed, err := readServices(path, maxLine, varsFile)
+		if err != nil {
+			return nil, err
+		}
+		all = listed
+	}
+	if discover != nil {
+		discovered, err := discover(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		all = append(all, discovered...)
+	}
+	return filterByTags(all, tags), nil
+}
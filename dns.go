@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResolvers are the resolvers the dns subcommand compares by
+// default, system being that of the host.
+const DefaultResolvers = "8.8.8.8,1.1.1.1,system"
+
+// resolverSystem is the name of the resolver of the host.
+const resolverSystem = "system"
+
+// runDNS implement the dns subcommand, resolving the hostnames of the
+// services with several resolvers and reporting those they disagree on,
+// and return the exit code: exitFailed when resolvers disagree or fail.
+func runDNS(args []string) int {
+	fs := flag.NewFlagSet("dns", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck dns [flags] services.txt")
+		fs.PrintDefaults()
+	}
+	resolvers := fs.String("resolvers", DefaultResolvers, "comma separated list of the resolvers compared, as host[:port] or system")
+	timeout := fs.Duration("timeout", DefaultTimeout, "time allowed for each lookup")
+	varsFile := fs.String("vars-file", "", "YAML or JSON file of the variables the lines of the services file containing {{ are rendered against, a service per combination of their values")
+	var tags []string
+	fs.Func("tags", "comma separated list of tags, only services with one of them are resolved", func(s string) error {
+		tags = append(tags, strings.Split(s, ",")...)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "missing file argument")
+		return exitError
+	}
+	var list []dnsResolver
+	for _, addr := range strings.Split(*resolvers, ",") {
+		r, err := newDNSResolver(strings.TrimSpace(addr))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		list = append(list, r)
+	}
+
+	services, err := readServices(fs.Arg(0), DefaultMaxLineLength, *varsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	props := checkPropagation(context.Background(), hostnames(filterByTags(services, tags)), list, *timeout)
+	writePropagation(os.Stdout, props)
+	for _, p := range props {
+		if !p.consistent() {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// dnsResolver resolve hostnames into their sorted addresses.
+type dnsResolver struct {
+	name   string
+	lookup func(ctx context.Context, host string) ([]string, error)
+}
+
+// newDNSResolver return the resolver at addr, host[:port] with port 53 by
+// default, or that of the host for system.
+func newDNSResolver(addr string) (dnsResolver, error) {
+	if addr == resolverSystem {
+		return dnsResolver{name: addr, lookup: net.DefaultResolver.LookupHost}, nil
+	}
+	server := addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		server = net.JoinHostPort(addr, "53")
+	}
+	if host, _, _ := net.SplitHostPort(server); host == "" {
+		return dnsResolver{}, fmt.Errorf("invalid resolver %q", addr)
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return dnsResolver{name: addr, lookup: r.LookupHost}, nil
+}
+
+// hostnames return the sorted hostnames of the urls of services, without
+// ip addresses, srv urls and scenarios.
+func hostnames(services []Service) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	add := func(rawURL string) {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Scheme == srvScheme {
+			return
+		}
+		host := u.Hostname()
+		if host == "" || net.ParseIP(host) != nil || seen[host] {
+			return
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	for _, svc := range services {
+		add(svc.URL)
+		for _, member := range svc.Members {
+			add(member)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// propagation is what resolvers answered for a hostname.
+type propagation struct {
+	Host    string
+	Answers []dnsAnswer
+}
+
+// dnsAnswer is the answer of a resolver: the sorted addresses, NXDOMAIN,
+// or the error of the lookup.
+type dnsAnswer struct {
+	Resolver string
+	Addrs    []string
+	NotFound bool
+	Err      error
+}
+
+func (a dnsAnswer) String() string {
+	switch {
+	case a.NotFound:
+		return "NXDOMAIN"
+	case a.Err != nil:
+		return "error: " + a.Err.Error()
+	}
+	return strings.Join(a.Addrs, ", ")
+}
+
+// consistent report whether every resolver answered, and the same.
+func (p propagation) consistent() bool {
+	for _, a := range p.Answers {
+		if (a.Err != nil && !a.NotFound) || a.String() != p.Answers[0].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPropagation resolve every host with every resolver, concurrently.
+func checkPropagation(ctx context.Context, hosts []string, resolvers []dnsResolver, timeout time.Duration) []propagation {
+	props := make([]propagation, len(hosts))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, DefaultWorkers)
+	for i, host := range hosts {
+		props[i] = propagation{Host: host, Answers: make([]dnsAnswer, len(resolvers))}
+		for j, r := range resolvers {
+			wg.Add(1)
+			go func(a *dnsAnswer) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				addrs, err := r.lookup(ctx, host)
+				sort.Strings(addrs)
+				var dnsErr *net.DNSError
+				*a = dnsAnswer{Resolver: r.name, Addrs: addrs, Err: err, NotFound: errors.As(err, &dnsErr) && dnsErr.IsNotFound}
+			}(&props[i].Answers[j])
+		}
+	}
+	wg.Wait()
+	return props
+}
+
+// writePropagation print a line per host, followed by the answer of each
+// resolver unless they all agree.
+func writePropagation(w io.Writer, props []propagation) {
+	for _, p := range props {
+		if p.consistent() {
+			fmt.Fprintf(w, "Host: %s; Consistent; %s\n", p.Host, p.Answers[0])
+			continue
+		}
+		fmt.Fprintf(w, "Host: %s; Disagreement\n", p.Host)
+		for _, a := range p.Answers {
+			fmt.Fprintf(w, "  %s: %s\n", a.Resolver, a)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHostnames(t *testing.T) {
+	services := []Service{
+		{URL: "https://b.com/health"},
+		{URL: "https://a.com:8443/"},
+		{URL: "https://b.com/other"},
+		{URL: "https://203.0.113.10/"},
+		{URL: "srv://_https._tcp.c.com"},
+		{Name: "web", Members: []string{"https://web1.a.com", "https://web2.a.com"}},
+	}
+	got := hostnames(services)
+	want := []string{"a.com", "b.com", "web1.a.com", "web2.a.com"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q; want %q", got, want)
+		}
+	}
+}
+
+func TestCheckPropagation(t *testing.T) {
+	static := func(name string, answers map[string][]string) dnsResolver {
+		return dnsResolver{name: name, lookup: func(_ context.Context, host string) ([]string, error) {
+			addrs, ok := answers[host]
+			if !ok {
+				return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return append([]string(nil), addrs...), nil
+		}}
+	}
+	resolvers := []dnsResolver{
+		static("8.8.8.8", map[string][]string{"a.com": {"203.0.113.2", "203.0.113.1"}, "b.com": {"203.0.113.10"}}),
+		static("1.1.1.1", map[string][]string{"a.com": {"203.0.113.1", "203.0.113.2"}, "b.com": {"198.51.100.4"}}),
+		static("internal", map[string][]string{"a.com": {"203.0.113.1", "203.0.113.2"}, "b.com": {"203.0.113.10"}}),
+	}
+	props := checkPropagation(context.Background(), []string{"a.com", "b.com", "gone.com"}, resolvers, time.Second)
+	if !props[0].consistent() || props[1].consistent() || !props[2].consistent() {
+		t.Errorf("got consistent %v, %v, %v; want true, false, true", props[0].consistent(), props[1].consistent(), props[2].consistent())
+	}
+
+	var buf bytes.Buffer
+	writePropagation(&buf, props)
+	want := `Host: a.com; Consistent; 203.0.113.1, 203.0.113.2
+Host: b.com; Disagreement
+  8.8.8.8: 203.0.113.10
+  1.1.1.1: 198.51.100.4
+  internal: 203.0.113.10
+Host: gone.com; Consistent; NXDOMAIN
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestNewDNSResolver(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "10.0.0.2:5353", "[2001:4860:4860::8888]:53", "system"} {
+		if _, err := newDNSResolver(addr); err != nil {
+			t.Errorf("%s: %v", addr, err)
+		}
+	}
+	if _, err := newDNSResolver(""); err == nil {
+		t.Error("empty address: want an error")
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// defaultDNSTTL is how long the addresses of a host are cached when the
+// TTL of its records is unknown, such as for hosts of /etc/hosts or
+// answers over TCP.
+const defaultDNSTTL = 30 * time.Second
+
+// WithDNSCache cache the addresses of hosts for the TTL of their records,
+// so that checking many urls of the same hosts resolves each host once and
+// lookups don't weigh on latencies. Without it, every new connection
+// resolves its host.
+func WithDNSCache(enabled bool) Option {
+	return func(c *checker) {
+		c.dnsCache = nil
+		if enabled {
+			c.dnsCache = newDNSCache()
+		}
+	}
+}
+
+// dnsCache cache the addresses of hosts until their TTL expires. Expired
+// entries are swept every defaultDNSTTL, on lookups, so that checking
+// changing hosts does not grow the cache forever.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	swept   time.Time
+	now     func() time.Time
+	// server overrides the name server of the host, for tests.
+	server string
+}
+
+// dnsEntry is the outcome of the lookup of a host, known once ready is
+// closed.
+type dnsEntry struct {
+	ready   chan struct{}
+	ips     []net.IP
+	err     error
+	expires time.Time
+}
+
+func newDNSCache() *dnsCache {
+	return &dnsCache{entries: make(map[string]*dnsEntry), now: time.Now}
+}
+
+// lookup return the addresses of host, resolving it unless they are
+// cached, from source when not nil. Concurrent lookups of a host share a
+// single resolution. Failed lookups are not cached.
+func (dc *dnsCache) lookup(ctx context.Context, host string, source net.IP) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	dc.mu.Lock()
+	if now := dc.now(); !now.Before(dc.swept.Add(defaultDNSTTL)) {
+		dc.sweep(now)
+	}
+	e, ok := dc.entries[host]
+	if ok {
+		select {
+		case <-e.ready:
+			if e.err != nil || !dc.now().Before(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &dnsEntry{ready: make(chan struct{})}
+		dc.entries[host] = e
+		dc.mu.Unlock()
+		var ttl time.Duration
+		e.ips, ttl, e.err = dc.resolve(ctx, host, source)
+		e.expires = dc.now().Add(ttl)
+		close(e.ready)
+		return e.ips, e.err
+	}
+	dc.mu.Unlock()
+	select {
+	case <-e.ready:
+		return e.ips, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// sweep remove the entries expired at now. dc.mu must be held.
+func (dc *dnsCache) sweep(now time.Time) {
+	for host, e := range dc.entries {
+		select {
+		case <-e.ready:
+			if e.err != nil || !now.Before(e.expires) {
+				delete(dc.entries, host)
+			}
+		default:
+		}
+	}
+	dc.swept = now
+}
+
+// resolve return the addresses of host with the least TTL of the records
+// of the answers, read from the DNS messages of the lookup, querying the
+// name servers from source when not nil.
+func (dc *dnsCache) resolve(ctx context.Context, host string, source net.IP) ([]net.IP, time.Duration, error) {
+	var mu sync.Mutex
+	ttl, known := defaultDNSTTL, false
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if dc.server != "" {
+				addr = dc.server
+			}
+			var d net.Dialer
+			if source != nil {
+				if strings.HasPrefix(network, "udp") {
+					d.LocalAddr = &net.UDPAddr{IP: source}
+				} else {
+					d.LocalAddr = &net.TCPAddr{IP: source}
+				}
+			}
+			conn, err := d.DialContext(ctx, network, addr)
+			udp, ok := conn.(*net.UDPConn)
+			if !ok {
+				return conn, err
+			}
+			return &ttlConn{UDPConn: udp, record: func(d time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				if !known || d < ttl {
+					ttl, known = d, true
+				}
+			}}, nil
+		},
+	}
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return ips, ttl, nil
+}
+
+// ttlConn pass the TTL of the address records of each DNS message read
+// from a UDP connection to record. It remains a net.PacketConn, which the
+// resolver tells UDP connections by.
+type ttlConn struct {
+	*net.UDPConn
+	record func(time.Duration)
+}
+
+func (c *ttlConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	var p dnsmessage.Parser
+	if _, perr := p.Start(b[:n]); perr != nil || p.SkipAllQuestions() != nil {
+		return n, err
+	}
+	for {
+		h, perr := p.AnswerHeader()
+		if perr != nil {
+			return n, err
+		}
+		switch h.Type {
+		case dnsmessage.TypeA, dnsmessage.TypeAAAA, dnsmessage.TypeCNAME:
+			c.record(time.Duration(h.TTL) * time.Second)
+		}
+		if p.SkipAnswer() != nil {
+			return n, err
+		}
+	}
+}
+
+// dialContext dial addr with d, resolving its host with the DNS cache of c
+// when enabled. The addresses are raced as d would race them, see
+// dialParallel.
+func (c *checker) dialContext(ctx context.Context, d *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if c.dnsCache == nil || err != nil || net.ParseIP(host) != nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	ips, err := c.dnsCache.lookup(ctx, host, c.sourceIP)
+	if err != nil {
+		return nil, err
+	}
+	var primaries, fallbacks []string
+	for _, ip := range ips {
+		switch {
+		case strings.HasSuffix(network, "4") && ip.To4() == nil,
+			strings.HasSuffix(network, "6") && ip.To4() != nil,
+			c.sourceIP != nil && (c.sourceIP.To4() == nil) != (ip.To4() == nil):
+			continue
+		case len(primaries) == 0 || (ip.To4() == nil) == (net.ParseIP(primaries[0]).To4() == nil):
+			primaries = append(primaries, ip.String())
+		default:
+			fallbacks = append(fallbacks, ip.String())
+		}
+	}
+	if len(primaries) == 0 {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("no address of the network of " + host)}
+	}
+	return dialParallel(ctx, d, network, port, primaries, fallbacks)
+}
+
+// dialParallel dial port on the primaries addresses in turn and, unless
+// one of them connects within the fallback delay of d, on the fallbacks
+// ones, of the other family, in parallel, returning the first connection,
+// as net.Dialer does with the addresses it resolves (RFC 6555, Happy
+// Eyeballs).
+func dialParallel(ctx context.Context, d *net.Dialer, network, port string, primaries, fallbacks []string) (net.Conn, error) {
+	serial := func(ctx context.Context, addrs []string) (net.Conn, error) {
+		var err error
+		for _, addr := range addrs {
+			conn, dialErr := d.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if dialErr == nil {
+				return conn, nil
+			}
+			err = dialErr
+		}
+		return nil, err
+	}
+	if len(fallbacks) == 0 || d.FallbackDelay < 0 {
+		return serial(ctx, primaries)
+	}
+
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, 2)
+	race := func(addrs []string, primary bool) {
+		conn, err := serial(ctx, addrs)
+		results <- dialResult{conn, err, primary}
+	}
+	go race(primaries, true)
+	delay := cmp.Or(d.FallbackDelay, 300*time.Millisecond)
+	fallback := time.NewTimer(delay)
+	defer fallback.Stop()
+
+	var primaryErr, fallbackErr error
+	started, done := 1, 0
+	for {
+		select {
+		case <-fallback.C:
+			go race(fallbacks, false)
+			started++
+		case res := <-results:
+			done++
+			if res.err == nil {
+				// The other race, if any, closes its late connection.
+				go func(pending int) {
+					for range pending {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(started - done)
+				if started == 1 {
+					fallback.Stop()
+				}
+				return res.conn, nil
+			}
+			if res.primary {
+				primaryErr = res.err
+				if started == 1 && fallback.Stop() {
+					go race(fallbacks, false)
+					started++
+				}
+			} else {
+				fallbackErr = res.err
+			}
+			if done == 2 {
+				return nil, cmp.Or(primaryErr, fallbackErr)
+			}
+		}
+	}
+}
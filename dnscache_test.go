@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNS answer the A queries read from conn with 127.0.0.1 and a TTL of
+// ttl seconds, counting them in queries.
+func fakeDNS(conn net.PacketConn, ttl uint32, queries *atomic.Int32) {
+	b := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(b[:n])
+		if err != nil {
+			continue
+		}
+		q, err := p.Question()
+		if err != nil {
+			continue
+		}
+		builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true})
+		builder.StartQuestions()
+		builder.Question(q)
+		builder.StartAnswers()
+		if q.Type == dnsmessage.TypeA {
+			queries.Add(1)
+			builder.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: ttl}, dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		}
+		resp, _ := builder.Finish()
+		conn.WriteTo(resp, addr)
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var queries atomic.Int32
+	go fakeDNS(conn, 60, &queries)
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	dc := newDNSCache()
+	dc.server, dc.now = conn.LocalAddr().String(), func() time.Time { return now }
+	for range 3 {
+		ips, err := dc.lookup(context.Background(), "app.test", nil)
+		if err != nil || len(ips) != 1 || !ips[0].Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("got %v, %v", ips, err)
+		}
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("within the TTL: got %d queries, want 1", n)
+	}
+	// Beyond the default TTL, within that of the record.
+	now = now.Add(45 * time.Second)
+	dc.lookup(context.Background(), "APP.test.", nil)
+	if n := queries.Load(); n != 1 {
+		t.Errorf("within the TTL of the record: got %d queries, want 1", n)
+	}
+	now = now.Add(15 * time.Second)
+	dc.lookup(context.Background(), "app.test", nil)
+	if n := queries.Load(); n != 2 {
+		t.Errorf("after the TTL: got %d queries, want 2", n)
+	}
+	// Expired entries are swept.
+	dc.lookup(context.Background(), "other.test", nil)
+	now = now.Add(2 * time.Minute)
+	dc.lookup(context.Background(), "app.test", nil)
+	if _, ok := dc.entries["other.test"]; ok || len(dc.entries) != 1 {
+		t.Errorf("want expired entries swept; got %v", dc.entries)
+	}
+	// Name servers are queried from the source address.
+	if _, err := dc.lookup(context.Background(), "new.test", net.ParseIP("192.0.2.1")); err == nil {
+		t.Error("want an error querying from an address of another host")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	before := queries.Load()
+	c := newChecker(WithRetries(0), WithFreshConnections(true))
+	c.dnsCache.server = conn.LocalAddr().String()
+	url := strings.Replace(srv.URL, "127.0.0.1", "app.test", 1)
+	for range 2 {
+		if res := c.check(context.Background(), Service{URL: url}); !res.Up() {
+			t.Fatalf("want up; got %v", res.Err)
+		}
+	}
+	if n := queries.Load() - before; n != 1 {
+		t.Errorf("checks: got %d queries, want 1", n)
+	}
+	if newChecker(WithDNSCache(false)).dnsCache != nil {
+		t.Error("WithDNSCache(false): want no cache")
+	}
+}
+
+func TestDialParallel(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	d := &net.Dialer{Timeout: 100 * time.Millisecond, FallbackDelay: time.Hour}
+	start := time.Now()
+	conn, err := dialParallel(context.Background(), d, "tcp", port, []string{"127.0.0.1"}, []string{"::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("want the primary without waiting for the fallbacks; took %s", elapsed)
+	}
+	if _, err := dialParallel(context.Background(), d, "tcp", closedPort, []string{"127.0.0.1"}, []string{"::1"}); err == nil {
+		t.Error("want an error when every address fails")
+	}
+
+	d.FallbackDelay = time.Millisecond
+	conn, err = dialParallel(context.Background(), d, "tcp", port, []string{"192.0.2.1"}, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("want the fallback after its delay; got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dockerURLLabel is the label of the containers to check, its value being
+// a line of a services file such as "http://localhost:8080/health timeout=2s".
+const dockerURLLabel = "healthcheck.url"
+
+// dockerOptions locate the Docker daemon whose containers are checked.
+type dockerOptions struct {
+	host string
+}
+
+func (o *dockerOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.host, "docker-host", cmp.Or(os.Getenv("DOCKER_HOST"), "unix:///var/run/docker.sock"), "Docker daemon address, unix:// or tcp://")
+}
+
+// dockerContainer is a container listed by the Docker API.
+type dockerContainer struct {
+	ID     string
+	Names  []string
+	Labels map[string]string
+}
+
+// discoverer return a function listing the running containers with a
+// healthcheck.url label.
+func (o *dockerOptions) discoverer() (discoverFunc, error) {
+	u, err := url.Parse(o.host)
+	if err != nil {
+		return nil, fmt.Errorf("docker discovery: %w", err)
+	}
+	client := http.DefaultClient
+	base := "http://" + u.Host
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}}
+		base = "http://docker"
+	case "tcp", "http":
+	default:
+		return nil, fmt.Errorf("docker discovery: unsupported host %q", o.host)
+	}
+
+	filters := url.QueryEscape(`{"label":["` + dockerURLLabel + `"],"status":["running"]}`)
+	return func(ctx context.Context) ([]Service, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/containers/json?filters="+filters, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("docker discovery: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return nil, fmt.Errorf("docker discovery: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+		var containers []dockerContainer
+		if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+			return nil, fmt.Errorf("docker discovery: %w", err)
+		}
+		return dockerServices(containers), nil
+	}, nil
+}
+
+// dockerServices return the services of the healthcheck.url labels of
+// containers. Invalid labels are reported on stderr and skipped.
+func dockerServices(containers []dockerContainer) []Service {
+	var discovered []Service
+	for _, ctr := range containers {
+		name := ctr.ID
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		svc, err := ParseService(ctr.Labels[dockerURLLabel])
+		if err == nil && svc.Scenario != nil {
+			err = fmt.Errorf("scenarios are not allowed")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "docker discovery: container %s: %s\n", name, err)
+			continue
+		}
+		svc.Name = cmp.Or(svc.Name, "docker/"+name)
+		svc.Tags = append(svc.Tags, "docker")
+		discovered = append(discovered, svc)
+	}
+	return discovered
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDockerDiscovery(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip(err)
+	}
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" || !strings.Contains(r.URL.Query().Get("filters"), `"healthcheck.url"`) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"Id": "1a2b", "Names": ["/web"], "Labels": {"healthcheck.url": "http://localhost:8080/health timeout=2s #prod"}},
+			{"Id": "3c4d", "Names": ["/api"], "Labels": {"healthcheck.url": "name=api http://localhost:9090"}},
+			{"Id": "5e6f", "Labels": {"healthcheck.url": "not a url"}}
+		]`))
+	}))
+	daemon.Listener = lis
+	daemon.Start()
+	defer daemon.Close()
+
+	discover, err := (&dockerOptions{host: "unix://" + socket}).discoverer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 services; got %+v", got)
+	}
+	if web := got[0]; web.Name != "docker/web" || web.URL != "http://localhost:8080/health" || web.Timeout != 2*time.Second || len(web.Tags) != 2 || web.Tags[1] != "docker" {
+		t.Errorf("want docker/web with its options; got %+v", web)
+	}
+	if api := got[1]; api.Name != "api" {
+		t.Errorf("want the name of the label kept; got %s", api.Name)
+	}
+
+	if _, err := (&dockerOptions{host: "ssh://host"}).discoverer(); err == nil {
+		t.Error("want an error for an unsupported host")
+	}
+}
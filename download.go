@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithDownload read the bodies of checks to their end, at most limit bytes,
+// recording the time to their last byte as TTLB next to the time to first
+// byte. Bodies larger than limit are cut, their TTLB being that of limit
+// bytes. 0 disables downloads. Event streams and range checks, which read
+// their bodies themselves, are left out.
+func WithDownload(limit int64) Option {
+	return func(c *checker) { c.download = limit }
+}
+
+// sizeUnits map the suffixes of sizes to their number of bytes.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"B", 1},
+}
+
+// parseSize parse a positive size in bytes with an optional unit, like
+// 512KB or 10MiB.
+func parseSize(s string) (int64, error) {
+	n, unit := strings.TrimSpace(s), int64(1)
+	for _, u := range sizeUnits {
+		if v, ok := strings.CutSuffix(n, u.suffix); ok {
+			n, unit = strings.TrimSpace(v), u.bytes
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * float64(unit)), nil
+}
+
+// limitBody make resp.Body end after limit bytes.
+func limitBody(resp *http.Response, limit int64) {
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, limit), resp.Body}
+}
+
+// download read resp.Body to its end and record the time since start as the
+// TTLB of result. resp.Body is replaced by its first maxProbeBody bytes, for
+// the checks of the body that follow.
+func download(resp *http.Response, start time.Time, result *Result) error {
+	var kept bytes.Buffer
+	_, err := io.Copy(&kept, io.LimitReader(resp.Body, maxProbeBody))
+	if err == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+	}
+	result.TTLB = time.Since(start)
+	resp.Body = io.NopCloser(&kept)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 1000)))
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(strings.Repeat("b", 1000)))
+	}))
+	defer srv.Close()
+	svc, err := ParseService(srv.URL + " drift=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := newChecker(WithRetries(0)).check(context.Background(), svc)
+	if !res.Up() || res.TTFB <= 0 || res.TTFB >= 50*time.Millisecond || res.TTLB != 0 {
+		t.Errorf("without download: got %v, TTFB %s, TTLB %s", res.Err, res.TTFB, res.TTLB)
+	}
+	res = newChecker(WithRetries(0), WithDownload(1<<20), WithAcceptEncoding("identity")).check(context.Background(), svc)
+	if !res.Up() || res.TTFB >= 50*time.Millisecond || res.TTLB < 50*time.Millisecond || res.BytesTransferred != 2000 {
+		t.Errorf("download: got %v, TTFB %s, TTLB %s, %d bytes", res.Err, res.TTFB, res.TTLB, res.BytesTransferred)
+	}
+	if res.BodyHash != bodyHash([]byte(strings.Repeat("a", 1000)+strings.Repeat("b", 1000))) {
+		t.Error("download: the body is not kept for the checks that follow")
+	}
+	res = newChecker(WithRetries(0), WithDownload(500)).check(context.Background(), svc)
+	if !res.Up() || res.TTLB <= 0 || res.TTLB >= 50*time.Millisecond {
+		t.Errorf("download cut at the limit: got %v, TTLB %s", res.Err, res.TTLB)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"512KB", 512e3},
+		{"1.5MB", 1.5e6},
+		{"10MiB", 10 << 20},
+		{"1 GB", 1e9},
+		{"100B", 100},
+		{"0", 0},
+		{"-1MB", 0},
+		{"MB", 0},
+		{"10TB", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if got != tt.want || (err != nil) != (tt.want == 0) {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
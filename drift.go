@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// WithContentHashes set the body hashes of the previous run, by service
+// name or url, which the services with drift=true are compared with.
+// hashes is updated in place with the hashes of the run, so that they can
+// be saved for the next one. Without it, hashes are kept in memory and
+// compared from one run of the checker to the next.
+func WithContentHashes(hashes map[string]string) Option {
+	return func(c *checker) { c.hashes.m = hashes }
+}
+
+// contentHashes are the last body hashes of the services with drift=true.
+type contentHashes struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// swap record hash as the hash of the service key and return its previous
+// one.
+func (h *contentHashes) swap(key, hash string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev := h.m[key]
+	h.m[key] = hash
+	return prev
+}
+
+// DriftError report a body whose content changed since the previous check.
+type DriftError struct {
+	Before string
+	After  string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("content changed, body hash %.12s -> %.12s", e.Before, e.After)
+}
+
+// bodyHash return the hex encoded SHA-256 of body.
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkDrift compare the body hash of the result of svc with the previous
+// one, failing the result when it changed.
+func (c *checker) checkDrift(svc Service, result Result) Result {
+	if !svc.Drift || result.BodyHash == "" {
+		return result
+	}
+	prev := c.hashes.swap(svc.key(), result.BodyHash)
+	if prev != "" && prev != result.BodyHash && result.Err == nil {
+		result.Err = &DriftError{Before: prev, After: result.BodyHash}
+	}
+	return result
+}
+
+// readContentHashes read the hashes saved at path by writeContentHashes,
+// none when the file does not exist yet.
+func readContentHashes(path string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return hashes, nil
+}
+
+// writeContentHashes save hashes at path for the next run.
+func writeContentHashes(path string, hashes map[string]string) error {
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
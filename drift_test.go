@@ -0,0 +1,72 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	var body atomic.Value
+	body.Store("terms v1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(body.Load().(string)))
+			zw.Close()
+			return
+		}
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer srv.Close()
+
+	svc, err := ParseService(srv.URL + " drift=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newChecker(WithRetries(0))
+	check := func() Result {
+		t.Helper()
+		return c.checkURL(context.Background(), svc)
+	}
+	first := check()
+	if !first.Up() || first.BodyHash != bodyHash([]byte("terms v1")) {
+		t.Fatalf("first check: got %+v", first)
+	}
+	if res := check(); !res.Up() {
+		t.Errorf("same content: want up; got %v", res.Err)
+	}
+	body.Store("down for maintenance")
+	var drift *DriftError
+	if res := check(); !errors.As(res.Err, &drift) || drift.Before != first.BodyHash {
+		t.Errorf("changed content: want a DriftError; got %v", res.Err)
+	}
+	if res := check(); !res.Up() {
+		t.Errorf("content unchanged since the previous check: want up; got %v", res.Err)
+	}
+
+	// Hashes are those of the decoded bodies and are saved between runs.
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	hashes, err := readContentHashes(path)
+	if err != nil || len(hashes) != 0 {
+		t.Fatalf("missing file: want no hashes; got %v, %v", hashes, err)
+	}
+	HealthCheck([]Service{svc}, WithContentHashes(hashes), WithAcceptEncoding("gzip"))
+	if err := writeContentHashes(path, hashes); err != nil {
+		t.Fatal(err)
+	}
+	if hashes, err = readContentHashes(path); err != nil || hashes[srv.URL] != bodyHash([]byte("down for maintenance")) {
+		t.Fatalf("got %v, %v", hashes, err)
+	}
+	body.Store("terms v2")
+	res := HealthCheck([]Service{svc}, WithContentHashes(hashes), WithAcceptEncoding("gzip"))[0]
+	if !errors.As(res.Err, &drift) {
+		t.Errorf("changed content since the saved run: want a DriftError; got %v", res.Err)
+	}
+}
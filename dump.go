@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultMaxDumps is the default number of failures dumped by a process.
+const DefaultMaxDumps = 1000
+
+// WithDumpFailures write the response of each failed HTTP check, with its
+// headers and body, to a file of its own in dir for post-incident
+// analysis. Files are named by the time of the check and the service:
+//
+//	20261017T143005.123456789Z-checkout-api.http
+//
+// Bodies of responses whose status fails the check are kept up to the
+// limit of WithMaxBodySize, or of DefaultMaxBodySize when it is 0. Those
+// of other responses, failing on their body, are kept up to the preview
+// limit, so that checks of large bodies do not hold them in memory until
+// they are known to have succeeded.
+func WithDumpFailures(dir string) Option {
+	return func(c *checker) { c.dumpDir = dir }
+}
+
+// WithMaxDumps set the number of failures dumped by the checker, those
+// beyond it being left out so that an outage cannot fill the disk. 0
+// means no limit.
+func WithMaxDumps(n int) Option {
+	return func(c *checker) { c.maxDumps = n }
+}
+
+// recordLimit return the number of bytes of the body of resp to keep for
+// previews and dumps of failures of svc.
+func (c *checker) recordLimit(svc Service, resp *http.Response) int {
+	limit := c.bodyPreview
+	if c.dumpDir != "" {
+		limit = max(limit, DefaultBodyPreview)
+		if resp.StatusCode >= http.StatusInternalServerError || !expectedStatus(resp.StatusCode, svc.ExpectStatus) {
+			limit = max(limit, int(cmp.Or(c.maxBodySize, DefaultMaxBodySize)))
+		}
+	}
+	return limit
+}
+
+// failedResponse is the response of a failed check to dump.
+type failedResponse struct {
+	at     time.Time
+	method string
+	url    string
+	proto  string
+	status string
+	header http.Header
+	body   []byte
+}
+
+func (c *checker) newFailedResponse(req *http.Request, resp *http.Response, body []byte) *failedResponse {
+	return &failedResponse{
+		at:     c.now(),
+		method: req.Method,
+		url:    redactURL(req.URL.String()),
+		proto:  resp.Proto,
+		status: resp.Status,
+		header: resp.Header,
+		body:   body,
+	}
+}
+
+// dumpFailure write the response of the failed result res to c.dumpDir, as
+// its request line and error followed by the response as received, status
+// line and headers then body. Dumps are only readable by their owner, as
+// are the directories created for them. Failures to write are printed on
+// stderr, as is reaching the limit of WithMaxDumps, once.
+func (c *checker) dumpFailure(res Result) {
+	if n := c.dumps.Add(1); c.maxDumps > 0 && n > int64(c.maxDumps) {
+		if n == int64(c.maxDumps)+1 {
+			fmt.Fprintf(os.Stderr, "dump failure: %d failures dumped to %s, the next ones are not\n", c.maxDumps, c.dumpDir)
+		}
+		return
+	}
+	r := res.response
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s\n", r.method, r.url)
+	if res.Err != nil {
+		fmt.Fprintf(&b, "Error: %s\n", res.Err)
+	}
+	fmt.Fprintf(&b, "\n%s %s\r\n", r.proto, r.status)
+	r.header.Write(&b)
+	b.WriteString("\r\n")
+	b.Write(r.body)
+
+	name := r.at.UTC().Format("20060102T150405.000000000Z") + "-" + dumpName(resultKey(res)) + ".http"
+	err := os.MkdirAll(c.dumpDir, 0o700)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(c.dumpDir, name), b.Bytes(), 0o600)
+	}
+	if err != nil {
+		err = fmt.Errorf("dump failure: %w", err)
+		fmt.Fprintln(os.Stderr, err)
+		reportError(err, map[string]string{"dump": c.dumpDir})
+	}
+}
+
+// dumpNameLimit is the maximum length of the service part of the names of
+// dump files.
+const dumpNameLimit = 100
+
+// dumpName return key with the characters other than letters, digits, dots
+// and dashes replaced by underscores, for use in a file name.
+func dumpName(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	name := []byte(key[:min(len(key), dumpNameLimit)])
+	for i, c := range name {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '.' && c != '-' {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
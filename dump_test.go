@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDumpFailures(t *testing.T) {
+	var flaky atomic.Int32
+	body := strings.Repeat("x", 2000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.Header().Set("X-Debug", "db timeout")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(body))
+		case "/flaky":
+			if flaky.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "failures")
+	c := newChecker(WithRetries(1), WithDumpFailures(dir), WithBodyPreview(10))
+	c.retryDelay = 0
+	c.now = func() time.Time { return time.Date(2026, 10, 17, 14, 30, 5, 0, time.UTC) }
+	u, _ := url.Parse(srv.URL)
+	u.User = url.UserPassword("admin", "hunter2")
+	for _, svc := range []Service{{URL: srv.URL + "/ok"}, {URL: srv.URL + "/flaky"}, {Name: "api/v1", URL: u.String() + "/down"}} {
+		res := c.check(context.Background(), svc)
+		if res.response != nil {
+			t.Errorf("%s: response left in the result", svc.URL)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "20261017T143005.000000000Z-api_v1.http" {
+		t.Fatalf("want a dump of api/v1; got %v", entries)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("want the directory only accessible by its owner; got %v %v", info.Mode(), err)
+	}
+	if info, err := entries[0].Info(); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("want the dump only readable by its owner; got %v %v", info.Mode(), err)
+	}
+	dump, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword("admin", "xxxxx")
+	want := "GET " + u.String() + "/down\nError: "
+	if s := string(dump); !strings.HasPrefix(s, want) ||
+		!strings.Contains(s, "\n\nHTTP/1.1 503 Service Unavailable\r\n") ||
+		!strings.Contains(s, "\r\nX-Debug: db timeout\r\n") ||
+		!strings.HasSuffix(s, "\r\n\r\n"+body) {
+		t.Errorf("unexpected dump:\n%s", s)
+	}
+}
+
+func TestDumpLimits(t *testing.T) {
+	body := strings.Repeat("x", 2000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := newChecker(WithDumpFailures(dir), WithMaxDumps(2), WithBodyPreview(0))
+	if got := c.recordLimit(Service{}, &http.Response{StatusCode: http.StatusOK}); got != DefaultBodyPreview {
+		t.Errorf("want ok responses kept up to %d bytes; got %d", DefaultBodyPreview, got)
+	}
+	if got := c.recordLimit(Service{ExpectStatus: []int{204}}, &http.Response{StatusCode: http.StatusOK}); got != DefaultMaxBodySize {
+		t.Errorf("want unexpected statuses kept up to %d bytes; got %d", DefaultMaxBodySize, got)
+	}
+
+	for i := range 3 {
+		c.check(context.Background(), Service{Name: fmt.Sprintf("down-%d", i), URL: srv.URL + "/down"})
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("want 2 dumps; got %v", entries)
+	}
+}
+
+func TestDumpName(t *testing.T) {
+	for key, want := range map[string]string{
+		"checkout-api":                  "checkout-api",
+		"https://a.com:8443/health?x=1": "a.com_8443_health_x_1",
+		strings.Repeat("a", 200):        strings.Repeat("a", dumpNameLimit),
+	} {
+		if got := dumpName(key); got != want {
+			t.Errorf("dumpName(%q) = %q; want %q", key, got, want)
+		}
+	}
+}
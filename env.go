@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replace ${VAR} in s by the value of the environment variable
+// VAR, so that a services file can be shared by environments. Unset
+// variables are errors rather than silently empty.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var err error
+	expanded := envVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := envVarPattern.FindStringSubmatch(m)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return expanded, err
+}
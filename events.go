@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// eventsKeepAlive is the interval of the comments sent to idle event
+// streams, so that proxies do not close them.
+const eventsKeepAlive = 30 * time.Second
+
+// broker fan out the results of serve mode to the subscribers of the
+// events stream.
+type broker struct {
+	mu   sync.Mutex
+	subs map[chan record]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subs: make(map[chan record]struct{})}
+}
+
+// subscribe return a channel receiving the records published from now on,
+// and a function ending the subscription.
+func (b *broker) subscribe() (<-chan record, func()) {
+	ch := make(chan record, 64)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// publish send rec to every subscriber. Records are dropped for subscribers
+// too slow to keep up rather than holding back the checks.
+func (b *broker) publish(rec record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- rec:
+		default:
+		}
+	}
+}
+
+// eventsHandler stream the records published to b as server-sent events,
+// one "result" event per result whose data is its JSON form.
+func eventsHandler(b *broker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		records, unsubscribe := b.subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case rec := <-records:
+				data, err := json.Marshal(rec)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "event: result\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	s := newServer(newChecker(), 10)
+	events := s.events
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("want text/event-stream; got %s", ct)
+	}
+
+	// The subscription is registered before the headers are sent.
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events.publish(record{Time: at, Result: Result{Url: "https://a.com", Status: 200, Latency: time.Millisecond}})
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	want := []string{
+		"event: result",
+		`data: {"time":"2026-01-01T00:00:00Z","url":"https://a.com","state":"up","status":200,"latency_ms":1}`,
+		"",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := newBroker()
+	records, unsubscribe := b.subscribe()
+	for i := 0; i < 100; i++ {
+		b.publish(record{Result: Result{Status: i}})
+	}
+	if len(records) != cap(records) {
+		t.Errorf("want the subscription full; got %d records", len(records))
+	}
+	unsubscribe()
+	if len(b.subs) != 0 {
+		t.Error("want no subscriber left")
+	}
+}
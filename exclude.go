@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// excludeServices return services but those whose name or url matches one
+// of patterns, and the number of services excluded.
+func excludeServices(services []Service, patterns []*regexp.Regexp) ([]Service, int) {
+	if len(patterns) == 0 {
+		return services, 0
+	}
+	kept := services[:0:0]
+	for _, svc := range services {
+		if !excluded(svc, patterns) {
+			kept = append(kept, svc)
+		}
+	}
+	return kept, len(services) - len(kept)
+}
+
+func excluded(svc Service, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if svc.Name != "" && re.MatchString(svc.Name) || re.MatchString(svc.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// readIgnoreFile return the patterns of the ignore file at path, a regular
+// expression per line. Blank lines and lines starting with # are skipped.
+func readIgnoreFile(path string) ([]*regexp.Regexp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns []*regexp.Regexp
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, scanner.Err()
+}
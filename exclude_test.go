@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExcludeServices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".healthcheckignore")
+	if err := os.WriteFile(path, []byte("# staging hosts\n\n  ^https://staging\\.  \n^legacy-\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := readIgnoreFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns = append(patterns, regexp.MustCompile(`/tmp/`))
+	services := []Service{
+		{URL: "https://a.com"},
+		{URL: "https://staging.a.com"},
+		{Name: "legacy-api", URL: "https://api.a.com"},
+		{URL: "https://a.com/tmp/report"},
+		{Name: "api", URL: "https://b.com/legacy-"},
+	}
+	kept, n := excludeServices(services, patterns)
+	if n != 3 || len(kept) != 2 || kept[0].URL != "https://a.com" || kept[1].Name != "api" {
+		t.Errorf("got %d excluded, kept %+v", n, kept)
+	}
+
+	var b strings.Builder
+	s := summarize([]Result{{Url: "https://a.com"}}, 0)
+	s.Excluded = n
+	writeSummary(&b, s)
+	if !strings.HasPrefix(b.String(), "Summary: 1 up; 0 down; 3 excluded\n") {
+		t.Errorf("unexpected summary %q", b.String())
+	}
+
+	if err := os.WriteFile(path, []byte("ok\n(\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readIgnoreFile(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("want the line of the invalid pattern; got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxExpansion is the maximum number of urls a pattern expands to, so that
+// a typo such as {1..1000000} fails rather than exhausting memory.
+const maxExpansion = 10000
+
+var rangePattern = regexp.MustCompile(`^(-?[0-9]+)\.\.(-?[0-9]+)$`)
+
+// expandPattern return the strings s expands to, each {a,b,c} list and
+// {first..last} numeric range being replaced by each of its items in turn,
+// as a shell does: https://node{01..03}.a.com expands to the urls of
+// node01, node02 and node03, and https://{eu,us}.a.com to those of eu.a.com
+// and us.a.com. Ranges with a zero padded bound are padded to the width of
+// the widest one. ${VAR} and braces which are neither lists nor ranges are
+// left as is.
+func expandPattern(s string) ([]string, error) {
+	expanded := []string{""}
+	for {
+		start, end, items, err := nextGroup(s)
+		if err != nil {
+			return nil, err
+		}
+		if items == nil {
+			break
+		}
+		if len(expanded)*len(items) > maxExpansion {
+			return nil, fmt.Errorf("pattern expands to more than %d urls", maxExpansion)
+		}
+		next := make([]string, 0, len(expanded)*len(items))
+		for _, prefix := range expanded {
+			for _, item := range items {
+				next = append(next, prefix+s[:start]+item)
+			}
+		}
+		expanded, s = next, s[end:]
+	}
+	for i := range expanded {
+		expanded[i] += s
+	}
+	return expanded, nil
+}
+
+// nextGroup return the first list or range of s, between start and end,
+// and its items, or nil items when s has none.
+func nextGroup(s string) (start, end int, items []string, err error) {
+	for from := 0; ; {
+		i := strings.IndexByte(s[from:], '{')
+		if i < 0 {
+			return 0, 0, nil, nil
+		}
+		start = from + i
+		j := strings.IndexAny(s[start+1:], "{}")
+		if j < 0 {
+			return 0, 0, nil, nil
+		}
+		end = start + 1 + j + 1
+		if s[end-1] == '}' && (start == 0 || s[start-1] != '$') {
+			if items, err := groupItems(s[start+1 : end-1]); items != nil || err != nil {
+				return start, end, items, err
+			}
+		}
+		from = start + 1
+	}
+}
+
+// groupItems return the items of the list or range group, or nil when it
+// is neither. Ranges of more than maxExpansion items are errors.
+func groupItems(group string) ([]string, error) {
+	m := rangePattern.FindStringSubmatch(group)
+	if m == nil {
+		if !strings.Contains(group, ",") {
+			return nil, nil
+		}
+		return strings.Split(group, ","), nil
+	}
+	first, err1 := strconv.Atoi(m[1])
+	last, err2 := strconv.Atoi(m[2])
+	if err1 != nil || err2 != nil || max(first-last, last-first) >= maxExpansion {
+		return nil, fmt.Errorf("range {%s} expands to more than %d urls", group, maxExpansion)
+	}
+	width := 0
+	if zeroPadded(m[1]) || zeroPadded(m[2]) {
+		width = max(len(m[1]), len(m[2]))
+	}
+	step := 1
+	if last < first {
+		step = -1
+	}
+	var items []string
+	for n := first; ; n += step {
+		items = append(items, fmt.Sprintf("%0*d", width, n))
+		if n == last {
+			return items, nil
+		}
+	}
+}
+
+// zeroPadded report whether the bound of a range has leading zeros.
+func zeroPadded(bound string) bool {
+	bound = strings.TrimPrefix(bound, "-")
+	return len(bound) > 1 && bound[0] == '0'
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestExpandPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"https://a.com", []string{"https://a.com"}},
+		{"https://node{1..3}.a.com", []string{"https://node1.a.com", "https://node2.a.com", "https://node3.a.com"}},
+		{"https://node{08..10}.a.com", []string{"https://node08.a.com", "https://node09.a.com", "https://node10.a.com"}},
+		{"https://node{3..1}.a.com", []string{"https://node3.a.com", "https://node2.a.com", "https://node1.a.com"}},
+		{"https://{eu,us}.a.com/{a,b}", []string{"https://eu.a.com/a", "https://eu.a.com/b", "https://us.a.com/a", "https://us.a.com/b"}},
+		{"https://{,www.}a.com", []string{"https://a.com", "https://www.a.com"}},
+		{"https://api.${ENV}.a.com/{x}", []string{"https://api.${ENV}.a.com/{x}"}},
+		{"https://a.com/{a..b}/{1,2}", []string{"https://a.com/{a..b}/1", "https://a.com/{a..b}/2"}},
+	}
+	for _, test := range tests {
+		got, err := expandPattern(test.pattern)
+		if err != nil || !slices.Equal(got, test.want) {
+			t.Errorf("expandPattern(%q) = %q, %v; want %q", test.pattern, got, err, test.want)
+		}
+	}
+
+	for _, pattern := range []string{"https://n{1..100000}.a.com", "https://n{1..200}.a.com/{1..200}"} {
+		if _, err := expandPattern(pattern); err == nil {
+			t.Errorf("expandPattern(%q): want an error", pattern)
+		}
+	}
+}
+
+func TestParseServicesPatterns(t *testing.T) {
+	services, err := ParseServices(strings.NewReader(""+
+		"https://node{01..03}.a.com/healthz timeout=2s #fleet\n"+
+		"name=api url=https://api.a.com/{v1}\n"+
+		"name=web url=https://web{1,2}.a.com\n"+
+		"https://{eu,us}.a.com/graphql graphql-query=\"{ health { ok } }\"\n",
+	), "services.txt")
+	var urls []string
+	for _, svc := range services {
+		urls = append(urls, svc.URL)
+		if svc.Line == 1 && (svc.Timeout != 2e9 || !slices.Equal(svc.Tags, []string{"fleet"})) {
+			t.Errorf("options of the pattern not applied to %+v", svc)
+		}
+	}
+	want := []string{
+		"https://node01.a.com/healthz", "https://node02.a.com/healthz", "https://node03.a.com/healthz",
+		"https://api.a.com/{v1}",
+		"https://eu.a.com/graphql", "https://us.a.com/graphql",
+	}
+	if !slices.Equal(urls, want) {
+		t.Errorf("got urls %q; want %q", urls, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "services.txt:3: name with a url pattern") {
+		t.Errorf("want an error on line 3; got %v", err)
+	}
+}
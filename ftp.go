@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// checkFTP check the FTP server of an ftp://[user:password@]host:port url:
+// it reads the banner of the server, whose delay is the latency of the
+// result, logs in when the url has a user, then quits. The first line of
+// the banner is recorded in the result.
+func checkFTP(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	start := time.Now()
+	conn, err := c.dial(ctx, "tcp", hostPort(u, "21"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	result.Latency = time.Since(start)
+	result.Banner, _, _ = strings.Cut(banner, "\n")
+	if err != nil {
+		return fmt.Errorf("ftp banner: %w", err)
+	}
+
+	if user := u.User.Username(); user != "" {
+		password, _ := u.User.Password()
+		code, _, err := ftpCommand(text, "USER "+user)
+		if err == nil && code == 331 {
+			code, _, err = ftpCommand(text, "PASS "+password)
+		}
+		if err != nil {
+			return err
+		}
+		if code != 230 {
+			return errors.New("ftp: login refused")
+		}
+	}
+	ftpCommand(text, "QUIT")
+	return nil
+}
+
+// ftpCommand send cmd to text and return the code and message of its
+// reply, failing for codes other than 2xx and 3xx.
+func ftpCommand(text *textproto.Conn, cmd string) (int, string, error) {
+	if err := text.PrintfLine("%s", cmd); err != nil {
+		return 0, "", err
+	}
+	verb, _, _ := strings.Cut(cmd, " ")
+	code, msg, err := text.ReadResponse(0)
+	if err == nil && code >= 400 {
+		err = &textproto.Error{Code: code, Msg: msg}
+	}
+	if err != nil {
+		return code, msg, fmt.Errorf("ftp %s: %w", strings.ToLower(verb), err)
+	}
+	return code, msg, nil
+}
+
+// checkSFTP check the SFTP server of an sftp://host:port url as checkSSH
+// does, without authenticating.
+//
+// Stating a path is out of scope: it takes an authenticated SSH session,
+// with the key exchange and encryption of a full SSH client, which the
+// checker doesn't have. Urls with a path or credentials are rejected
+// rather than checked without them.
+func checkSFTP(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	if strings.TrimPrefix(u.Path, "/") != "" {
+		return fmt.Errorf("sftp: checking the path %s is not supported, only the server is checked", u.Path)
+	}
+	if u.User != nil {
+		return errors.New("sftp: credentials are not supported, only the server is checked")
+	}
+	return checkSSH(ctx, c, u, result)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeFTP serve on l the login of an FTP server accepting user with
+// password.
+func fakeFTP(l net.Listener, user, password string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			text := textproto.NewConn(conn)
+			text.PrintfLine("220-files.a.com FTP server ready")
+			text.PrintfLine("220 Authorized use only")
+			var gotUser string
+			for {
+				line, err := text.ReadLine()
+				if err != nil {
+					return
+				}
+				verb, arg, _ := strings.Cut(line, " ")
+				switch verb {
+				case "USER":
+					gotUser = arg
+					text.PrintfLine("331 Password required")
+				case "PASS":
+					if gotUser != user || arg != password {
+						text.PrintfLine("530 Login incorrect.")
+						continue
+					}
+					text.PrintfLine("230 Logged in")
+				case "QUIT":
+					text.PrintfLine("221 Goodbye")
+					return
+				default:
+					text.PrintfLine("500 Unknown command")
+				}
+			}
+		}()
+	}
+}
+
+func TestCheckFTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeFTP(l, "monitor", "s3cret")
+
+	c := newChecker(WithRetries(0))
+	for _, userinfo := range []string{"", "monitor:s3cret@"} {
+		res := c.check(context.Background(), Service{URL: "ftp://" + userinfo + l.Addr().String()})
+		if !res.Up() || res.Banner != "files.a.com FTP server ready" {
+			t.Errorf("%q: want up with the banner; got %v, %q", userinfo, res.Err, res.Banner)
+		}
+	}
+	res := c.check(context.Background(), Service{URL: "ftp://monitor:wrong@" + l.Addr().String()})
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "530") || !strings.Contains(res.Err.Error(), "Login incorrect.") || ErrorCodeOf(res.Err) != CodeFTP {
+		t.Errorf("wrong password: got %v", res.Err)
+	}
+}
+
+func TestCheckSFTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeSSH(l, "SSH-2.0-OpenSSH_9.6")
+
+	c := newChecker(WithRetries(0))
+	if res := c.check(context.Background(), Service{URL: "sftp://" + l.Addr().String()}); !res.Up() || res.Banner != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("got %v, %q", res.Err, res.Banner)
+	}
+	for _, url := range []string{"sftp://" + l.Addr().String() + "/backups", "sftp://monitor:s3cret@" + l.Addr().String()} {
+		if res := c.check(context.Background(), Service{URL: url}); res.Up() || !strings.Contains(res.Err.Error(), "not supported") {
+			t.Errorf("%s: want an error; got %v", url, res.Err)
+		}
+	}
+}
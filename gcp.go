@@ -0,0 +1,269 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authGCPIDToken is the auth= value of services protected by Google
+// identity tokens, such as Cloud Run services or IAP.
+const authGCPIDToken = "gcp-id-token"
+
+// gcpMetadataURL is the identity endpoint of the metadata server of Google
+// Cloud instances.
+const gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity"
+
+// idTokenRefresh is how long before they expire ID tokens are renewed, so
+// that they do not expire during a check.
+const idTokenRefresh = 5 * time.Minute
+
+// WithGCPCredentials set the service account key file Google ID tokens are
+// minted with for services with auth=gcp-id-token. The file named by
+// GOOGLE_APPLICATION_CREDENTIALS is used otherwise, or the metadata server
+// when it is not set either.
+func WithGCPCredentials(path string) Option {
+	return func(c *checker) { c.idTokens.keyFile = path }
+}
+
+// idTokenSource mint Google ID tokens and cache them per audience until
+// they are about to expire.
+type idTokenSource struct {
+	keyFile     string
+	metadataURL string
+	client      *http.Client
+	now         func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]idToken
+}
+
+type idToken struct {
+	value  string
+	expiry time.Time
+}
+
+func newIDTokenSource() *idTokenSource {
+	return &idTokenSource{
+		keyFile:     os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+		metadataURL: gcpMetadataURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+		tokens:      make(map[string]idToken),
+	}
+}
+
+// token return an ID token for audience. Tokens are minted without
+// holding the lock, so that a slow token endpoint does not hold back
+// checks of other audiences; checks racing for the same audience may each
+// mint one, the last one being cached.
+func (s *idTokenSource) token(ctx context.Context, audience string) (string, error) {
+	s.mu.Lock()
+	t, ok := s.tokens[audience]
+	s.mu.Unlock()
+	if ok && s.now().Before(t.expiry.Add(-idTokenRefresh)) {
+		return t.value, nil
+	}
+
+	var value string
+	var err error
+	if s.keyFile != "" {
+		value, err = s.fromKey(ctx, audience)
+	} else {
+		value, err = s.fromMetadata(ctx, audience)
+	}
+	if err != nil {
+		return "", fmt.Errorf("gcp id token: %w", err)
+	}
+	expiry, err := jwtExpiry(value)
+	if err != nil {
+		return "", fmt.Errorf("gcp id token: %w", err)
+	}
+	s.mu.Lock()
+	s.tokens[audience] = idToken{value: value, expiry: expiry}
+	s.mu.Unlock()
+	return value, nil
+}
+
+// fromMetadata ask the metadata server for an ID token.
+func (s *idTokenSource) fromMetadata(ctx context.Context, audience string) (string, error) {
+	u := s.metadataURL + "?" + url.Values{"audience": {audience}, "format": {"full"}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server: %s", resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// serviceAccountKey is the part of a service account key file used to
+// mint ID tokens.
+type serviceAccountKey struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// fromKey exchange a JWT signed with the service account key for an ID
+// token, see https://developers.google.com/identity/protocols/oauth2/service-account.
+func (s *idTokenSource) fromKey(ctx context.Context, audience string) (string, error) {
+	data, err := os.ReadFile(s.keyFile)
+	if err != nil {
+		return "", err
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("%s: %w", s.keyFile, err)
+	}
+	signer, err := parseRSAKey(key.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", s.keyFile, err)
+	}
+
+	now := s.now()
+	assertion, err := signJWT(signer, key.PrivateKeyID, map[string]any{
+		"iss":             key.ClientEmail,
+		"sub":             key.ClientEmail,
+		"aud":             key.TokenURI,
+		"iat":             now.Unix(),
+		"exp":             now.Add(time.Hour).Unix(),
+		"target_audience": audience,
+	})
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token endpoint: no id_token")
+	}
+	return token.IDToken, nil
+}
+
+func parseRSAKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// signJWT return the RS256 JWT of claims.
+func signJWT(key *rsa.PrivateKey, keyID string, claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// jwtExpiry return the expiry of a JWT, without verifying it.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed token: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, errors.New("token without expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// authorize set on req the credentials of the auth= option of svc. The
+// audience of ID tokens defaults to the origin of the url of req, as Cloud
+// Run expects.
+func (c *checker) authorize(req *http.Request, svc Service) error {
+	switch svc.Auth {
+	case "":
+		return nil
+	case authGCPIDToken:
+		audience := cmp.Or(svc.Audience, req.URL.Scheme+"://"+req.URL.Host)
+		token, err := c.idTokens.token(req.Context(), audience)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	default:
+		return fmt.Errorf("unknown auth %q", svc.Auth)
+	}
+}
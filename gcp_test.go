@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeIDToken return an unsigned JWT for audience expiring in an hour.
+func fakeIDToken(audience string) string {
+	enc := base64.RawURLEncoding
+	claims := fmt.Sprintf(`{"aud":%q,"exp":%d}`, audience, time.Now().Add(time.Hour).Unix())
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+// protectedServer return a server answering 401 unless sent an ID token
+// for its own origin.
+func protectedServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(token, ".")
+		var claims struct {
+			Aud string `json:"aud"`
+		}
+		if len(parts) == 3 {
+			payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			json.Unmarshal(payload, &claims)
+		}
+		if claims.Aud != srv.URL {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGCPIDTokenMetadata(t *testing.T) {
+	target := protectedServer(t)
+	var calls atomic.Int32
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, fakeIDToken(r.URL.Query().Get("audience")))
+	}))
+	defer metadata.Close()
+
+	c := newChecker(WithRetries(0))
+	c.idTokens.keyFile = ""
+	c.idTokens.metadataURL = metadata.URL
+	svc, err := ParseService(target.URL + "/healthz auth=gcp-id-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if res := c.checkURL(context.Background(), svc); !res.Up() {
+			t.Fatalf("want up; got %d %v", res.Status, res.Err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("want the token cached; got %d metadata calls", n)
+	}
+
+	svc.Audience = "https://other"
+	if res := c.checkURL(context.Background(), svc); res.Status != http.StatusUnauthorized {
+		t.Errorf("want 401 with another audience; got %d %v", res.Status, res.Err)
+	}
+}
+
+func TestGCPIDTokenServiceAccountKey(t *testing.T) {
+	target := protectedServer(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Iss            string `json:"iss"`
+			TargetAudience string `json:"target_audience"`
+		}
+		json.Unmarshal(payload, &claims)
+		if claims.Iss != "checker@p.iam.gserviceaccount.com" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": fakeIDToken(claims.TargetAudience)})
+	}))
+	defer tokens.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: must(x509.MarshalPKCS8PrivateKey(key))})
+	data, _ := json.Marshal(serviceAccountKey{
+		ClientEmail:  "checker@p.iam.gserviceaccount.com",
+		PrivateKey:   string(pemKey),
+		PrivateKeyID: "k1",
+		TokenURI:     tokens.URL,
+	})
+	if err := os.WriteFile(keyFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := Service{URL: target.URL, Auth: authGCPIDToken}
+	res := HealthCheck([]Service{svc}, WithGCPCredentials(keyFile), WithRetries(0))[0]
+	if !res.Up() {
+		t.Errorf("want up; got %d %v", res.Status, res.Err)
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// WithGeoIP enrich the results of the checks with the country and
+// autonomous system of the address the services were reached at, as found
+// in g. Behind a proxy, it is the address of the proxy.
+func WithGeoIP(g *geoIP) Option {
+	return func(c *checker) { c.geoIP = g }
+}
+
+// Geo locate the address a service was reached at.
+type Geo struct {
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	Org     string `json:"org,omitempty"`
+}
+
+func (g *Geo) String() string {
+	s := g.IP
+	if g.Country != "" {
+		s += " " + g.Country
+	}
+	if g.ASN != 0 {
+		s += fmt.Sprintf(" AS%d", g.ASN)
+	}
+	if g.Org != "" {
+		s += " " + g.Org
+	}
+	return s
+}
+
+// geoIP look up addresses in local MaxMind databases: a GeoIP2 or GeoLite2
+// Country or City database and an ASN database, either being optional.
+type geoIP struct {
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+}
+
+// openGeoIP open the MaxMind databases at countryPath and asnPath, either
+// being empty when not used.
+func openGeoIP(countryPath, asnPath string) (*geoIP, error) {
+	var g geoIP
+	var err error
+	if countryPath != "" {
+		if g.country, err = maxminddb.Open(countryPath); err != nil {
+			return nil, fmt.Errorf("%s: %w", countryPath, err)
+		}
+	}
+	if asnPath != "" {
+		if g.asn, err = maxminddb.Open(asnPath); err != nil {
+			g.Close()
+			return nil, fmt.Errorf("%s: %w", asnPath, err)
+		}
+	}
+	return &g, nil
+}
+
+// Close close the databases.
+func (g *geoIP) Close() error {
+	var errs []error
+	for _, db := range []*maxminddb.Reader{g.country, g.asn} {
+		if db != nil {
+			errs = append(errs, db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookup return what the databases know of ip. Addresses missing from
+// them only have their IP set.
+func (g *geoIP) lookup(ip net.IP) *Geo {
+	geo := &Geo{IP: ip.String()}
+	if g.country != nil {
+		var record struct {
+			Country struct {
+				ISOCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		if g.country.Lookup(ip, &record) == nil {
+			geo.Country = record.Country.ISOCode
+		}
+	}
+	if g.asn != nil {
+		var record struct {
+			ASN uint   `maxminddb:"autonomous_system_number"`
+			Org string `maxminddb:"autonomous_system_organization"`
+		}
+		if g.asn.Lookup(ip, &record) == nil {
+			geo.ASN, geo.Org = record.ASN, strings.TrimSpace(record.Org)
+		}
+	}
+	return geo
+}
+
+// remoteIP return the ip of a remote address, nil when it has none.
+func remoteIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return nil
+}
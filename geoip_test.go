@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeMMDB write to path an IPv4 MaxMind database holding record for the
+// addresses of network.
+func writeMMDB(t *testing.T, path string, network *net.IPNet, record map[string]any) {
+	t.Helper()
+	var encode func(buf []byte, v any) []byte
+	// Sizes from 29 to 284 take an extra byte.
+	control := func(buf []byte, typ, size int) []byte {
+		var extra []byte
+		if size >= 29 {
+			size, extra = 29, []byte{byte(size - 29)}
+		}
+		if typ > 7 {
+			buf = append(buf, byte(size), byte(typ-7))
+		} else {
+			buf = append(buf, byte(typ<<5|size))
+		}
+		return append(buf, extra...)
+	}
+	encode = func(buf []byte, v any) []byte {
+		switch v := v.(type) {
+		case string:
+			return append(control(buf, 2, len(v)), v...)
+		case uint64:
+			b := binary.BigEndian.AppendUint64(nil, v)
+			for len(b) > 0 && b[0] == 0 {
+				b = b[1:]
+			}
+			return append(control(buf, 9, len(b)), b...)
+		case map[string]any:
+			buf = control(buf, 7, len(v))
+			for k, e := range v {
+				buf = encode(encode(buf, k), e)
+			}
+			return buf
+		case []any:
+			buf = control(buf, 11, len(v))
+			for _, e := range v {
+				buf = encode(buf, e)
+			}
+			return buf
+		}
+		t.Fatalf("cannot encode %T", v)
+		return nil
+	}
+
+	// A node per bit of the network, leading to the record on the side of
+	// that bit and nowhere on the other.
+	ones, _ := network.Mask.Size()
+	nodes := uint32(ones)
+	var db []byte
+	for i := 0; i < ones; i++ {
+		next := uint32(i + 1)
+		if i == ones-1 {
+			next = nodes + 16
+		}
+		records := [2]uint32{nodes, nodes}
+		records[network.IP.To4()[i/8]>>(7-i%8)&1] = next
+		for _, r := range records {
+			db = append(db, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+	db = append(db, make([]byte, 16)...)
+	db = encode(db, record)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	db = encode(db, map[string]any{
+		"node_count":                  uint64(nodes),
+		"record_size":                 uint64(24),
+		"ip_version":                  uint64(4),
+		"database_type":               "Test",
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint64(2),
+		"binary_format_minor_version": uint64(0),
+		"build_epoch":                 uint64(1700000000),
+	})
+	if err := os.WriteFile(path, db, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoIP(t *testing.T) {
+	dir := t.TempDir()
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	countryDB, asnDB := filepath.Join(dir, "country.mmdb"), filepath.Join(dir, "asn.mmdb")
+	writeMMDB(t, countryDB, loopback, map[string]any{
+		"country": map[string]any{"iso_code": "FR", "names": map[string]any{"en": "France"}},
+	})
+	writeMMDB(t, asnDB, loopback, map[string]any{
+		"autonomous_system_number":       uint64(64496),
+		"autonomous_system_organization": "Example Org",
+	})
+	geo, err := openGeoIP(countryDB, asnDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+
+	if got := geo.lookup(net.ParseIP("192.0.2.1")); *got != (Geo{IP: "192.0.2.1"}) {
+		t.Errorf("unknown address: got %+v", got)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	res := newChecker(WithGeoIP(geo)).checkURL(context.Background(), Service{URL: srv.URL})
+	want := Geo{IP: "127.0.0.1", Country: "FR", ASN: 64496, Org: "Example Org"}
+	if res.Geo == nil || *res.Geo != want {
+		t.Fatalf("got %+v; want %+v", res.Geo, want)
+	}
+	if got, want := res.Geo.String(), "127.0.0.1 FR AS64496 Example Org"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
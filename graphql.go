@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultGraphQLQuery is the query of graphql=true services, which any
+// GraphQL server answers.
+const DefaultGraphQLQuery = "{ __typename }"
+
+// GraphQLError report a GraphQL response with errors, which servers send
+// with a 200 status.
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// graphQLRequest return the body of the POST request sending query.
+func graphQLRequest(query string) string {
+	body, _ := json.Marshal(struct {
+		Query string `json:"query"`
+	}{query})
+	return string(body)
+}
+
+// checkGraphQL return an error unless resp has an expected status and a
+// GraphQL response body without errors.
+func checkGraphQL(resp *http.Response, expect []int) error {
+	if !expectedStatus(resp.StatusCode, expect) {
+		return &StatusError{Status: resp.StatusCode, Expect: expect}
+	}
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxProbeBody)).Decode(&body); err != nil {
+		return fmt.Errorf("graphql response: %w", err)
+	}
+	if len(body.Errors) > 0 {
+		gerr := &GraphQLError{}
+		for _, e := range body.Errors {
+			gerr.Messages = append(gerr.Messages, e.Message)
+		}
+		return gerr
+	}
+	if body.Data == nil {
+		return fmt.Errorf("graphql response: no data")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch req.Query {
+		case DefaultGraphQLQuery:
+			w.Write([]byte(`{"data": {"__typename": "Query"}}`))
+		case "{ orders { id } }":
+			w.Write([]byte(`{"data": null, "errors": [{"message": "database unavailable"}, {"message": "retry later"}]}`))
+		default:
+			w.Write([]byte(`<html>maintenance</html>`))
+		}
+	}))
+	defer srv.Close()
+
+	c := newChecker(WithRetries(0))
+	check := func(options string) Result {
+		t.Helper()
+		svc, err := ParseService(srv.URL + " " + options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.checkURL(context.Background(), svc)
+	}
+	if res := check("graphql=true"); !res.Up() {
+		t.Errorf("default query: want up; got %v", res.Err)
+	}
+	var gerr *GraphQLError
+	if res := check(`graphql-query="{ orders { id } }"`); !errors.As(res.Err, &gerr) || len(gerr.Messages) != 2 {
+		t.Errorf("errors: want a GraphQLError; got %v", res.Err)
+	} else if got, want := gerr.Error(), "graphql: database unavailable; retry later"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if res := check(`graphql-query="{ other }"`); res.Err == nil {
+		t.Error("not json: want an error")
+	}
+	if _, err := ParseService(srv.URL + " graphql=true cors-origin=https://a.com"); err == nil {
+		t.Error("graphql with cors-origin: want an error")
+	}
+}
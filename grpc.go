@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"coding-challenge/checkpb"
+)
+
+// grpcChecker implement the Checker gRPC service with a checker.
+type grpcChecker struct {
+	checkpb.UnimplementedCheckerServer
+	c *checker
+}
+
+// newGRPCServer return a gRPC server whose checks are run by c. Streams
+// must carry token as a bearer token in their authorization metadata,
+// unless it is empty.
+func newGRPCServer(c *checker, token string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts, grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			got := []byte(strings.Join(md.Get("authorization"), ","))
+			if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+				return status.Error(codes.Unauthenticated, "invalid token")
+			}
+			return handler(srv, ss)
+		}))
+	}
+	srv := grpc.NewServer(opts...)
+	checkpb.RegisterCheckerServer(srv, &grpcChecker{c: c})
+	return srv
+}
+
+// Check check the services of the request stream with the worker pool,
+// sending results as they complete. Services are parsed as those of POST
+// /check, see parseAPIService. A request is only received once a
+// worker takes the previous one, so that slow checks hold clients back
+// through flow control instead of piling up requests.
+func (g *grpcChecker) Check(stream checkpb.Checker_CheckServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	results := make(chan *checkpb.CheckResult)
+	send := func(res *checkpb.CheckResult) {
+		select {
+		case results <- res:
+		case <-ctx.Done():
+		}
+	}
+
+	jobs := make(chan job)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					recvErr <- err
+				}
+				return
+			}
+			svc, err := parseAPIService(req.GetService())
+			if err != nil {
+				send(&checkpb.CheckResult{Id: req.GetId(), State: checkpb.State_STATE_INVALID, Error: err.Error()})
+				continue
+			}
+			mu.Lock()
+			ids = append(ids, req.GetId())
+			i := len(ids) - 1
+			mu.Unlock()
+			select {
+			case jobs <- job{i: i, svc: svc}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(results)
+		check := func(svc Service) Result { return g.c.check(ctx, svc) }
+		g.c.pool(jobs, check, func(j job, res Result) {
+			mu.Lock()
+			id := ids[j.i]
+			mu.Unlock()
+			pb := newCheckResult(res)
+			pb.Id = id
+			send(pb)
+		})
+	}()
+
+	for res := range results {
+		if err := stream.Send(res); err != nil {
+			cancel()
+			for range results {
+			}
+			return err
+		}
+	}
+	select {
+	case err := <-recvErr:
+		return err
+	default:
+		return nil
+	}
+}
+
+// checkStates map the states of results to their protobuf value.
+var checkStates = map[string]checkpb.State{
+	"up":          checkpb.State_STATE_UP,
+	"down":        checkpb.State_STATE_DOWN,
+	"dependency":  checkpb.State_STATE_DEPENDENCY_DOWN,
+	"maintenance": checkpb.State_STATE_MAINTENANCE,
+}
+
+func newCheckResult(res Result) *checkpb.CheckResult {
+	pb := &checkpb.CheckResult{
+		Name:   res.Name,
+		Url:    res.Url,
+		Tags:   res.Tags,
+		State:  checkStates[state(res)],
+		Status: int32(res.Status),
+		Error:  errorString(res.Err),
+	}
+	if res.Latency > 0 {
+		pb.Latency = durationpb.New(res.Latency)
+	}
+	for _, member := range res.Members {
+		pb.Members = append(pb.Members, newCheckResult(member))
+	}
+	return pb
+}
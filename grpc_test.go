@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"coding-challenge/checkpb"
+)
+
+func TestGRPCCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcSrv := newGRPCServer(newChecker(WithWorkers(2)), "secret")
+	go grpcSrv.Serve(lis)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := checkpb.NewCheckerClient(conn)
+	stream, err := client.Check(context.Background())
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("want Unauthenticated without token; got %v", err)
+	}
+
+	stream, err = client.Check(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string]string{
+		"up":         srv.URL + " #api",
+		"unexpected": srv.URL + " expect=204",
+		"invalid":    "gopher://a.com",
+		"header":     srv.URL + " header=Authorization:${TOKEN}",
+		"protocol":   "redis://a.com:6379",
+	}
+	for id, service := range requests {
+		if err := stream.Send(&checkpb.CheckRequest{Id: id, Service: service}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]*checkpb.CheckResult)
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[res.GetId()] = res
+	}
+	if len(got) != len(requests) {
+		t.Fatalf("want %d results; got %v", len(requests), got)
+	}
+	if r := got["up"]; r.GetState() != checkpb.State_STATE_UP || r.GetStatus() != 200 || r.GetTags()[0] != "api" || r.GetLatency().AsDuration() <= 0 {
+		t.Errorf("want up with a latency; got %v", r)
+	}
+	if r := got["unexpected"]; r.GetState() != checkpb.State_STATE_DOWN || r.GetError() == "" {
+		t.Errorf("want down with an error; got %v", r)
+	}
+	for _, id := range []string{"invalid", "header", "protocol"} {
+		if r := got[id]; r.GetState() != checkpb.State_STATE_INVALID || r.GetError() == "" {
+			t.Errorf("%s: want invalid with an error; got %v", id, r)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithHAR record every request of the checks, with its response and
+// timings, in h.
+func WithHAR(h *HAR) Option {
+	return func(c *checker) { c.har = h }
+}
+
+// HAR record requests in the HTTP Archive format, see
+// http://www.softwareishard.com/blog/har-12-spec/. The zero value is ready to
+// use and may be used concurrently.
+type HAR struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// Encode write the recorded requests to w as a HAR file, ordered by start.
+func (h *HAR) Encode(w io.Writer) error {
+	h.mu.Lock()
+	entries := append([]harEntry{}, h.entries...)
+	h.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].start.Before(entries[j].start) })
+
+	var file struct {
+		Log harLog `json:"log"`
+	}
+	file.Log = harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "healthcheck", Version: "1.0"},
+		Entries: entries,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// writeHAR write the requests recorded in h to the HAR file at path, only
+// readable by its owner.
+func writeHAR(path string, h *HAR) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := h.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (h *HAR) add(e harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	start           time.Time
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Service         string      `json:"_service"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// harTimings are durations in milliseconds, -1 when they do not apply.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harTransport record the requests of a service sent through next in har.
+type harTransport struct {
+	next    http.RoundTripper
+	har     *HAR
+	service string
+}
+
+// RoundTrip send req through next and record it once its response body is
+// closed, or right away when it fails.
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := &harEntry{start: time.Now(), Service: harService(t.service), Request: harRequestOf(req)}
+	var times harTrace
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), times.clientTrace()))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		t.har.add(e.finish(&times, time.Now()))
+		return nil, err
+	}
+	e.Response = harResponseOf(resp)
+	if addr := times.remoteAddr; addr != nil {
+		e.ServerIPAddress, _, _ = net.SplitHostPort(addr.String())
+	}
+	// Checks usually close bodies without reading them, the announced
+	// length is the best known size then.
+	resp.Body = &harBody{ReadCloser: resp.Body, done: func(n int64) {
+		e.Response.Content.Size = max(n, resp.ContentLength)
+		e.Response.BodySize = max(n, resp.ContentLength)
+		t.har.add(e.finish(&times, time.Now()))
+	}}
+	return resp, nil
+}
+
+// finish complete e with the timings of its request, ended at end.
+func (e *harEntry) finish(times *harTrace, end time.Time) harEntry {
+	times.mu.Lock()
+	defer times.mu.Unlock()
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return float64(to.Sub(from)) / float64(time.Millisecond)
+	}
+	connectEnd := times.tlsDone
+	if connectEnd.IsZero() {
+		connectEnd = times.connectDone
+	}
+	t := harTimings{
+		DNS:     ms(times.dnsStart, times.dnsDone),
+		Connect: ms(times.connectStart, connectEnd),
+		SSL:     ms(times.tlsStart, times.tlsDone),
+		Send:    ms(times.gotConn, times.wroteRequest),
+		Wait:    ms(times.wroteRequest, times.firstByte),
+		Receive: ms(times.firstByte, end),
+	}
+	t.Blocked = ms(e.start, times.gotConn) - max(t.DNS, 0) - max(t.Connect, 0)
+	if times.gotConn.IsZero() {
+		t.Blocked = -1
+	}
+	e.Timings = t
+	e.StartedDateTime = e.start.Format(time.RFC3339Nano)
+	e.Time = float64(end.Sub(e.start)) / float64(time.Millisecond)
+	return *e
+}
+
+// harTrace collect the times of the steps of a request.
+type harTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	remoteAddr   net.Addr
+}
+
+func (h *harTrace) clientTrace() *httptrace.ClientTrace {
+	set := func(t *time.Time) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		// Keep the first time, dialing may try several addresses.
+		if t.IsZero() {
+			*t = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set(&h.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&h.dnsDone) },
+		ConnectStart:      func(string, string) { set(&h.connectStart) },
+		ConnectDone:       func(string, string, error) { set(&h.connectDone) },
+		TLSHandshakeStart: func() { set(&h.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&h.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(&h.gotConn)
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.remoteAddr = info.Conn.RemoteAddr()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&h.wroteRequest) },
+		GotFirstResponseByte: func() { set(&h.firstByte) },
+	}
+}
+
+// harBody count the bytes read from a response body and call done with
+// their number when closed.
+type harBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(n int64)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n) })
+	return err
+}
+
+// harRedacted replace the values of headers, cookies and query parameters
+// which may hold secrets in HAR files, as redactURL does passwords.
+const harRedacted = "xxxxx"
+
+// harRequestHeaders are the request headers recorded as they are, the
+// values of others, such as Authorization or those of header= which may
+// come from Vault, being redacted.
+var harRequestHeaders = map[string]bool{
+	"Accept":                         true,
+	"Accept-Encoding":                true,
+	"Access-Control-Request-Headers": true,
+	"Access-Control-Request-Method":  true,
+	"Cache-Control":                  true,
+	"Content-Length":                 true,
+	"Content-Type":                   true,
+	"If-Modified-Since":              true,
+	"If-None-Match":                  true,
+	"Origin":                         true,
+	"Range":                          true,
+	"User-Agent":                     true,
+	"X-Request-Id":                   true,
+}
+
+// sensitiveName report whether the header or query parameter name may hold
+// a secret.
+func sensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"auth", "token", "key", "secret", "pass", "sig", "session", "cookie", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// harURL return u as recorded in HAR files, with its password and the
+// values of its sensitive query parameters redacted.
+func harURL(u *url.URL) string {
+	query, redacted := u.Query(), false
+	for name, values := range query {
+		if sensitiveName(name) {
+			for i := range values {
+				values[i] = harRedacted
+			}
+			redacted = true
+		}
+	}
+	if redacted {
+		v := *u
+		v.RawQuery = query.Encode()
+		u = &v
+	}
+	return redactURL(u.String())
+}
+
+// harService return the key of a service as recorded in HAR files, redacted
+// as harURL does when it is the url of the service.
+func harService(key string) string {
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		return harURL(u)
+	}
+	return key
+}
+
+func harRequestOf(req *http.Request) harRequest {
+	r := harRequest{
+		Method:      req.Method,
+		URL:         harURL(req.URL),
+		HTTPVersion: req.Proto,
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     harHeaders(req.Header, func(name string) bool { return !harRequestHeaders[name] }),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    req.ContentLength,
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		r.Headers = append([]harNameValue{{Name: "Host", Value: req.Host}}, r.Headers...)
+	}
+	query, _ := url.ParseQuery(req.URL.RawQuery)
+	for name, values := range query {
+		for _, v := range values {
+			if sensitiveName(name) {
+				v = harRedacted
+			}
+			r.QueryString = append(r.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(r.QueryString, func(i, j int) bool { return r.QueryString[i].Name < r.QueryString[j].Name })
+	if req.ContentLength > 0 && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			text, _ := io.ReadAll(body)
+			body.Close()
+			r.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(text)}
+		}
+	}
+	return r
+}
+
+func harResponseOf(resp *http.Response) harResponse {
+	r := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     harCookies(resp.Cookies()),
+		Headers:     harHeaders(resp.Header, sensitiveName),
+		Content:     harContent{MimeType: resp.Header.Get("Content-Type")},
+		HeadersSize: -1,
+	}
+	// Redirects may carry tokens in their query too.
+	if location, err := url.Parse(resp.Header.Get("Location")); err == nil && location.String() != "" {
+		r.RedirectURL = harURL(location)
+		for i, h := range r.Headers {
+			if h.Name == "Location" {
+				r.Headers[i].Value = r.RedirectURL
+			}
+		}
+	}
+	return r
+}
+
+// harHeaders return the headers of h, with the values of those redact
+// reports redacted.
+func harHeaders(h http.Header, redact func(name string) bool) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			if redact(name) {
+				v = harRedacted
+			}
+			headers = append(headers, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// harCookies return cookies with their values redacted.
+func harCookies(cookies []*http.Cookie) []harCookie {
+	c := []harCookie{}
+	for _, cookie := range cookies {
+		c = append(c, harCookie{Name: cookie.Name, Value: harRedacted})
+	}
+	return c
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHAR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new?a=1", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	var har HAR
+	services := []Service{{Name: "a", URL: srv.URL + "/old"}, {URL: down.URL}}
+	HealthCheck(services, WithHAR(&har), WithRetries(0))
+
+	var buf bytes.Buffer
+	if err := har.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Log struct {
+			Version string
+			Entries []struct {
+				Request struct {
+					Method      string
+					URL         string
+					QueryString []harNameValue
+				}
+				Response struct {
+					Status  int
+					Content harContent
+				}
+				Timings harTimings
+				Service string `json:"_service"`
+				Error   string `json:"_error"`
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Log.Version != "1.2" || len(file.Log.Entries) != 3 {
+		t.Fatalf("want 3 entries of a HAR 1.2 file; got %s", buf.String())
+	}
+
+	byURL := map[string]int{}
+	for i, e := range file.Log.Entries {
+		byURL[e.Request.URL] = i
+	}
+	redirect := file.Log.Entries[byURL[srv.URL+"/old"]]
+	if redirect.Response.Status != http.StatusFound || redirect.Service != "a" || redirect.Request.Method != http.MethodGet {
+		t.Errorf("redirect: got %+v", redirect)
+	}
+	target := file.Log.Entries[byURL[srv.URL+"/new?a=1"]]
+	if target.Response.Status != http.StatusOK || target.Response.Content.Size != 2 || target.Response.Content.MimeType != "text/plain" {
+		t.Errorf("target: got %+v", target)
+	}
+	if len(target.Request.QueryString) != 1 || target.Request.QueryString[0] != (harNameValue{Name: "a", Value: "1"}) {
+		t.Errorf("query string: got %+v", target.Request.QueryString)
+	}
+	if target.Timings.Wait < 0 || target.Timings.Send < 0 {
+		t.Errorf("timings: got %+v", target.Timings)
+	}
+	failed := file.Log.Entries[byURL[down.URL]]
+	if failed.Error == "" || failed.Response.Status != 0 || failed.Service != down.URL {
+		t.Errorf("failed: got %+v", failed)
+	}
+}
+
+func TestHARRedaction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.Redirect(w, r, "/home?session_id=s3ss10n", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "c00k13"})
+	}))
+	defer srv.Close()
+
+	svc, err := ParseService(srv.URL + `/login?api_key=k3y&page=2 header="Authorization: Bearer t0k3n" header=X-Custom:v4ult`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har HAR
+	HealthCheck([]Service{svc}, WithHAR(&har), WithRetries(0))
+	path := filepath.Join(t.TempDir(), "checks.har")
+	if err := writeHAR(path, &har); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("want a 0600 file; got %o", perm)
+	}
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{"k3y", "t0k3n", "v4ult", "s3ss10n", "c00k13"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("want %s redacted; got %s", secret, data)
+		}
+	}
+	for _, kept := range []string{"page=2", `"User-Agent"`, `"sid"`} {
+		if !bytes.Contains(data, []byte(kept)) {
+			t.Errorf("want %s recorded; got %s", kept, data)
+		}
+	}
+}
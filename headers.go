@@ -0,0 +1,26 @@
+package main
+
+import "net/http"
+
+// DefaultUserAgent is the User-Agent sent with checks unless WithUserAgent
+// sets another one.
+const DefaultUserAgent = "healthcheck/1.0"
+
+// WithUserAgent set the User-Agent sent with checks, for instance that of a
+// browser when a firewall blocks unknown agents. A header=User-Agent:...
+// option of a service takes precedence.
+func WithUserAgent(agent string) Option {
+	return func(c *checker) { c.userAgent = agent }
+}
+
+// setHeaders set the headers common to every request of a check on req:
+// the User-Agent, the headers of svc then the Host header.
+func (c *checker) setHeaders(req *http.Request, svc Service) {
+	req.Header.Set("User-Agent", c.userAgent)
+	for name, values := range svc.Header {
+		req.Header[name] = values
+	}
+	if host := c.hostHeaderOf(svc); host != "" {
+		req.Host = host
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		svc  Service
+		want string
+	}{
+		{"default", nil, Service{URL: srv.URL}, DefaultUserAgent},
+		{"option", []Option{WithUserAgent("Mozilla/5.0")}, Service{URL: srv.URL}, "Mozilla/5.0"},
+		{"service header", []Option{WithUserAgent("Mozilla/5.0")},
+			Service{URL: srv.URL, Header: http.Header{"User-Agent": {"svc"}}}, "svc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := newChecker(tt.opts...).checkURL(context.Background(), tt.svc); !res.Up() {
+				t.Fatal(res.Err)
+			}
+			if got != tt.want {
+				t.Errorf("want User-Agent %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
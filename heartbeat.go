@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// heartbeatTimeout bound the time spent pinging the heartbeat endpoint so a
+// slow monitoring service never delays the end of a run.
+const heartbeatTimeout = 10 * time.Second
+
+// pingHeartbeat notify a dead-man-switch service (healthchecks.io style) of
+// the outcome of a run: url is pinged on success and url/fail on failure.
+func pingHeartbeat(ctx context.Context, url string, success bool) error {
+	if !success {
+		url = strings.TrimSuffix(url, "/") + "/fail"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("heartbeat: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("heartbeat: %s: %s", url, resp.Status)
+	}
+	return nil
+}
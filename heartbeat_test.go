@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPingHeartbeat(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	if err := pingHeartbeat(context.Background(), srv.URL+"/abc", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := pingHeartbeat(context.Background(), srv.URL+"/abc/", false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/abc", "/abc/fail"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("want pings %v; got %v", want, paths)
+	}
+}
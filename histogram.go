@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// histSubBuckets is the number of linear buckets each power of two range of
+// a histogram is split in, bounding the error of percentiles to 1/16.
+const histSubBuckets = 16
+
+// histogram count latencies in log-linear buckets of microseconds, as HDR
+// histograms do, so that percentiles keep the same relative precision from
+// microseconds to minutes with a few hundred buckets at most.
+type histogram struct {
+	counts   []int
+	total    int
+	min, max time.Duration
+}
+
+// bucketOf return the index of the bucket of d.
+func bucketOf(d time.Duration) int {
+	us := uint64(max(d.Microseconds(), 0))
+	if us < histSubBuckets {
+		return int(us)
+	}
+	e := bits.Len64(us) - 1 // us is in [2^e, 2^(e+1))
+	shift := e - bits.Len64(histSubBuckets) + 1
+	return histSubBuckets*(shift+1) + int(us>>shift) - histSubBuckets
+}
+
+// bucketBounds return the range [lo, hi) of the bucket i.
+func bucketBounds(i int) (lo, hi time.Duration) {
+	if i < histSubBuckets {
+		return time.Duration(i) * time.Microsecond, time.Duration(i+1) * time.Microsecond
+	}
+	shift := i/histSubBuckets - 1
+	sub := histSubBuckets + i%histSubBuckets
+	return time.Duration(sub<<shift) * time.Microsecond, time.Duration((sub+1)<<shift) * time.Microsecond
+}
+
+func (h *histogram) record(d time.Duration) {
+	i := bucketOf(d)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.total++
+}
+
+// percentile return the p-th percentile, the upper bound of the bucket of
+// the nearest-rank sample capped to the recorded extremes.
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := max(int(p/100*float64(h.total)+0.999999999), 1)
+	seen := 0
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			_, hi := bucketBounds(i)
+			return min(max(hi, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// histRange is a power of two range of latencies and the number of them.
+type histRange struct {
+	From, To time.Duration
+	Count    int
+}
+
+// ranges return the counts per power of two range of microseconds, from
+// the first to the last non-empty one.
+func (h *histogram) ranges() []histRange {
+	var ranges []histRange
+	for i, n := range h.counts {
+		lo, hi := bucketBounds(i)
+		if i >= histSubBuckets {
+			// Group the sub-buckets of a power of two.
+			lo, _ = bucketBounds(i - i%histSubBuckets)
+			_, hi = bucketBounds(i - i%histSubBuckets + histSubBuckets - 1)
+		} else {
+			lo, hi = 0, histSubBuckets*time.Microsecond
+		}
+		if len(ranges) == 0 || ranges[len(ranges)-1].From != lo {
+			if n == 0 && len(ranges) == 0 {
+				continue
+			}
+			ranges = append(ranges, histRange{From: lo, To: hi})
+		}
+		ranges[len(ranges)-1].Count += n
+	}
+	for len(ranges) > 0 && ranges[len(ranges)-1].Count == 0 {
+		ranges = ranges[:len(ranges)-1]
+	}
+	return ranges
+}
+
+// writeHistogram print the percentiles of h and its distribution as bars.
+func writeHistogram(w io.Writer, h *histogram) {
+	round := func(d time.Duration) time.Duration {
+		if d < time.Millisecond {
+			return d.Round(time.Microsecond)
+		}
+		return d.Round(100 * time.Microsecond)
+	}
+	fmt.Fprintf(w, "Latency: %d samples; Min: %s; P50: %s; P90: %s; P99: %s; P99.9: %s; Max: %s\n",
+		h.total, round(h.min), round(h.percentile(50)), round(h.percentile(90)),
+		round(h.percentile(99)), round(h.percentile(99.9)), round(h.max))
+
+	ranges := h.ranges()
+	widest := 0
+	for _, r := range ranges {
+		widest = max(widest, r.Count)
+	}
+	for _, r := range ranges {
+		bar := strings.Repeat("#", (r.Count*40+widest-1)/widest)
+		line := fmt.Sprintf("  %9s - %-9s %6d %s", r.From, r.To, r.Count, bar)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
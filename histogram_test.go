@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	for _, d := range []time.Duration{0, 5 * time.Microsecond, 16 * time.Microsecond, 33 * time.Microsecond,
+		time.Millisecond, 12345 * time.Microsecond, 3 * time.Second, 2 * time.Minute} {
+		lo, hi := bucketBounds(bucketOf(d))
+		if d.Truncate(time.Microsecond) < lo || d >= hi {
+			t.Errorf("%s: out of its bucket [%s, %s)", d, lo, hi)
+		}
+		if hi-lo > max(hi/histSubBuckets, time.Microsecond) {
+			t.Errorf("%s: bucket [%s, %s) too wide", d, lo, hi)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+		{100, time.Second},
+	} {
+		got := h.percentile(tt.p)
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/histSubBuckets {
+			t.Errorf("p%v: want %s within 1/%d; got %s", tt.p, tt.want, histSubBuckets, got)
+		}
+	}
+	if h.min != time.Millisecond || h.max != time.Second {
+		t.Errorf("want extremes 1ms and 1s; got %s and %s", h.min, h.max)
+	}
+
+	total := 0
+	for _, r := range h.ranges() {
+		total += r.Count
+	}
+	if total != 1000 {
+		t.Errorf("want 1000 latencies in the ranges; got %d", total)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// punycodeURL return rawURL with an internationalized host converted to
+// punycode, as sent in requests, and the url with its unicode host, as
+// shown to people. unicode is empty when the host is plain ASCII.
+// Urls which do not parse are returned unchanged, validateURL reporting
+// them.
+func punycodeURL(rawURL string) (ascii, unicode string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, "", nil
+	}
+	host := u.Hostname()
+	if !isIDN(host) || net.ParseIP(host) != nil {
+		return rawURL, "", nil
+	}
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", "", &URLError{URL: rawURL, Reason: "invalid internationalized host: " + strings.TrimPrefix(err.Error(), "idna: ")}
+	}
+	unicodeHost, err := idna.Display.ToUnicode(asciiHost)
+	if err != nil {
+		return "", "", &URLError{URL: rawURL, Reason: "invalid internationalized host: " + strings.TrimPrefix(err.Error(), "idna: ")}
+	}
+	port := u.Port()
+	u.Host = asciiHost
+	if port != "" {
+		u.Host += ":" + port
+	}
+	ascii = u.String()
+	// String would escape the unicode host, replace it afterwards.
+	unicode = strings.Replace(ascii, asciiHost, unicodeHost, 1)
+	return ascii, unicode, nil
+}
+
+// isIDN report whether host has non ASCII characters or punycode labels.
+func isIDN(host string) bool {
+	for i := 0; i < len(host); i++ {
+		if host[i] >= 0x80 {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(host), "xn--") || strings.Contains(strings.ToLower(host), ".xn--")
+}
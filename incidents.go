@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultIncidentLog is the number of closed incidents kept by serve mode.
+const DefaultIncidentLog = 100
+
+// incidentLog track the incidents of serve mode: an incident opens when a
+// service goes down and closes when it is up again. Services in
+// maintenance leave their incidents as they are.
+type incidentLog struct {
+	limit int
+
+	mu     sync.Mutex
+	open   map[string]*incident
+	closed []incident
+}
+
+func newIncidentLog(limit int) *incidentLog {
+	return &incidentLog{limit: max(limit, 1), open: make(map[string]*incident)}
+}
+
+// update open and close incidents from the results of a run at t.
+func (l *incidentLog) update(results []Result, t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, res := range results {
+		key := resultKey(res)
+		i := l.open[key]
+		switch state(res) {
+		case "maintenance":
+		case "up":
+			if i == nil {
+				continue
+			}
+			i.End = t
+			if len(l.closed) == l.limit {
+				l.closed = append(l.closed[:0], l.closed[1:]...)
+			}
+			l.closed = append(l.closed, *i)
+			delete(l.open, key)
+		default:
+			if i != nil {
+				i.Last = t
+				continue
+			}
+			l.open[key] = &incident{Key: key, Start: t, Last: t, Err: errorString(res.Err)}
+		}
+	}
+}
+
+// incidents return the open incidents then the closed ones, most recent
+// first.
+func (l *incidentLog) incidents() []incident {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	all := make([]incident, 0, len(l.open)+len(l.closed))
+	for _, i := range l.open {
+		all = append(all, *i)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start.After(all[j].Start) })
+	for i := len(l.closed) - 1; i >= 0; i-- {
+		all = append(all, l.closed[i])
+	}
+	return all
+}
+
+// jsonIncident is the JSON form of an incident.
+type jsonIncident struct {
+	Service    string     `json:"service"`
+	Start      time.Time  `json:"start"`
+	End        *time.Time `json:"end,omitempty"`
+	Ongoing    bool       `json:"ongoing"`
+	DurationMS float64    `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func newJSONIncident(i incident) jsonIncident {
+	j := jsonIncident{Service: i.Key, Start: i.Start, Ongoing: i.End.IsZero(), DurationMS: millis(i.duration()), Error: i.Err}
+	if !j.Ongoing {
+		j.End = &i.End
+	}
+	return j
+}
+
+// incidentsHandler serve the incidents of log as JSON, most recent first.
+func incidentsHandler(log *incidentLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		incidents := []jsonIncident{}
+		for _, i := range log.incidents() {
+			incidents = append(incidents, newJSONIncident(i))
+		}
+		writeJSON(w, http.StatusOK, incidents)
+	})
+}
+
+// atomFeed is an Atom feed, see RFC 4287.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+}
+
+// incidentsFeedHandler serve the incidents of log as an Atom feed, an
+// entry per incident updated when it closes.
+func incidentsFeedHandler(log *incidentLog) http.Handler {
+	started := time.Now()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		self := (&url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path}).String()
+		feed := atomFeed{
+			ID:     self,
+			Title:  "healthcheck incidents",
+			Link:   atomLink{Href: self, Rel: "self"},
+			Author: atomAuthor{Name: "healthcheck"},
+		}
+		// The feed changes when an incident opens or closes.
+		var updated time.Time
+		for _, i := range log.incidents() {
+			e := atomEntry{
+				ID:      fmt.Sprintf("%s#%s@%d", self, url.PathEscape(i.Key), i.Start.UnixNano()),
+				Updated: i.Start.UTC().Format(time.RFC3339),
+				Title:   i.Key + " is down",
+				Summary: fmt.Sprintf("Down since %s", i.Start.UTC().Format(time.RFC1123)),
+			}
+			if !i.End.IsZero() {
+				e.Updated = i.End.UTC().Format(time.RFC3339)
+				e.Title = fmt.Sprintf("%s was down for %s", i.Key, i.duration().Round(time.Second))
+				e.Summary = fmt.Sprintf("Down from %s to %s", i.Start.UTC().Format(time.RFC1123), i.End.UTC().Format(time.RFC1123))
+			}
+			if i.Err != "" {
+				e.Summary += ": " + i.Err
+			}
+			updated = maxTime(updated, i.Start, i.End)
+			feed.Entries = append(feed.Entries, e)
+		}
+		if updated.IsZero() {
+			updated = started
+		}
+		feed.Updated = updated.UTC().Format(time.RFC3339)
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		enc.Encode(feed)
+	})
+}
+
+func maxTime(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncidents(t *testing.T) {
+	s := newServer(newChecker(), 10)
+	mux := s.handler()
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	if code, body := get("/incidents"); code != http.StatusOK || body != "[]" {
+		t.Errorf("want no incidents; got %d %s", code, body)
+	}
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := Result{Url: "https://a.com", Status: 200}
+	aDown := Result{Url: "https://a.com", Status: 503, Err: &StatusError{Status: 503}}
+	db := Result{Name: "db", Url: "https://db.a.com", Status: 200}
+	dbDown := Result{Name: "db", Url: "https://db.a.com", Err: errors.New("refused")}
+	runs := [][]Result{
+		{aDown, db},
+		{aDown, db},
+		{a, db},
+		{a, Result{Name: "db", Maintenance: true}},
+		{a, dbDown},
+	}
+	for i, results := range runs {
+		s.incidents.update(results, t0.Add(time.Duration(i)*time.Minute))
+	}
+
+	code, body := get("/incidents")
+	want := `[{"service":"db","start":"2026-01-01T00:04:00Z","ongoing":true,"duration_ms":0,"error":"refused"},` +
+		`{"service":"https://a.com","start":"2026-01-01T00:00:00Z","end":"2026-01-01T00:02:00Z","ongoing":false,"duration_ms":120000,"error":"unexpected status 503"}]`
+	if code != http.StatusOK || body != want {
+		t.Errorf("want:\n%s\ngot %d:\n%s", want, code, body)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://status.a.com/incidents.atom", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("want an Atom feed; got %s", ct)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.ID != "http://status.a.com/incidents.atom" || feed.Updated != "2026-01-01T00:04:00Z" || len(feed.Entries) != 2 {
+		t.Fatalf("got %+v", feed)
+	}
+	if e := feed.Entries[0]; e.Title != "db is down" || !strings.HasSuffix(e.Summary, ": refused") {
+		t.Errorf("open incident: got %+v", e)
+	}
+	if e := feed.Entries[1]; e.Title != "https://a.com was down for 2m0s" || e.Updated != "2026-01-01T00:02:00Z" {
+		t.Errorf("closed incident: got %+v", e)
+	}
+}
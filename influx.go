@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxMeasurement is the measurement name of every point written.
+const influxMeasurement = "healthcheck"
+
+var (
+	influxTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// writeInflux write results as InfluxDB line protocol, one point per result
+// and per member of composite services, timestamped with the start of their
+// check or with ts when it is unknown.
+func writeInflux(w io.Writer, results []Result, ts time.Time) error {
+	for _, res := range results {
+		if _, err := io.WriteString(w, influxLine(res, ts)); err != nil {
+			return err
+		}
+		for _, member := range res.Members {
+			if _, err := io.WriteString(w, influxLine(member, ts)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// influxLine format a result as a single line protocol point.
+func influxLine(res Result, ts time.Time) string {
+	var b strings.Builder
+	b.WriteString(influxMeasurement)
+	if res.Name != "" {
+		b.WriteString(",name=")
+		b.WriteString(influxTagEscaper.Replace(res.Name))
+	}
+	if res.Url != "" {
+		b.WriteString(",url=")
+		b.WriteString(influxTagEscaper.Replace(res.Url))
+	}
+	if res.UnicodeURL != "" {
+		b.WriteString(",url_unicode=")
+		b.WriteString(influxTagEscaper.Replace(res.UnicodeURL))
+	}
+	if res.Maintenance {
+		b.WriteString(" maintenance=true")
+	} else {
+		b.WriteString(" up=")
+		b.WriteString(strconv.FormatBool(res.Up()))
+	}
+	if len(res.Members) > 0 && res.Err == nil {
+		b.WriteString(",latency_ms=")
+		b.WriteString(formatMillis(res.Latency))
+	}
+	if res.Status != 0 {
+		b.WriteString(",status=")
+		b.WriteString(strconv.Itoa(res.Status))
+		b.WriteString("i,latency_ms=")
+		b.WriteString(formatMillis(res.Latency))
+	}
+	if s := res.Stats; s != nil {
+		b.WriteString(",samples=")
+		b.WriteString(strconv.Itoa(s.Samples))
+		b.WriteString("i,success_rate=")
+		b.WriteString(strconv.FormatFloat(s.SuccessRate(), 'f', -1, 64))
+		for _, f := range []struct {
+			name string
+			d    time.Duration
+		}{{"min_ms", s.Min}, {"avg_ms", s.Avg}, {"p95_ms", s.P95}, {"max_ms", s.Max}} {
+			b.WriteString(",")
+			b.WriteString(f.name)
+			b.WriteString("=")
+			b.WriteString(formatMillis(f.d))
+		}
+	}
+	if res.BytesTransferred > 0 {
+		b.WriteString(`,content_encoding="`)
+		b.WriteString(influxStringEscaper.Replace(res.ContentEncoding))
+		b.WriteString(`",bytes_transferred=`)
+		b.WriteString(strconv.FormatInt(res.BytesTransferred, 10))
+		b.WriteString("i,bytes_decoded=")
+		b.WriteString(strconv.FormatInt(res.BytesDecoded, 10))
+		b.WriteString("i")
+	}
+	if res.Err != nil {
+		b.WriteString(`,error="`)
+		b.WriteString(influxStringEscaper.Replace(res.Err.Error()))
+		b.WriteString(`",error_code="`)
+		b.WriteString(string(ErrorCodeOf(res.Err)))
+		b.WriteByte('"')
+	}
+	if !res.Start.IsZero() {
+		ts = res.Start
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(ts.UnixNano(), 10))
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// pushInflux send results to an InfluxDB or Telegraf HTTP write endpoint such
+// as http://localhost:8086/api/v2/write?org=o&bucket=b. The token, when not
+// empty, is sent in the Authorization header.
+func pushInflux(ctx context.Context, endpoint, token string, results []Result, ts time.Time) error {
+	var body bytes.Buffer
+	if err := writeInflux(&body, results, ts); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influx push: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// formatMillis format d as a floating number of milliseconds.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
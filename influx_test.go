@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteInflux(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	results := []Result{
+		{Name: "go", Url: "https://go.dev", Status: 200, Latency: 1500 * time.Microsecond},
+		{Url: "https://a.com/x y,z=1", Err: errors.New(`dial "tcp": refused`)},
+		{Url: "https://xn--bcher-kva.example", UnicodeURL: "https://bücher.example", Status: 200, Latency: time.Millisecond},
+		{Url: "https://later.dev", Status: 204, Latency: time.Millisecond, Start: time.Unix(1700000042, 5)},
+	}
+
+	var b strings.Builder
+	if err := writeInflux(&b, results, ts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "healthcheck,name=go,url=https://go.dev up=true,status=200i,latency_ms=1.5 1700000000000000000\n" +
+		`healthcheck,url=https://a.com/x\ y\,z\=1 up=false,error="dial \"tcp\": refused",error_code="HC900" 1700000000000000000` + "\n" +
+		"healthcheck,url=https://xn--bcher-kva.example,url_unicode=https://bücher.example up=true,status=200i,latency_ms=1 1700000000000000000\n" +
+		"healthcheck,url=https://later.dev up=true,status=204i,latency_ms=1 1700000042000000005\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestPushInflux(t *testing.T) {
+	var gotBody, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotAuth = string(body), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	results := []Result{{Url: "https://go.dev", Status: 200}}
+	if err := pushInflux(context.Background(), srv.URL, "secret", results, time.Unix(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Token secret" {
+		t.Errorf("want Authorization Token secret; got %q", gotAuth)
+	}
+	if !strings.HasPrefix(gotBody, "healthcheck,url=https://go.dev up=true") {
+		t.Errorf("unexpected body %q", gotBody)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	if err := pushInflux(context.Background(), failing.URL, "", results, time.Now()); err == nil {
+		t.Error("want an error on a 401 response")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonResult is the JSON form of a Result: errors as strings and durations
+// in milliseconds.
+type jsonResult struct {
+	Time             *time.Time        `json:"time,omitempty"`
+	Start            *time.Time        `json:"start,omitempty"`
+	Name             string            `json:"name,omitempty"`
+	URL              string            `json:"url,omitempty"`
+	UnicodeURL       string            `json:"unicode_url,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Severity         string            `json:"severity,omitempty"`
+	State            string            `json:"state"`
+	Status           int               `json:"status,omitempty"`
+	LatencyMS        float64           `json:"latency_ms,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	ErrorCode        ErrorCode         `json:"error_code,omitempty"`
+	Members          []jsonResult      `json:"members,omitempty"`
+	Steps            []jsonStep        `json:"steps,omitempty"`
+	Stats            *jsonStats        `json:"stats,omitempty"`
+	ContentEncoding  string            `json:"content_encoding,omitempty"`
+	BytesTransferred int64             `json:"bytes_transferred,omitempty"`
+	BytesDecoded     int64             `json:"bytes_decoded,omitempty"`
+	BodyHash         string            `json:"body_hash,omitempty"`
+	TLSVersion       string            `json:"tls_version,omitempty"`
+	TLSCipher        string            `json:"tls_cipher,omitempty"`
+	CertExpiry       *time.Time        `json:"cert_expiry,omitempty"`
+	Banner           string            `json:"banner,omitempty"`
+	OffsetMS         float64           `json:"offset_ms,omitempty"`
+	Stratum          int               `json:"stratum,omitempty"`
+	RequestID        string            `json:"request_id,omitempty"`
+	Cache            map[string]string `json:"cache,omitempty"`
+	TTFBMS           float64           `json:"ttfb_ms,omitempty"`
+	TTLBMS           float64           `json:"ttlb_ms,omitempty"`
+	Geo              *Geo              `json:"geo,omitempty"`
+	Audit            []string          `json:"audit,omitempty"`
+	BodyPreview      string            `json:"body_preview,omitempty"`
+	Source           string            `json:"source,omitempty"`
+	Line             int               `json:"line,omitempty"`
+}
+
+type jsonStep struct {
+	Name      string    `json:"name,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMS float64   `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+}
+
+type jsonStats struct {
+	Samples     int     `json:"samples"`
+	SuccessRate float64 `json:"success_rate"`
+	MinMS       float64 `json:"min_ms"`
+	AvgMS       float64 `json:"avg_ms"`
+	P95MS       float64 `json:"p95_ms"`
+	MaxMS       float64 `json:"max_ms"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func newJSONResult(res Result) jsonResult {
+	j := jsonResult{
+		Name:             res.Name,
+		URL:              res.Url,
+		UnicodeURL:       res.UnicodeURL,
+		Tags:             res.Tags,
+		Severity:         res.Severity,
+		State:            state(res),
+		Status:           res.Status,
+		LatencyMS:        millis(res.Latency),
+		Error:            errorString(res.Err),
+		ErrorCode:        ErrorCodeOf(res.Err),
+		ContentEncoding:  res.ContentEncoding,
+		BytesTransferred: res.BytesTransferred,
+		BytesDecoded:     res.BytesDecoded,
+		BodyHash:         res.BodyHash,
+		TLSVersion:       res.TLSVersion,
+		TLSCipher:        res.TLSCipher,
+		Banner:           res.Banner,
+		OffsetMS:         millis(res.Offset),
+		Stratum:          res.Stratum,
+		RequestID:        res.RequestID,
+		Cache:            res.Cache,
+		TTFBMS:           millis(res.TTFB),
+		TTLBMS:           millis(res.TTLB),
+		Geo:              res.Geo,
+		Audit:            res.Audit,
+		BodyPreview:      res.BodyPreview,
+		Source:           res.Source,
+		Line:             res.Line,
+	}
+	if !res.Start.IsZero() {
+		j.Start = &res.Start
+	}
+	if !res.CertExpiry.IsZero() {
+		j.CertExpiry = &res.CertExpiry
+	}
+	for _, member := range res.Members {
+		j.Members = append(j.Members, newJSONResult(member))
+	}
+	for _, step := range res.Steps {
+		j.Steps = append(j.Steps, jsonStep{
+			Name:      step.Name,
+			Status:    step.Status,
+			LatencyMS: millis(step.Latency),
+			Error:     errorString(step.Err),
+			ErrorCode: ErrorCodeOf(step.Err),
+		})
+	}
+	if s := res.Stats; s != nil {
+		j.Stats = &jsonStats{
+			Samples:     s.Samples,
+			SuccessRate: s.SuccessRate(),
+			MinMS:       millis(s.Min),
+			AvgMS:       millis(s.Avg),
+			P95MS:       millis(s.P95),
+			MaxMS:       millis(s.Max),
+		}
+	}
+	return j
+}
+
+// MarshalJSON encode the result with its error as a string and durations in
+// milliseconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newJSONResult(r))
+}
+
+// MarshalJSON encode the result of the record with its time.
+func (r record) MarshalJSON() ([]byte, error) {
+	j := newJSONResult(r.Result)
+	j.Time = &r.Time
+	return json.Marshal(j)
+}
+
+// jsonReport is the output of a run with -format json.
+type jsonReport struct {
+	Time    time.Time   `json:"time"`
+	Results []Result    `json:"results"`
+	Summary jsonSummary `json:"summary"`
+}
+
+type jsonSummary struct {
+	tally
+	Excluded int               `json:"excluded,omitempty"`
+	Tags     map[string]*tally `json:"tags,omitempty"`
+	Latency  *jsonHistogram    `json:"latency,omitempty"`
+}
+
+type jsonHistogram struct {
+	Samples int          `json:"samples"`
+	MinMS   float64      `json:"min_ms"`
+	P50MS   float64      `json:"p50_ms"`
+	P90MS   float64      `json:"p90_ms"`
+	P99MS   float64      `json:"p99_ms"`
+	P999MS  float64      `json:"p999_ms"`
+	MaxMS   float64      `json:"max_ms"`
+	Buckets []jsonBucket `json:"buckets"`
+}
+
+type jsonBucket struct {
+	FromMS float64 `json:"from_ms"`
+	ToMS   float64 `json:"to_ms"`
+	Count  int     `json:"count"`
+}
+
+func newJSONSummary(s summary) jsonSummary {
+	j := jsonSummary{tally: s.Total, Excluded: s.Excluded, Tags: s.Tags}
+	if h := &s.Latency; h.total > 0 {
+		j.Latency = &jsonHistogram{
+			Samples: h.total,
+			MinMS:   millis(h.min),
+			P50MS:   millis(h.percentile(50)),
+			P90MS:   millis(h.percentile(90)),
+			P99MS:   millis(h.percentile(99)),
+			P999MS:  millis(h.percentile(99.9)),
+			MaxMS:   millis(h.max),
+		}
+		for _, r := range h.ranges() {
+			j.Latency.Buckets = append(j.Latency.Buckets, jsonBucket{FromMS: millis(r.From), ToMS: millis(r.To), Count: r.Count})
+		}
+	}
+	return j
+}
+
+// writeJSONReport write the results of a run at t and their summary s to
+// w.
+func writeJSONReport(w io.Writer, results []Result, s summary, t time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonReport{Time: t, Results: results, Summary: newJSONSummary(s)})
+}
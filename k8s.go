@@ -0,0 +1,234 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Files of the service account of pods, used to reach the Kubernetes API
+// from within a cluster.
+const k8sServiceAccount = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Annotations of Kubernetes services and ingresses changing their checks.
+const (
+	k8sPathAnnotation = "healthcheck/path" // path requested, "/" by default
+	k8sPortAnnotation = "healthcheck/port" // port of services checked, the first one by default
+)
+
+// k8sOptions select the Kubernetes services and ingresses to check.
+type k8sOptions struct {
+	api       string
+	namespace string
+	selector  string
+}
+
+func (o *k8sOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.api, "k8s-api", "", "Kubernetes API URL, e.g. http://127.0.0.1:8001 for kubectl proxy; the in-cluster API when empty")
+	fs.StringVar(&o.namespace, "k8s-namespace", "", "namespace of the discovered services and ingresses, all when empty")
+	fs.StringVar(&o.selector, "k8s-selector", "", "label selector of the discovered services and ingresses, e.g. app=web,tier!=db")
+}
+
+// k8sClient send requests to the Kubernetes API.
+type k8sClient struct {
+	api       string
+	client    *http.Client
+	tokenFile string
+}
+
+// k8sObjectMeta is the metadata of Kubernetes objects.
+type k8sObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type k8sServiceList struct {
+	Items []struct {
+		Metadata k8sObjectMeta `json:"metadata"`
+		Spec     struct {
+			Ports []struct {
+				Name string `json:"name"`
+				Port int    `json:"port"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type k8sIngressList struct {
+	Items []struct {
+		Metadata k8sObjectMeta `json:"metadata"`
+		Spec     struct {
+			TLS []struct {
+				Hosts []string `json:"hosts"`
+			} `json:"tls"`
+			Rules []struct {
+				Host string `json:"host"`
+				HTTP *struct {
+					Paths []struct {
+						Path string `json:"path"`
+					} `json:"paths"`
+				} `json:"http"`
+			} `json:"rules"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// discoverer return a function listing the services and ingresses selected
+// by o. Outside of a cluster, the API is set with -k8s-api.
+func (o *k8sOptions) discoverer() (discoverFunc, error) {
+	kc := &k8sClient{api: o.api, client: http.DefaultClient}
+	if kc.api == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("k8s discovery: not running in a cluster, set -k8s-api")
+		}
+		ca, err := os.ReadFile(path.Join(k8sServiceAccount, "ca.crt"))
+		if err != nil {
+			return nil, fmt.Errorf("k8s discovery: %w", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		kc.api = "https://" + net.JoinHostPort(host, port)
+		kc.client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		kc.tokenFile = path.Join(k8sServiceAccount, "token")
+	}
+	return func(ctx context.Context) ([]Service, error) {
+		return kc.discover(ctx, o.namespace, o.selector)
+	}, nil
+}
+
+// discover list the services and ingresses of namespace, all when empty,
+// matching selector.
+func (kc *k8sClient) discover(ctx context.Context, namespace, selector string) ([]Service, error) {
+	var services k8sServiceList
+	if err := kc.list(ctx, "/api/v1", namespace, "services", selector, &services); err != nil {
+		return nil, err
+	}
+	var ingresses k8sIngressList
+	if err := kc.list(ctx, "/apis/networking.k8s.io/v1", namespace, "ingresses", selector, &ingresses); err != nil {
+		return nil, err
+	}
+
+	var discovered []Service
+	for _, item := range services.Items {
+		meta := item.Metadata
+		if len(item.Spec.Ports) == 0 {
+			continue
+		}
+		port := item.Spec.Ports[0]
+		if want := meta.Annotations[k8sPortAnnotation]; want != "" {
+			for _, p := range item.Spec.Ports {
+				if p.Name == want || strconv.Itoa(p.Port) == want {
+					port = p
+				}
+			}
+		}
+		scheme := "http"
+		if port.Name == "https" || port.Port == 443 {
+			scheme = "https"
+		}
+		host := net.JoinHostPort(meta.Name+"."+meta.Namespace+".svc", strconv.Itoa(port.Port))
+		discovered = append(discovered, Service{
+			Name: "k8s/service/" + meta.Namespace + "/" + meta.Name,
+			URL:  scheme + "://" + host + k8sPath(meta, "/"),
+			Tags: []string{"k8s"},
+		})
+	}
+	for _, item := range ingresses.Items {
+		meta := item.Metadata
+		tlsHosts := make(map[string]bool)
+		for _, t := range item.Spec.TLS {
+			for _, h := range t.Hosts {
+				tlsHosts[h] = true
+			}
+		}
+		for _, rule := range item.Spec.Rules {
+			// Rules without host or with a wildcard one have no url.
+			if rule.Host == "" || strings.HasPrefix(rule.Host, "*") {
+				continue
+			}
+			scheme := "http"
+			if tlsHosts[rule.Host] {
+				scheme = "https"
+			}
+			paths := []string{"/"}
+			if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+				paths = paths[:0]
+				for _, p := range rule.HTTP.Paths {
+					paths = append(paths, cmp.Or(p.Path, "/"))
+				}
+			}
+			for _, p := range paths {
+				discovered = append(discovered, Service{
+					Name: "k8s/ingress/" + meta.Namespace + "/" + meta.Name + "/" + rule.Host + p,
+					URL:  scheme + "://" + rule.Host + k8sPath(meta, p),
+					Tags: []string{"k8s"},
+				})
+			}
+		}
+	}
+	return discovered, nil
+}
+
+// k8sPath return the path to check of an object, the healthcheck/path
+// annotation taking precedence over def.
+func k8sPath(meta k8sObjectMeta, def string) string {
+	p := cmp.Or(meta.Annotations[k8sPathAnnotation], def)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
+
+// list get the objects of resource in namespace matching selector and
+// decode them into v.
+func (kc *k8sClient) list(ctx context.Context, group, namespace, resource, selector string, v any) error {
+	u := strings.TrimSuffix(kc.api, "/") + group
+	if namespace != "" {
+		u += "/namespaces/" + url.PathEscape(namespace)
+	}
+	u += "/" + resource
+	if selector != "" {
+		u += "?labelSelector=" + url.QueryEscape(selector)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if kc.tokenFile != "" {
+		// The token is read on every request as it is rotated.
+		token, err := os.ReadFile(kc.tokenFile)
+		if err != nil {
+			return fmt.Errorf("k8s discovery: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := kc.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("k8s discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("k8s discovery: %s %s: %s", resource, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("k8s discovery: %s: %w", resource, err)
+	}
+	return nil
+}
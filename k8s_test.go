@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestK8sDiscovery(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("labelSelector"); got != "team=payments" {
+			t.Errorf("want selector team=payments; got %q", got)
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/prod/services":
+			w.Write([]byte(`{"items": [
+				{"metadata": {"name": "api", "namespace": "prod"},
+				 "spec": {"ports": [{"name": "https", "port": 8443}]}},
+				{"metadata": {"name": "web", "namespace": "prod", "annotations": {"healthcheck/path": "healthz", "healthcheck/port": "metrics"}},
+				 "spec": {"ports": [{"name": "http", "port": 80}, {"name": "metrics", "port": 9090}]}},
+				{"metadata": {"name": "headless", "namespace": "prod"}, "spec": {}}
+			]}`))
+		case "/apis/networking.k8s.io/v1/namespaces/prod/ingresses":
+			w.Write([]byte(`{"items": [
+				{"metadata": {"name": "shop", "namespace": "prod"},
+				 "spec": {"tls": [{"hosts": ["shop.a.com"]}], "rules": [
+					{"host": "shop.a.com", "http": {"paths": [{"path": "/"}, {"path": "/api"}]}},
+					{"host": "old.a.com"},
+					{"host": "*.a.com"},
+					{"http": {"paths": [{"path": "/"}]}}
+				]}}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	opts := k8sOptions{api: api.URL, namespace: "prod", selector: "team=payments"}
+	discover, err := opts.discoverer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Service{
+		{Name: "k8s/service/prod/api", URL: "https://api.prod.svc:8443/"},
+		{Name: "k8s/service/prod/web", URL: "http://web.prod.svc:9090/healthz"},
+		{Name: "k8s/ingress/prod/shop/shop.a.com/", URL: "https://shop.a.com/"},
+		{Name: "k8s/ingress/prod/shop/shop.a.com/api", URL: "https://shop.a.com/api"},
+		{Name: "k8s/ingress/prod/shop/old.a.com/", URL: "http://old.a.com/"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d services; got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].URL != want[i].URL || got[i].Tags[0] != "k8s" {
+			t.Errorf("want %s %s; got %s %s %v", want[i].Name, want[i].URL, got[i].Name, got[i].URL, got[i].Tags)
+		}
+	}
+
+	opts.namespace = "missing"
+	if discover, _ := opts.discoverer(); discover != nil {
+		if _, err := discover(context.Background()); err == nil {
+			t.Error("want an error when the API fails")
+		}
+	}
+}
+
+func TestK8sDiscoveryOutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := (&k8sOptions{}).discoverer(); err == nil {
+		t.Error("want an error without API outside a cluster")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+)
+
+// Kafka api keys and versions used by checkKafka.
+const (
+	kafkaMetadata        = 3
+	kafkaMetadataVersion = 4 // the first with allow_auto_topic_creation
+	kafkaAPIVersions     = 18
+)
+
+// kafkaUnknownTopic is the error code of metadata of missing topics.
+const kafkaUnknownTopic = 3
+
+// KafkaError report an error code in a response of a Kafka broker.
+type KafkaError struct {
+	Topic string
+	Code  int16
+}
+
+func (e *KafkaError) Error() string {
+	if e.Code == kafkaUnknownTopic {
+		return fmt.Sprintf("kafka: unknown topic %q", e.Topic)
+	}
+	if e.Topic != "" {
+		return fmt.Sprintf("kafka: topic %q: error code %d", e.Topic, e.Code)
+	}
+	return fmt.Sprintf("kafka: error code %d", e.Code)
+}
+
+// checkKafka check the Kafka broker of a kafka://host:port url with the
+// ApiVersions handshake and, with a topic= parameter, that the metadata of
+// the topic exists, without creating it. TLS and SASL are not supported.
+func checkKafka(ctx context.Context, c *checker, u *url.URL, _ *Result) error {
+	conn, err := c.dial(ctx, "tcp", hostPort(u, "9092"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	k := &kafkaConn{conn: conn}
+
+	resp, err := k.roundTrip(kafkaAPIVersions, 0, nil)
+	if err != nil {
+		return err
+	}
+	var code int16
+	var apis []struct{ Key, Min, Max int16 }
+	if err := kafkaRead(resp, &code); err != nil {
+		return err
+	}
+	if code != 0 {
+		return &KafkaError{Code: code}
+	}
+	if err := kafkaRead(resp, &apis); err != nil {
+		return err
+	}
+
+	topic := u.Query().Get("topic")
+	if topic == "" {
+		return nil
+	}
+	supported := false
+	for _, api := range apis {
+		supported = supported || (api.Key == kafkaMetadata && api.Min <= kafkaMetadataVersion && kafkaMetadataVersion <= api.Max)
+	}
+	if !supported {
+		return fmt.Errorf("kafka: metadata v%d unsupported by the broker", kafkaMetadataVersion)
+	}
+	var req bytes.Buffer
+	binary.Write(&req, binary.BigEndian, int32(1))
+	kafkaWriteString(&req, topic)
+	req.WriteByte(0) // allow_auto_topic_creation
+	if resp, err = k.roundTrip(kafkaMetadata, kafkaMetadataVersion, req.Bytes()); err != nil {
+		return err
+	}
+	return kafkaTopicError(resp, topic)
+}
+
+// kafkaConn send requests to a Kafka broker.
+type kafkaConn struct {
+	conn          net.Conn
+	correlationID int32
+}
+
+// roundTrip send the request body of api at version and return the body of
+// the response.
+func (k *kafkaConn) roundTrip(api, version int16, body []byte) (*bytes.Reader, error) {
+	k.correlationID++
+	var req bytes.Buffer
+	binary.Write(&req, binary.BigEndian, []int16{api, version})
+	binary.Write(&req, binary.BigEndian, k.correlationID)
+	kafkaWriteString(&req, DefaultUserAgent)
+	req.Write(body)
+	size := binary.BigEndian.AppendUint32(nil, uint32(req.Len()))
+	if _, err := k.conn.Write(append(size, req.Bytes()...)); err != nil {
+		return nil, err
+	}
+
+	var n int32
+	if err := binary.Read(k.conn, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	if n < 4 || n > maxProbeBody {
+		return nil, fmt.Errorf("kafka: invalid response size %d", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(k.conn, resp); err != nil {
+		return nil, err
+	}
+	if id := int32(binary.BigEndian.Uint32(resp)); id != k.correlationID {
+		return nil, fmt.Errorf("kafka: response to request %d, want %d", id, k.correlationID)
+	}
+	return bytes.NewReader(resp[4:]), nil
+}
+
+// kafkaTopicError return the error of topic in a metadata v4 response.
+func kafkaTopicError(r *bytes.Reader, topic string) error {
+	var throttle, brokers int32
+	if err := kafkaRead(r, &throttle, &brokers); err != nil {
+		return err
+	}
+	for i := int32(0); i < brokers; i++ {
+		var node, port int32
+		var host, rack string
+		if err := kafkaRead(r, &node, &host, &port, &rack); err != nil {
+			return err
+		}
+	}
+	var clusterID string
+	var controller, topics int32
+	if err := kafkaRead(r, &clusterID, &controller, &topics); err != nil {
+		return err
+	}
+	for i := int32(0); i < topics; i++ {
+		var code int16
+		var name string
+		if err := kafkaRead(r, &code, &name); err != nil {
+			return err
+		}
+		if name != topic {
+			continue
+		}
+		if code != 0 {
+			return &KafkaError{Topic: topic, Code: code}
+		}
+		return nil
+	}
+	return &KafkaError{Topic: topic, Code: kafkaUnknownTopic}
+}
+
+// kafkaRead decode values from r: fixed size integers, strings and
+// nullable strings, and slices of structs of fixed size integers.
+func kafkaRead(r *bytes.Reader, values ...any) error {
+	for _, v := range values {
+		var err error
+		switch v := v.(type) {
+		case *string:
+			var n int16
+			if err = binary.Read(r, binary.BigEndian, &n); err != nil || n < 0 {
+				break
+			}
+			b := make([]byte, n)
+			_, err = io.ReadFull(r, b)
+			*v = string(b)
+		case *[]struct{ Key, Min, Max int16 }:
+			var n int32
+			if err = binary.Read(r, binary.BigEndian, &n); err != nil || n < 0 {
+				break
+			}
+			if int(n)*6 > r.Len() {
+				return errors.New("kafka: truncated response")
+			}
+			*v = make([]struct{ Key, Min, Max int16 }, n)
+			err = binary.Read(r, binary.BigEndian, *v)
+		default:
+			err = binary.Read(r, binary.BigEndian, v)
+		}
+		if err != nil {
+			return fmt.Errorf("kafka: invalid response: %w", err)
+		}
+	}
+	return nil
+}
+
+// kafkaWriteString write s to w as a Kafka string.
+func kafkaWriteString(w *bytes.Buffer, s string) {
+	binary.Write(w, binary.BigEndian, int16(len(s)))
+	w.WriteString(s)
+}
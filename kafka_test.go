@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeKafka serve on l the ApiVersions and Metadata v4 requests of Kafka
+// clients, with topics as the only topics.
+func fakeKafka(l net.Listener, topics ...string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var n int32
+				if binary.Read(conn, binary.BigEndian, &n) != nil {
+					return
+				}
+				req := make([]byte, n)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				r := bytes.NewReader(req)
+				var api, version int16
+				var correlationID int32
+				var clientID string
+				kafkaRead(r, &api, &version, &correlationID, &clientID)
+
+				var resp bytes.Buffer
+				write := func(v ...any) {
+					for _, v := range v {
+						if s, ok := v.(string); ok {
+							kafkaWriteString(&resp, s)
+							continue
+						}
+						binary.Write(&resp, binary.BigEndian, v)
+					}
+				}
+				write(correlationID)
+				switch api {
+				case kafkaAPIVersions:
+					write(int16(0), int32(2), int16(kafkaMetadata), int16(0), int16(9), int16(kafkaAPIVersions), int16(0), int16(3))
+				case kafkaMetadata:
+					var count int32
+					var topic string
+					kafkaRead(r, &count, &topic)
+					write(int32(0), int32(1), int32(1), "localhost", int32(9092), int16(-1), int16(-1), int32(1), int32(1))
+					code := int16(kafkaUnknownTopic)
+					for _, t := range topics {
+						if t == topic {
+							code = 0
+						}
+					}
+					write(code, topic, false, int32(0))
+				}
+				conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(resp.Len())), resp.Bytes()...))
+			}
+		}()
+	}
+}
+
+func TestCheckKafka(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeKafka(l, "orders")
+
+	c := newChecker(WithRetries(0))
+	for _, query := range []string{"", "?topic=orders"} {
+		if res := c.check(context.Background(), Service{URL: "kafka://" + l.Addr().String() + query}); !res.Up() {
+			t.Errorf("%q: want up; got %v", query, res.Err)
+		}
+	}
+	res := c.check(context.Background(), Service{URL: "kafka://" + l.Addr().String() + "?topic=payments"})
+	var kerr *KafkaError
+	if !errors.As(res.Err, &kerr) || kerr.Code != kafkaUnknownTopic || kerr.Error() != `kafka: unknown topic "payments"` {
+		t.Errorf("missing topic: want a KafkaError; got %v", res.Err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// maxLDAPMessage is the size beyond which a message of an LDAP server is
+// rejected, bind responses being much smaller.
+const maxLDAPMessage = 1 << 16
+
+// LDAPError report a bind rejected by an LDAP server with a result code,
+// such as 49 for invalid credentials.
+type LDAPError struct {
+	Code    int
+	Message string
+}
+
+func (e *LDAPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap bind: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap bind: result code %d: %s", e.Code, e.Message)
+}
+
+type ldapBindRequest struct {
+	Version  int
+	Name     []byte
+	Password []byte `asn1:"tag:0"`
+}
+
+type ldapBindResponse struct {
+	Code      asn1.Enumerated
+	MatchedDN []byte
+	Message   []byte
+}
+
+// checkLDAP check the directory server of an ldap://[dn:password@]host:port
+// url, ldaps:// for TLS: it binds as the DN of the url with its password,
+// anonymously without them, then unbinds. The latency of the result is the
+// round trip of the bind.
+func checkLDAP(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	port := "389"
+	if u.Scheme == "ldaps" {
+		port = "636"
+	}
+	conn, err := c.dial(ctx, "tcp", hostPort(u, port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if u.Scheme == "ldaps" {
+		tlsConn := tls.Client(conn, c.tlsConfig(u.Hostname()))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		state := tlsConn.ConnectionState()
+		recordTLS(&state, result)
+		conn = tlsConn
+	}
+
+	bind := ldapBindRequest{Version: 3, Name: []byte(u.User.Username())}
+	if password, ok := u.User.Password(); ok {
+		bind.Password = []byte(password)
+	}
+	req, err := asn1.Marshal(struct {
+		ID   int
+		Bind ldapBindRequest `asn1:"application,tag:0"`
+	}{1, bind})
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	b, err := ldapRead(bufio.NewReader(conn))
+	if err != nil {
+		return fmt.Errorf("ldap bind: %w", err)
+	}
+	result.Latency = time.Since(start)
+	var resp struct {
+		ID   int
+		Bind ldapBindResponse `asn1:"application,tag:1"`
+	}
+	if _, err := asn1.Unmarshal(b, &resp); err != nil {
+		return fmt.Errorf("ldap bind: %w", err)
+	}
+	if resp.Bind.Code != 0 {
+		return &LDAPError{Code: int(resp.Bind.Code), Message: string(resp.Bind.Message)}
+	}
+	unbind, _ := asn1.Marshal(struct {
+		ID     int
+		Unbind asn1.RawValue
+	}{2, asn1.RawValue{Class: asn1.ClassApplication, Tag: 2, Bytes: []byte{}}})
+	conn.Write(unbind)
+	return nil
+}
+
+// ldapRead read an LDAP message, a BER element, from r.
+func ldapRead(r *bufio.Reader) ([]byte, error) {
+	head := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	size := int(head[1])
+	if size&0x80 != 0 {
+		n := size & 0x7f
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("invalid length of %d bytes", n)
+		}
+		head = head[:2+n]
+		if _, err := io.ReadFull(r, head[2:]); err != nil {
+			return nil, err
+		}
+		size = 0
+		for _, b := range head[2:] {
+			size = size<<8 | int(b)
+		}
+	}
+	if size > maxLDAPMessage {
+		return nil, fmt.Errorf("message of %d bytes", size)
+	}
+	b := make([]byte, len(head)+size)
+	copy(b, head)
+	_, err := io.ReadFull(r, b[len(head):])
+	return b, err
+}
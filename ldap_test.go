@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/asn1"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeLDAP answer the binds sent to l as an LDAP server accepting
+// anonymous binds and those of dn with password.
+func fakeLDAP(l net.Listener, dn, password string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			b, err := ldapRead(bufio.NewReader(conn))
+			if err != nil {
+				return
+			}
+			var req struct {
+				ID   int
+				Bind ldapBindRequest `asn1:"application,tag:0"`
+			}
+			if _, err := asn1.Unmarshal(b, &req); err != nil {
+				return
+			}
+			resp := ldapBindResponse{MatchedDN: []byte{}, Message: []byte{}}
+			if name := string(req.Bind.Name); name != "" && (name != dn || string(req.Bind.Password) != password) {
+				resp.Code, resp.Message = 49, []byte("invalid credentials")
+			}
+			b, _ = asn1.Marshal(struct {
+				ID   int
+				Bind ldapBindResponse `asn1:"application,tag:1"`
+			}{req.ID, resp})
+			conn.Write(b)
+		}()
+	}
+}
+
+func TestCheckLDAP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeLDAP(l, "cn=monitor,dc=a,dc=com", "s3cret")
+
+	c := newChecker(WithRetries(0))
+	for _, userinfo := range []string{"", "cn=monitor,dc=a,dc=com:s3cret@"} {
+		svc, err := ParseService("ldap://" + userinfo + l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := c.check(context.Background(), svc); !res.Up() || res.Latency <= 0 {
+			t.Errorf("%q: want up; got %v, latency %s", userinfo, res.Err, res.Latency)
+		}
+	}
+	var lerr *LDAPError
+	res := c.check(context.Background(), Service{URL: "ldap://cn=monitor,dc=a,dc=com:wrong@" + l.Addr().String()})
+	if !errors.As(res.Err, &lerr) || lerr.Code != 49 || lerr.Message != "invalid credentials" {
+		t.Errorf("wrong password: want an LDAPError; got %v", res.Err)
+	}
+}
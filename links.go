@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// linkAttrs map the elements whose links are checked to the attribute
+// holding the url.
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"img":    "src",
+	"script": "src",
+}
+
+// runLinks implement the links subcommand, checking the links of a page
+// and reporting the broken ones, and return the exit code: exitFailed when
+// a link is broken.
+func runLinks(args []string) int {
+	fs := flag.NewFlagSet("links", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck links [flags] url")
+		fs.PrintDefaults()
+	}
+	timeout := fs.Duration("timeout", DefaultTimeout, "time allowed for each request")
+	retries := fs.Int("retries", DefaultRetries, "number of retries of a failed check")
+	workers := fs.Int("workers", DefaultWorkers, "number of concurrent checks")
+	userAgent := fs.String("user-agent", DefaultUserAgent, "User-Agent header sent with requests")
+	internal := fs.Bool("internal", false, "only check links to the host of the page")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	c := newChecker(WithTimeout(*timeout), WithRetries(*retries), WithWorkers(*workers), WithUserAgent(*userAgent))
+	links, err := c.pageLinks(context.Background(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *internal {
+		links = internalLinks(fs.Arg(0), links)
+	}
+	services := make([]Service, len(links))
+	for i, link := range links {
+		services[i] = Service{URL: link}
+	}
+	results := c.healthCheck(context.Background(), services, nil)
+	if writeLinks(os.Stdout, results) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// pageLinks fetch the page at pageURL and return the absolute urls of its
+// links, see extractLinks.
+func (c *checker) pageLinks(ctx context.Context, pageURL string) ([]string, error) {
+	svc := Service{URL: pageURL}
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req, svc)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !expectedStatus(resp.StatusCode, nil) {
+		return nil, fmt.Errorf("%s: %w", pageURL, &StatusError{Status: resp.StatusCode})
+	}
+	// Links are relative to the page after redirects.
+	return extractLinks(io.LimitReader(resp.Body, maxProbeBody), resp.Request.URL)
+}
+
+// extractLinks return the absolute urls of the anchors, images and scripts
+// of the HTML document r, resolved against its <base> or page, without
+// fragments, duplicates and urls which are not http(s), such as mailto:.
+func extractLinks(r io.Reader, page *url.URL) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	base := page
+	seen := make(map[string]bool)
+	var links []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, a := range n.Attr {
+				switch {
+				case n.Data == "base" && a.Key == "href" && base == page:
+					if u, err := page.Parse(strings.TrimSpace(a.Val)); err == nil {
+						base = u
+					}
+				case linkAttrs[n.Data] == a.Key:
+					u, err := base.Parse(strings.TrimSpace(a.Val))
+					if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+						continue
+					}
+					u.Fragment, u.RawFragment = "", ""
+					if link := u.String(); !seen[link] {
+						seen[link] = true
+						links = append(links, link)
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return links, nil
+}
+
+// internalLinks return the links to the host of pageURL.
+func internalLinks(pageURL string, links []string) []string {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return links
+	}
+	var internal []string
+	for _, link := range links {
+		if u, err := url.Parse(link); err == nil && strings.EqualFold(u.Host, page.Host) {
+			internal = append(internal, link)
+		}
+	}
+	return internal
+}
+
+// writeLinks print the broken links of results and the number of links
+// checked, and return the number of broken ones.
+func writeLinks(w io.Writer, results []Result) int {
+	broken := 0
+	for _, res := range results {
+		if res.Up() {
+			continue
+		}
+		broken++
+		writeTextResult(w, "", res)
+	}
+	fmt.Fprintf(w, "%d links checked, %d broken\n", len(results), broken)
+	return broken
+}
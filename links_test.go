@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	page, _ := url.Parse("https://a.com/docs/index.html")
+	doc := `<html><head>
+<script src="/app.js"></script>
+<link rel="stylesheet" href="/style.css">
+</head><body>
+<a href="intro.html">Intro</a>
+<a href="intro.html#setup">Setup</a>
+<a href="https://b.com/">B</a>
+<a href="mailto:a@a.com">Mail</a>
+<a href="#top">Top</a>
+<img src="//cdn.a.com/logo.png">
+</body></html>`
+	got, err := extractLinks(strings.NewReader(doc), page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://a.com/app.js",
+		"https://a.com/docs/intro.html",
+		"https://b.com/",
+		"https://a.com/docs/index.html",
+		"https://cdn.a.com/logo.png",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	got, _ = extractLinks(strings.NewReader(`<base href="https://c.com/v2/"><a href="api">API</a>`), page)
+	if len(got) != 1 || got[0] != "https://c.com/v2/api" {
+		t.Errorf("base: got %q", got)
+	}
+
+	if got := internalLinks(page.String(), want); len(got) != 3 {
+		t.Errorf("internal: got %q", got)
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/ok">ok</a> <a href="/missing">missing</a> <img src="/ok">`)
+	})
+	mux.HandleFunc("/ok", func(http.ResponseWriter, *http.Request) {})
+
+	c := newChecker(WithRetries(0))
+	links, err := c.pageLinks(context.Background(), srv.URL+"/")
+	if err != nil || len(links) != 2 {
+		t.Fatalf("got %q, %v", links, err)
+	}
+	services := []Service{{URL: links[0]}, {URL: links[1]}}
+	var buf bytes.Buffer
+	if broken := writeLinks(&buf, c.healthCheck(context.Background(), services, nil)); broken != 1 {
+		t.Errorf("got %d broken links; want 1", broken)
+	}
+	if out := buf.String(); !strings.Contains(out, "/missing; Start: ") || !strings.Contains(out, "; Status: 404") || !strings.HasSuffix(out, "2 links checked, 1 broken\n") {
+		t.Errorf("got:\n%s", out)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// loadBuckets are the upper bounds of the latency histogram of a load test.
+var loadBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// loadReport gather the outcome of the requests sent to a service.
+type loadReport struct {
+	URL       string
+	Requests  int
+	Errors    int
+	Duration  time.Duration
+	Latencies []time.Duration // sorted once the test is over
+}
+
+// ErrorRate is the ratio of failed requests, between 0 and 1.
+func (r *loadReport) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Throughput is the number of completed requests per second.
+func (r *loadReport) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Duration.Seconds()
+}
+
+// Histogram count latencies per bucket of loadBuckets, the last count being
+// for latencies above every bucket.
+func (r *loadReport) Histogram() []int {
+	counts := make([]int, len(loadBuckets)+1)
+	for _, l := range r.Latencies {
+		i, _ := slices.BinarySearch(loadBuckets, l)
+		counts[i]++
+	}
+	return counts
+}
+
+// runLoad implement the load subcommand and return the exit code.
+func runLoad(args []string) int {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck load [flags] services.txt")
+		fs.PrintDefaults()
+	}
+	requests := fs.Int("n", 100, "number of requests sent to each url")
+	rate := fs.Float64("rate", 10, "requests per second sent to each url")
+	workers := fs.Int("workers", DefaultWorkers, "number of concurrent requests")
+	timeout := fs.Duration("timeout", DefaultTimeout, "time allowed for each request")
+	userAgent := fs.String("user-agent", DefaultUserAgent, "User-Agent header sent with requests")
+	varsFile := fs.String("vars-file", "", "YAML or JSON file of the variables the lines of the services file containing {{ are rendered against, a service per combination of their values")
+	blackbox := fs.String("blackbox-config", "", "blackbox_exporter configuration file whose http modules services may use with module=")
+	var vault vaultOptions
+	vault.register(fs)
+	var tags []string
+	fs.Func("tags", "comma separated list of tags, only services with one of them are tested", func(s string) error {
+		tags = append(tags, strings.Split(s, ",")...)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "missing file argument")
+		return exitError
+	}
+	if *requests < 1 || *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "n and rate must be positive")
+		return exitError
+	}
+
+	all, err := readServices(fs.Arg(0), DefaultMaxLineLength, *varsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	all = filterByTags(all, tags)
+	if err := prepareServices(context.Background(), all, *blackbox, vault.client()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	var services []Service
+	for _, svc := range all {
+		if svc.URL == "" || strings.HasPrefix(svc.URL, srvScheme+"://") || protocolOf(svc.URL) != nil {
+			fmt.Fprintf(os.Stderr, "%s: only single http and https url services can be load tested\n", svc.key())
+			continue
+		}
+		services = append(services, svc)
+	}
+
+	c := newChecker(WithWorkers(*workers), WithTimeout(*timeout), WithUserAgent(*userAgent))
+	reports := c.load(context.Background(), services, *requests, *rate)
+	writeLoadReports(os.Stdout, reports)
+
+	for _, r := range reports {
+		if r.Errors > 0 {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// load send n requests to each service at rate requests per second, using
+// the worker pool. When the workers cannot keep up, requests are delayed and
+// the measured throughput falls below rate.
+func (c *checker) load(ctx context.Context, services []Service, n int, rate float64) []*loadReport {
+	reports := make([]*loadReport, len(services))
+	for i, svc := range services {
+		reports[i] = &loadReport{URL: svc.URL, Latencies: make([]time.Duration, 0, n)}
+	}
+
+	jobs := make(chan job)
+	var producers sync.WaitGroup
+	producers.Add(len(services))
+	for i, svc := range services {
+		go func(i int, svc Service) {
+			defer producers.Done()
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+			defer ticker.Stop()
+			for sent := 0; sent < n; sent++ {
+				if sent > 0 {
+					<-ticker.C
+				}
+				jobs <- job{i: i, svc: svc}
+			}
+		}(i, svc)
+	}
+	go func() {
+		producers.Wait()
+		close(jobs)
+	}()
+
+	start := time.Now()
+	var mu sync.Mutex
+	check := func(svc Service) Result { return c.attempt(ctx, svc) }
+	c.pool(jobs, check, func(j job, res Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		r := reports[j.i]
+		r.Requests++
+		r.Duration = time.Since(start)
+		if !res.Up() {
+			r.Errors++
+		}
+		if res.Status != 0 {
+			r.Latencies = append(r.Latencies, res.Latency)
+		}
+	})
+
+	for _, r := range reports {
+		slices.Sort(r.Latencies)
+	}
+	return reports
+}
+
+// writeLoadReports print the throughput, error rate, percentiles and latency
+// histogram of each service.
+func writeLoadReports(w io.Writer, reports []*loadReport) {
+	const barWidth = 40
+	for _, r := range reports {
+		fmt.Fprintf(w, "Url: %s; Requests: %d; Errors: %d (%.1f%%); Throughput: %.1f req/s\n",
+			r.URL, r.Requests, r.Errors, 100*r.ErrorRate(), r.Throughput())
+		if len(r.Latencies) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  p50/p95/p99: %s/%s/%s\n",
+			percentile(r.Latencies, 50).Round(time.Millisecond),
+			percentile(r.Latencies, 95).Round(time.Millisecond),
+			percentile(r.Latencies, 99).Round(time.Millisecond))
+
+		counts := r.Histogram()
+		for i, count := range counts {
+			label := "> " + loadBuckets[len(loadBuckets)-1].String()
+			if i < len(loadBuckets) {
+				label = "<= " + loadBuckets[i].String()
+			}
+			bar := strings.Repeat("#", count*barWidth/len(r.Latencies))
+			line := fmt.Sprintf("  %-8s %6d %s", label, count, bar)
+			fmt.Fprintln(w, strings.TrimRight(line, " "))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && calls.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	services := []Service{{URL: srv.URL}, {URL: srv.URL + "/flaky"}}
+	c := newChecker(WithWorkers(4))
+
+	start := time.Now()
+	reports := c.load(context.Background(), services, 10, 200)
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("want requests spread at the given rate; took %s", elapsed)
+	}
+
+	if reports[0].Requests != 10 || reports[0].Errors != 0 || len(reports[0].Latencies) != 10 {
+		t.Errorf("unexpected report %+v", reports[0])
+	}
+	if reports[1].Errors != 5 || reports[1].ErrorRate() != 0.5 {
+		t.Errorf("want half of the requests failed; got %d", reports[1].Errors)
+	}
+	if reports[0].Throughput() <= 0 {
+		t.Error("want a positive throughput")
+	}
+}
+
+func TestWriteLoadReports(t *testing.T) {
+	r := &loadReport{
+		URL:       "https://a.com",
+		Requests:  4,
+		Errors:    1,
+		Duration:  2 * time.Second,
+		Latencies: []time.Duration{3 * time.Millisecond, 8 * time.Millisecond, 9 * time.Millisecond, 6 * time.Second},
+	}
+	if got := r.Histogram(); got[0] != 1 || got[1] != 2 || got[len(got)-1] != 1 {
+		t.Errorf("unexpected histogram %v", got)
+	}
+
+	var b strings.Builder
+	writeLoadReports(&b, []*loadReport{r})
+	out := b.String()
+	for _, want := range []string{
+		"Url: https://a.com; Requests: 4; Errors: 1 (25.0%); Throughput: 2.0 req/s\n",
+		"  p50/p95/p99: 8ms/6s/6s\n",
+		"  <= 10ms       2 ####################\n",
+		"  <= 25ms       0\n",
+		"  > 5s          1 ##########\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunLoadSecrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"data": {"token": "t0k3n"}, "metadata": {"version": 1}}}`))
+	}))
+	defer vault.Close()
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "services.txt")
+	line := srv.URL + ` header="Authorization: Bearer ${vault:secret/data/api#token}"`
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runLoad([]string{"-n", "1", path}); code != exitError {
+		t.Errorf("without -vault-addr: want exit code %d; got %d", exitError, code)
+	}
+	if code := runLoad([]string{"-n", "1", "-vault-addr", vault.URL, "-vault-token", "s.root", path}); code != exitOK {
+		t.Fatalf("want exit code %d; got %d", exitOK, code)
+	}
+	if got.Load() != "Bearer t0k3n" {
+		t.Errorf("want the secret sent; got %q", got.Load())
+	}
+}
+
+func TestRunLoadModules(t *testing.T) {
+	var method atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method.Store(r.Method)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	path, config := filepath.Join(dir, "services.txt"), filepath.Join(dir, "blackbox.yml")
+	if err := os.WriteFile(path, []byte(srv.URL+" module=http_post"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte(blackboxYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runLoad([]string{"-n", "1", path}); code != exitError {
+		t.Errorf("without -blackbox-config: want exit code %d; got %d", exitError, code)
+	}
+	if code := runLoad([]string{"-n", "1", "-blackbox-config", config, path}); code != exitOK {
+		t.Fatalf("want exit code %d; got %d", exitOK, code)
+	}
+	if method.Load() != http.MethodPost {
+		t.Errorf("want the method of the module; got %v", method.Load())
+	}
+}
+
+func TestRunLoadProtocols(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.txt")
+	if err := os.WriteFile(path, []byte("redis://127.0.0.1:1\npostgres://127.0.0.1:1/app\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	code := runLoad([]string{"-n", "1", path})
+	os.Stdout = stdout
+	w.Close()
+	io.Copy(&out, r)
+	if code != exitOK || out.String() != "" {
+		t.Errorf("want protocol services skipped; got exit code %d and %q", code, out.String())
+	}
+}
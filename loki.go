@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// lokiTimeout bound the time spent pushing to Loki so a slow Loki never
+// delays the end of a run.
+const lokiTimeout = 10 * time.Second
+
+// lokiJob is the job label of every stream pushed to Loki.
+const lokiJob = "healthcheck"
+
+// lokiPush is the body of the push API of Loki.
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// statusClass return the class of the status of res, like 2xx or 5xx, for
+// a low cardinality label: maintenance, error for failures without status
+// and none for other results without status.
+func statusClass(res Result) string {
+	switch {
+	case res.Maintenance:
+		return "maintenance"
+	case res.Status != 0:
+		return strconv.Itoa(res.Status/100) + "xx"
+	case res.Err != nil:
+		return "error"
+	}
+	return "none"
+}
+
+// lokiStreams return a log line per result, the result as JSON timestamped
+// with ts, in streams labelled with the service, its name or url, and its
+// status class.
+func lokiStreams(results []Result, ts time.Time) ([]lokiStream, error) {
+	var streams []lokiStream
+	index := make(map[[2]string]int)
+	for _, res := range results {
+		j := newJSONResult(res)
+		j.Time = &ts
+		line, err := json.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		service := res.Name
+		if service == "" {
+			service = res.Url
+		}
+		key := [2]string{service, statusClass(res)}
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, lokiStream{Stream: map[string]string{"job": lokiJob, "service": key[0], "status_class": key[1]}})
+		}
+		streams[i].Values = append(streams[i].Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), string(line)})
+	}
+	return streams, nil
+}
+
+// pushLoki send a structured log line per result checked at ts to the Loki
+// push API at endpoint, such as http://loki:3100/loki/api/v1/push. The
+// tenant, when not empty, is sent in the X-Scope-OrgID header, and
+// credentials in the userinfo of endpoint with basic authentication, as
+// Grafana Cloud expects.
+func pushLoki(ctx context.Context, endpoint, tenant string, results []Result, ts time.Time) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	user := u.User
+	u.User = nil
+	streams, err := lokiStreams(results, ts)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(lokiPush{Streams: streams})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lokiTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("loki push: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushLoki(t *testing.T) {
+	var (
+		got    lokiPush
+		tenant string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Scope-OrgID")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	at := time.Unix(1700000000, 0).UTC()
+	results := []Result{
+		{Name: "api", Url: "https://api.a.com", Status: 503, Latency: 20 * time.Millisecond},
+		{Url: "https://b.com", Err: errors.New("timeout")},
+		{Name: "api", Url: "https://api.a.com", Status: 502},
+		{Url: "https://c.com", Maintenance: true},
+	}
+	if err := pushLoki(context.Background(), srv.URL+"/loki/api/v1/push", "team-a", results, at); err != nil {
+		t.Fatal(err)
+	}
+	if tenant != "team-a" {
+		t.Errorf("want the tenant header; got %q", tenant)
+	}
+	want := []struct {
+		service, class string
+		lines          int
+	}{{"api", "5xx", 2}, {"https://b.com", "error", 1}, {"https://c.com", "maintenance", 1}}
+	if len(got.Streams) != len(want) {
+		t.Fatalf("got %+v", got.Streams)
+	}
+	for i, w := range want {
+		s := got.Streams[i]
+		if s.Stream["job"] != "healthcheck" || s.Stream["service"] != w.service || s.Stream["status_class"] != w.class || len(s.Values) != w.lines {
+			t.Errorf("stream %d: got %+v", i, s)
+		}
+	}
+	value := got.Streams[1].Values[0]
+	if value[0] != "1700000000000000000" || !strings.Contains(value[1], `"error":"timeout"`) || !strings.Contains(value[1], `"state":"down"`) {
+		t.Errorf("unexpected value %q", value)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := pushLoki(context.Background(), failing.URL, "", results, at); err == nil || !strings.Contains(err.Error(), "too far behind") {
+		t.Errorf("want the error of Loki; got %v", err)
+	}
+}
@@ -1,83 +1,630 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
+	"net"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 )
 
-type Result struct {
-	Url     string
-	Status  int
-	Err     error
-	Latency time.Duration
+// Exit codes of the program.
+const (
+	exitOK     = 0 // every service is up
+	exitError  = 1 // the run itself failed
+	exitFailed = 2 // at least one service is down
+)
+
+// config hold the command line options.
+type config struct {
+	path         string
+	otelEndpoint string
+	format       string
+	influxURL    string
+	influxToken  string
+	remoteWrite  string
+	tenant       string
+	lokiURL      string
+	lokiTenant   string
+	heartbeatURL string
+	tags         []string
+	exclude      []*regexp.Regexp
+	ignoreFile   string
+	varsFile     string
+	timeout      time.Duration
+	retries      int
+	workers      int
+	samples      int
+	warmup       bool
+	keepAlive    bool
+	freshConns   bool
+	encoding     string
+	download     int64
+	maxBodySize  int64
+	bodyPreview  int
+	dumpDir      string
+	maxDumps     int
+	maxLine      int
+	failOn       string
+	requestID    string
+	adaptive     bool
+	noDNSCache   bool
+	cookies      bool
+	userAgent    string
+	hostHeader   string
+	sni          string
+	dedupe       bool
+	blackbox     string
+	har          string
+	probe        string
+	gcpCreds     string
+	apdexT       time.Duration
+	baseline     string
+	driftState   string
+	audit        string
+	minTLS       uint16
+	location     *time.Location
+	domainExpiry time.Duration
+	geoIPDB      string
+	asnDB        string
+	slowdown     float64
+	vault        vaultOptions
+	sourceIP     net.IP
+	discovery    discovery
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "load":
+			os.Exit(runLoad(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "dns":
+			os.Exit(runDNS(os.Args[2:]))
+		case "links":
+			os.Exit(runLinks(os.Args[2:]))
+		}
+	}
+
+	var cfg config
+	flag.StringVar(&cfg.otelEndpoint, "otel-endpoint", "", "OTLP/HTTP collector URL (e.g. http://localhost:4318); telemetry is disabled when empty")
+	flag.StringVar(&cfg.format, "format", "text", "output format written to stdout: text, json or influx")
+	flag.StringVar(&cfg.influxURL, "influx-url", "", "InfluxDB or Telegraf HTTP write URL results are pushed to")
+	flag.StringVar(&cfg.influxToken, "influx-token", "", "token sent to the InfluxDB write endpoint")
+	flag.StringVar(&cfg.remoteWrite, "remote-write-url", "", "Prometheus remote_write URL metrics of the results are pushed to, with credentials in its userinfo if needed")
+	flag.StringVar(&cfg.tenant, "remote-write-tenant", "", "tenant sent in the X-Scope-OrgID header of remote_write requests, for Mimir and Cortex")
+	flag.StringVar(&cfg.lokiURL, "loki-url", "", "Grafana Loki push URL a JSON log line per result is pushed to, with credentials in its userinfo if needed")
+	flag.StringVar(&cfg.lokiTenant, "loki-tenant", "", "tenant sent in the X-Scope-OrgID header of Loki pushes")
+	sentryDSN := flag.String("sentry-dsn", os.Getenv("SENTRY_DSN"), "Sentry DSN internal errors are reported to, such as panics, invalid services files and failed pushes; defaults to SENTRY_DSN")
+	flag.StringVar(&cfg.heartbeatURL, "heartbeat-url", "", "URL pinged after a successful run, URL/fail is pinged when the run fails")
+	flag.DurationVar(&cfg.timeout, "timeout", DefaultTimeout, "time allowed for each request, services may override it with timeout=")
+	flag.IntVar(&cfg.retries, "retries", DefaultRetries, "number of retries of a failed check, services may override it with retries=")
+	flag.IntVar(&cfg.workers, "workers", DefaultWorkers, "number of concurrent checks")
+	flag.StringVar(&cfg.failOn, "fail-on", SeverityMajor, "least severity of the services whose failures fail the run: critical, major or minor")
+	flag.BoolVar(&cfg.adaptive, "adaptive", false, "halve the number of concurrent checks when checks time out or get 5xx responses, growing it back up to -workers as they recover")
+	flag.DurationVar(&cfg.apdexT, "apdex-t", 0, "target latency T of the Apdex scores of the summary, computed overall and per tag; disabled when 0")
+	flag.StringVar(&cfg.audit, "audit", "", "audit of the responses reported with the results: security, for missing or weak security headers")
+	flag.Func("min-tls", "lowest TLS version https services may negotiate or accept: 1.0, 1.1, 1.2 or 1.3", func(s string) (err error) {
+		cfg.minTLS, err = parseTLSVersion(s)
+		return err
+	})
+	flag.Func("domain-expiry", "warn when the registration of the domain of a service expires within this window, like 30d, as found with RDAP", func(s string) (err error) {
+		cfg.domainExpiry, err = parseDays(s)
+		return err
+	})
+	flag.StringVar(&cfg.geoIPDB, "geoip-db", "", "MaxMind Country or City database the country of the address of every service is looked up in")
+	flag.StringVar(&cfg.asnDB, "asn-db", "", "MaxMind ASN database the autonomous system of the address of every service is looked up in")
+	flag.StringVar(&cfg.driftState, "drift-state", "", "file the body hashes of services with drift=true are compared with and saved to, from one run to the next")
+	flag.StringVar(&cfg.baseline, "baseline", "", "results of a previous run written with -format json; regressions from it, not failures, set the exit code")
+	flag.Float64Var(&cfg.slowdown, "baseline-slowdown", DefaultSlowdown, "latency increase in percent from the baseline reported as a regression")
+	flag.IntVar(&cfg.samples, "samples", 1, "number of times each url is checked, reporting min/avg/p95/max latency and success rate")
+	flag.BoolVar(&cfg.warmup, "warmup", false, "send an untimed request to each host before measuring it")
+	flag.BoolVar(&cfg.keepAlive, "keep-alive", true, "reuse connections across requests; -keep-alive=false opens a new connection per request")
+	flag.BoolVar(&cfg.freshConns, "fresh-connections", false, "open new connections for each check instead of reusing those of previous checks")
+	flag.StringVar(&cfg.encoding, "accept-encoding", "", "Accept-Encoding header sent with checks (e.g. \"gzip, br\"), reporting the response encoding and transferred/decoded sizes")
+	flag.Func("download", "read response bodies to their end, at most this size (e.g. 10MB), reporting the time to first and last byte separately", func(s string) (err error) {
+		cfg.download, err = parseSize(s)
+		return err
+	})
+	flag.Func("max-body-size", "size of response bodies (e.g. 1MB) beyond which checks reading them fail (default 10MiB)", func(s string) (err error) {
+		cfg.maxBodySize, err = parseSize(s)
+		return err
+	})
+	flag.IntVar(&cfg.bodyPreview, "body-preview", DefaultBodyPreview, "number of bytes of the response body of failed checks shown in their result, 0 to disable")
+	flag.Func("timezone", "time zone of the start of checks in the results, such as Europe/Paris or Local (default UTC)", func(s string) (err error) {
+		cfg.location, err = time.LoadLocation(s)
+		return err
+	})
+	flag.StringVar(&cfg.dumpDir, "dump-failures", "", "directory the response headers and body of each failed check are written to, a file per failure")
+	flag.IntVar(&cfg.maxDumps, "dump-max-files", DefaultMaxDumps, "number of failures -dump-failures writes, the next ones being left out, 0 for no limit")
+	flag.IntVar(&cfg.maxLine, "max-line-length", DefaultMaxLineLength, "length in bytes beyond which lines of the services file are invalid, 0 for no limit")
+	flag.StringVar(&cfg.requestID, "request-id-header", DefaultRequestIDHeader, "header of the unique ID sent with each check and reported with failures, to find them in server logs; empty disables it")
+	flag.Func("dns-cache", "on to resolve each host once per TTL of its records, off to resolve it for every new connection (default on)", func(s string) error {
+		switch s {
+		case "on":
+			cfg.noDNSCache = false
+		case "off":
+			cfg.noDNSCache = true
+		default:
+			return fmt.Errorf("want on or off, got %q", s)
+		}
+		return nil
+	})
+	flag.BoolVar(&cfg.cookies, "cookies", false, "keep cookies set by responses for the whole run, including across redirects")
+	flag.StringVar(&cfg.userAgent, "user-agent", DefaultUserAgent, "User-Agent header sent with checks")
+	flag.StringVar(&cfg.hostHeader, "host-header", "", "Host header sent with checks, services may override it with host-header=")
+	flag.StringVar(&cfg.sni, "sni", "", "TLS server name sent with checks, defaulting to the Host header; services may override it with sni=")
+	flag.BoolVar(&cfg.dedupe, "dedupe", false, "normalize urls (lowercase host, no fragment, no default port) and skip duplicates")
+	flag.StringVar(&cfg.probe, "probe", "", "check this single url, printing nothing unless it is down, and exit 0 when up or 1 otherwise; for container health checks")
+	flag.StringVar(&cfg.gcpCreds, "gcp-credentials", "", "service account key file for services with auth=gcp-id-token, defaulting to GOOGLE_APPLICATION_CREDENTIALS then the metadata server")
+	flag.StringVar(&cfg.har, "har", "", "file every request and response of the checks is recorded to, in HTTP Archive format")
+	flag.StringVar(&cfg.blackbox, "blackbox-config", "", "blackbox_exporter configuration file whose http modules services may use with module=")
+	sourceIP := flag.String("source-ip", "", "local address checks are sent from, on multi-homed hosts")
+	iface := flag.String("interface", "", "network interface checks are sent from, by its first address")
+	cfg.vault.register(flag.CommandLine)
+	cfg.discovery.register(flag.CommandLine)
+	flag.Func("tags", "comma separated list of tags, only services with one of them are checked", func(s string) error {
+		cfg.tags = append(cfg.tags, strings.Split(s, ",")...)
+		return nil
+	})
+	flag.Func("exclude", "regular expression of the names or urls of services not to check, may be repeated", func(s string) error {
+		re, err := regexp.Compile(s)
+		cfg.exclude = append(cfg.exclude, re)
+		return err
+	})
+	flag.StringVar(&cfg.ignoreFile, "ignore-file", "", "file of regular expressions, one per line, of the names or urls of services not to check")
+	flag.StringVar(&cfg.varsFile, "vars-file", "", "YAML or JSON file of the variables the lines of the services file containing {{ are rendered against, a service per combination of their values")
+	flag.Parse()
+	cfg.path = flag.Arg(0)
+	if err := setupSentry(*sentryDSN); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	var err error
+	if cfg.sourceIP, err = sourceIPOf(*sourceIP, *iface); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	if cfg.probe != "" {
+		os.Exit(runProbe(cfg))
+	}
+
+	code := run(cfg)
+	if cfg.heartbeatURL != "" {
+		if err := pingHeartbeat(context.Background(), cfg.heartbeatURL, code == exitOK); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "heartbeat"})
+		}
+	}
+	os.Exit(code)
+}
+
+// run check the services listed in cfg.path and return the exit code.
+func run(cfg config) int {
+	if cfg.path == "" && !cfg.discovery.enabled() {
 		fmt.Fprintln(os.Stderr, "missing file argument")
-		os.Exit(1)
+		return exitError
+	}
+	var discover discoverFunc
+	if cfg.discovery.enabled() {
+		var err error
+		if discover, err = cfg.discovery.discoverer(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	if cfg.format != "text" && cfg.format != "json" && cfg.format != "influx" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", cfg.format)
+		return exitError
+	}
+	if cfg.audit != "" && cfg.audit != "security" {
+		fmt.Fprintf(os.Stderr, "unknown audit %q\n", cfg.audit)
+		return exitError
+	}
+	if cfg.samples < 1 {
+		fmt.Fprintln(os.Stderr, "samples must be at least 1")
+		return exitError
+	}
+	if _, ok := severityRanks[cfg.failOn]; cfg.failOn != "" && !ok {
+		fmt.Fprintf(os.Stderr, "unknown severity %q\n", cfg.failOn)
+		return exitError
 	}
 
-	path := os.Args[1]
-	fmt.Printf("Opening %s\n", path)
+	shutdown, err := setupTelemetry(context.Background(), cfg.otelEndpoint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	// Flush pending spans and metrics before exiting.
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
 
-	f, err := os.Open(path)
+	if cfg.format == "text" && cfg.path != "" {
+		fmt.Printf("Opening %s\n", cfg.path)
+	}
+
+	path, discover := cfg.discovery.sitemap.input(cfg.path, discover)
+	services, err := loadServices(context.Background(), path, cfg.maxLine, cfg.varsFile, discover, cfg.tags)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return exitError
+	}
+	if err := prepareServices(context.Background(), services, cfg.blackbox, cfg.vault.client()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if cfg.dedupe {
+		var n int
+		if services, n = dedupe(services); n > 0 {
+			fmt.Fprintf(os.Stderr, "%s: %d duplicate services skipped\n", cfg.path, n)
+		}
+	}
+	if cfg.ignoreFile != "" {
+		patterns, err := readIgnoreFile(cfg.ignoreFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		cfg.exclude = append(cfg.exclude, patterns...)
+	}
+	services, excluded := excludeServices(services, cfg.exclude)
+	var baseline []jsonResult
+	if cfg.baseline != "" {
+		if baseline, err = readResultsFile(cfg.baseline); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	opts := cfg.options()
+	if isTerminal(os.Stderr) {
+		opts = append(opts, WithProgress(os.Stderr))
+	}
+	var har HAR
+	if cfg.har != "" {
+		opts = append(opts, WithHAR(&har))
+	}
+	if cfg.geoIPDB != "" || cfg.asnDB != "" {
+		geo, err := openGeoIP(cfg.geoIPDB, cfg.asnDB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer geo.Close()
+		opts = append(opts, WithGeoIP(geo))
+	}
+	var hashes map[string]string
+	if cfg.driftState != "" {
+		if hashes, err = readContentHashes(cfg.driftState); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		opts = append(opts, WithContentHashes(hashes))
+	}
+	results := HealthCheck(services, opts...)
+	if cfg.driftState != "" {
+		if err := writeContentHashes(cfg.driftState, hashes); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	if cfg.har != "" {
+		if err := writeHAR(cfg.har, &har); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	now := time.Now()
+	switch cfg.format {
+	case "influx":
+		err = writeInflux(os.Stdout, results, now)
+	case "json":
+		s := summarize(results, cfg.apdexT)
+		s.Excluded = excluded
+		err = writeJSONReport(os.Stdout, results, s, now)
+	default:
+		writeText(os.Stdout, results)
+		s := summarize(results, cfg.apdexT)
+		s.Excluded = excluded
+		writeSummary(os.Stdout, s)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if cfg.influxURL != "" {
+		if err := pushInflux(context.Background(), cfg.influxURL, cfg.influxToken, results, now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "influx"})
+			return exitError
+		}
+	}
+	if cfg.remoteWrite != "" {
+		if err := pushRemoteWrite(context.Background(), cfg.remoteWrite, cfg.tenant, results, now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "remote_write"})
+			return exitError
+		}
+	}
+	if cfg.lokiURL != "" {
+		if err := pushLoki(context.Background(), cfg.lokiURL, cfg.lokiTenant, results, now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "loki"})
+			return exitError
+		}
+	}
+
+	if cfg.baseline != "" {
+		current := make([]jsonResult, len(results))
+		for i, res := range results {
+			current[i] = newJSONResult(res)
+		}
+		// Keep stdout parsable in the machine readable formats.
+		w := os.Stdout
+		if cfg.format != "text" {
+			w = os.Stderr
+		}
+		if writeBaseline(w, diffResults(baseline, current), cfg.slowdown) {
+			return exitFailed
+		}
+		return exitOK
 	}
-	defer f.Close()
 
-	services := GetServices(f)
-	results := HealthCheck(services)
 	for _, res := range results {
-		if res.Err != nil {
-			fmt.Printf("Url: %s; Error: %s\n", res.Url, res.Err)
-			continue
+		if failsRun(res, cfg.failOn) {
+			return exitFailed
 		}
-		fmt.Printf("Url: %s; Status: %d; Latency: %s\n", res.Url, res.Status, res.Latency.Round(time.Millisecond))
 	}
+	return exitOK
 }
 
-// HealthCheck report if a list of web service is up and running.
-func HealthCheck(urls []string) []Result {
-	results := make([]Result, 0, len(urls))
-
-	var wg sync.WaitGroup
-	wg.Add(len(urls))
-	for _, url := range urls {
-		go func() {
-			defer wg.Done()
-			var result Result
-			start := time.Now()
-			resp, err := http.Get(url)
-			if err != nil {
-				result.Err = err
-			} else {
-				result.Status = resp.StatusCode
-				result.Url = url
-				result.Latency = time.Since(start)
-			}
-			results = append(results, result)
-		}()
+// options return the checker options set by cfg.
+func (cfg config) options() []Option {
+	opts := []Option{
+		WithTimeout(cfg.timeout),
+		WithRetries(cfg.retries),
+		WithWorkers(cfg.workers),
+		WithAdaptiveConcurrency(cfg.adaptive),
+		WithSamples(cfg.samples),
+		WithWarmup(cfg.warmup),
+		WithKeepAlive(cfg.keepAlive),
+		WithFreshConnections(cfg.freshConns),
+		WithAcceptEncoding(cfg.encoding),
+		WithDownload(cfg.download),
+		WithMaxBodySize(cmp.Or(cfg.maxBodySize, DefaultMaxBodySize)),
+		WithBodyPreview(cfg.bodyPreview),
+		WithDumpFailures(cfg.dumpDir),
+		WithMaxDumps(cfg.maxDumps),
+		WithRequestID(cfg.requestID),
+		WithDNSCache(!cfg.noDNSCache),
+		WithCookies(cfg.cookies),
+		WithUserAgent(cfg.userAgent),
+		WithHostHeader(cfg.hostHeader),
+		WithSNI(cfg.sni),
+		WithSecurityAudit(cfg.audit == "security"),
+		WithMinTLS(cfg.minTLS),
+		WithDomainExpiry(cfg.domainExpiry),
+	}
+	if cfg.gcpCreds != "" {
+		opts = append(opts, WithGCPCredentials(cfg.gcpCreds))
+	}
+	if cfg.sourceIP != nil {
+		opts = append(opts, WithSourceIP(cfg.sourceIP))
+	}
+	if cfg.location != nil {
+		opts = append(opts, WithTimezone(cfg.location))
+	}
+	return opts
+}
+
+// runProbe check the single service cfg.probe, a line of a services file,
+// for use as a Docker HEALTHCHECK or a Kubernetes exec probe: nothing is
+// printed when it is up and the exit code is 0, otherwise the reason is
+// printed on stderr and the exit code is 1, the only failure code Docker
+// defines.
+func runProbe(cfg config) int {
+	if cfg.path != "" || cfg.discovery.enabled() {
+		fmt.Fprintln(os.Stderr, "-probe checks a single url, without services file or discovery")
+		return exitError
+	}
+	svc, err := ParseService(cfg.probe)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	services := []Service{svc}
+	if err := prepareServices(context.Background(), services, cfg.blackbox, cfg.vault.client()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	res := HealthCheck(services, cfg.options()...)[0]
+	if !res.Up() {
+		if res.RequestID != "" {
+			fmt.Fprintf(os.Stderr, "%s (request ID %s): %s\n", redactURL(svc.URL), res.RequestID, res.Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", redactURL(svc.URL), res.Err)
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// prepareServices apply to services the blackbox_exporter modules of the
+// configuration file at blackbox, then replace the Vault references of
+// their headers by the secrets read with vault.
+func prepareServices(ctx context.Context, services []Service, blackbox string, vault *vaultClient) error {
+	if err := useBlackboxConfig(services, blackbox); err != nil {
+		return err
+	}
+	return resolveSecrets(ctx, services, vault)
+}
+
+// readServices parse the services file at path, lines longer than maxLine
+// bytes being invalid and templates being rendered against the variables of
+// varsFile, when not empty. Invalid lines and unknown dependencies are
+// reported on stderr, invalid lines being skipped.
+func readServices(path string, maxLine int, varsFile string) ([]Service, error) {
+	var vars TemplateVars
+	if varsFile != "" {
+		var err error
+		if vars, err = readVarsFile(varsFile); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	services, err := ParseServicesTemplate(f, path, maxLine, vars)
+	if err != nil {
+		printParseErrors(os.Stderr, err)
+		reportError(scrubParseErrors(err), map[string]string{"source": path})
 	}
+	for _, dep := range unknownDependencies(services) {
+		fmt.Fprintf(os.Stderr, "%s: unknown dependency %q\n", path, dep)
+	}
+	return services, nil
+}
 
-	wg.Wait()
-	return results
+// printParseErrors print err, an error of ParseServices, to w with each of
+// its invalid lines and its code, such as services.txt:12: HC002: unknown
+// option "foo".
+func printParseErrors(w io.Writer, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			printParseErrors(w, err)
+		}
+		return
+	}
+	if perr, ok := err.(*ParseError); ok {
+		fmt.Fprintf(w, "%s:%d: %s: %s\n", perr.Source, perr.Line, perr.ErrorCode(), perr.Err)
+		return
+	}
+	fmt.Fprintln(w, err)
 }
 
-// GetServices read each line of the input reader and return a list of url.
-func GetServices(r io.Reader) []string {
-	urls := make([]string, 0)
-	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		urls = append(urls, scanner.Text())
+// writeText print results in a human readable form. Named services are
+// reported by name rather than by url, members of composite services and
+// steps of scenarios are indented below them.
+//
+// Each result is formatted in a buffer of textBuffers and written at once,
+// so that writing many results to an unbuffered writer such as os.Stdout
+// takes a write per result rather than per field.
+func writeText(w io.Writer, results []Result) {
+	buf := textBuffers.Get().(*bytes.Buffer)
+	defer textBuffers.Put(buf)
+	for _, res := range results {
+		buf.Reset()
+		writeTextResult(buf, "", res)
+		for _, finding := range res.Audit {
+			fmt.Fprintf(buf, "  Audit: %s\n", finding)
+		}
+		if res.BodyPreview != "" {
+			fmt.Fprintf(buf, "  Body: %s\n", res.BodyPreview)
+		}
+		for _, member := range res.Members {
+			writeTextResult(buf, "  ", member)
+		}
+		for _, step := range res.Steps {
+			fmt.Fprintf(buf, "  Step: %s; Status: %d; Latency: %s", step.Name, step.Status, step.Latency.Round(time.Millisecond))
+			if step.Err != nil {
+				fmt.Fprintf(buf, "; Code: %s; Error: %s", ErrorCodeOf(step.Err), step.Err)
+			}
+			buf.WriteByte('\n')
+		}
+		w.Write(buf.Bytes())
+	}
+}
+
+// textBuffers pool the buffers of writeText.
+var textBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func writeTextResult(w io.Writer, indent string, res Result) {
+	io.WriteString(w, indent)
+	if res.Name != "" {
+		io.WriteString(w, "Service: ")
+		io.WriteString(w, res.Name)
+	} else {
+		io.WriteString(w, "Url: ")
+		io.WriteString(w, res.Url)
+		if res.UnicodeURL != "" {
+			io.WriteString(w, " (")
+			io.WriteString(w, res.UnicodeURL)
+			io.WriteString(w, ")")
+		}
+	}
+	if !res.Start.IsZero() {
+		io.WriteString(w, "; Start: ")
+		io.WriteString(w, res.Start.Format(startFormat))
+	}
+	switch {
+	case res.Maintenance:
+		io.WriteString(w, "; Maintenance\n")
+		return
+	case res.Status != 0:
+		fmt.Fprintf(w, "; Status: %d; Latency: %s", res.Status, res.Latency.Round(time.Millisecond))
+	case res.Err == nil && (len(res.Members) > 0 || res.Latency > 0):
+		fmt.Fprintf(w, "; Latency: %s", res.Latency.Round(time.Millisecond))
+	}
+	if s := res.Stats; s != nil {
+		fmt.Fprintf(w, "; Samples: %d; Success: %.0f%%; Min/Avg/P95/Max: %s/%s/%s/%s",
+			s.Samples, 100*s.SuccessRate(),
+			s.Min.Round(time.Millisecond), s.Avg.Round(time.Millisecond),
+			s.P95.Round(time.Millisecond), s.Max.Round(time.Millisecond))
+	}
+	if len(res.Cache) > 0 {
+		fmt.Fprintf(w, "; Cache: %s", formatCache(res.Cache))
+	}
+	if res.TTLB > 0 {
+		fmt.Fprintf(w, "; TTFB: %s; TTLB: %s", res.TTFB.Round(time.Millisecond), res.TTLB.Round(time.Millisecond))
+	}
+	if res.TLSVersion != "" {
+		fmt.Fprintf(w, "; TLS: %s %s", res.TLSVersion, res.TLSCipher)
+	}
+	if res.Banner != "" {
+		fmt.Fprintf(w, "; Banner: %s", res.Banner)
+	}
+	if res.Stratum != 0 {
+		fmt.Fprintf(w, "; Offset: %s; Stratum: %d", res.Offset.Round(time.Microsecond), res.Stratum)
+	}
+	if !res.CertExpiry.IsZero() {
+		fmt.Fprintf(w, "; Cert expires: %s", res.CertExpiry.Format(time.DateOnly))
+	}
+	if res.Geo != nil {
+		fmt.Fprintf(w, "; Geo: %s", res.Geo)
+	}
+	if res.BytesTransferred > 0 && res.BytesDecoded == 0 && res.ContentEncoding == "" {
+		fmt.Fprintf(w, "; Bytes: %d transferred", res.BytesTransferred)
+	} else if res.BytesTransferred > 0 {
+		encoding := res.ContentEncoding
+		if encoding == "" {
+			encoding = "identity"
+		}
+		fmt.Fprintf(w, "; Encoding: %s; Bytes: %d transferred, %d decoded", encoding, res.BytesTransferred, res.BytesDecoded)
+	}
+	if res.Err != nil {
+		if res.RequestID != "" {
+			fmt.Fprintf(w, "; Request ID: %s", res.RequestID)
+		}
+		fmt.Fprintf(w, "; Code: %s; Error: %s", ErrorCodeOf(res.Err), res.Err)
 	}
-	return urls
+	io.WriteString(w, "\n")
 }
@@ -1,35 +1,83 @@
 package main
 
 import (
-	"golang.org/x/exp/slices"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
-var services = `https://stackoverflow.com
-https://www.google.com
-https://go.dev
-https://www.docker.com
-https://kubernetes.io
-https://www.finconsgroup.com
-`
+func TestWriteText(t *testing.T) {
+	results := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 130 * time.Millisecond},
+		{Name: "checkout-api", Url: "https://b.com", Err: errors.New("timeout")},
+		{Url: "https://c.com", Status: 503, Latency: 5 * time.Millisecond, Err: &StatusError{Status: 503}},
+		{Url: "https://xn--bcher-kva.example", UnicodeURL: "https://bücher.example", Status: 200, Latency: time.Millisecond},
+	}
+
+	var b strings.Builder
+	writeText(&b, results)
+
+	want := "Url: https://a.com; Status: 200; Latency: 130ms\n" +
+		"Service: checkout-api; Code: HC900; Error: timeout\n" +
+		"Url: https://c.com; Status: 503; Latency: 5ms; Code: HC201; Error: unexpected status 503\n" +
+		"Url: https://xn--bcher-kva.example (https://bücher.example); Status: 200; Latency: 1ms\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
 
-func TestHealthCheck(t *testing.T) {
-	panic("TODO implements me")
+func TestPrintParseErrors(t *testing.T) {
+	_, err := ParseServicesLimit(strings.NewReader("https://a.com foo=1\na.com\nhttps://b.com "+strings.Repeat("x", 100)), "services.txt", 64)
+	var b strings.Builder
+	printParseErrors(&b, err)
+	want := "services.txt:1: HC002: unknown option \"foo\"\n" +
+		"services.txt:2: HC001: "
+	if got := b.String(); !strings.HasPrefix(got, want) || !strings.Contains(got, "services.txt:3: HC003: ") {
+		t.Errorf("want:\n%s...\ngot:\n%s", want, got)
+	}
 }
 
-func TestGetServices(t *testing.T) {
-	want := []string{
-		"https://stackoverflow.com",
-		"https://www.google.com",
-		"https://go.dev",
-		"https://www.docker.com",
-		"https://kubernetes.io",
-		"https://www.finconsgroup.com",
+func TestRunProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		cfg  config
+		want int
+	}{
+		{"up", config{probe: srv.URL}, exitOK},
+		{"down", config{probe: srv.URL + "/down"}, exitError},
+		{"expected status", config{probe: srv.URL + "/down expect=503"}, exitOK},
+		{"invalid", config{probe: "gopher://a.com"}, exitError},
+		{"with file", config{probe: srv.URL, path: "services.txt"}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.timeout = time.Second
+			if got := runProbe(tt.cfg); got != tt.want {
+				t.Errorf("want exit code %d; got %d", tt.want, got)
+			}
+		})
 	}
+}
 
-	got := GetServices(strings.NewReader(services))
-	if slices.Compare(want, got) != 0 {
-		t.Errorf("want: %v; got: %v", want, got)
+func BenchmarkWriteText(b *testing.B) {
+	results := make([]Result, 1000)
+	for i := range results {
+		results[i] = Result{Url: fmt.Sprintf("https://svc%d.a.com", i), Status: http.StatusOK, Latency: 12 * time.Millisecond}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		writeText(io.Discard, results)
 	}
 }
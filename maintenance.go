@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a period during which a service is not checked.
+type MaintenanceWindow interface {
+	Contains(t time.Time) bool
+}
+
+// parseMaintenanceWindow parse either an RFC3339 range or a cron expression
+// followed by the duration of each window:
+//
+//	2026-01-01T00:00:00Z/2026-01-01T02:00:00Z
+//	0 2 * * 0 for 2h
+func parseMaintenanceWindow(s string) (MaintenanceWindow, error) {
+	if expr, d, ok := strings.Cut(s, " for "); ok {
+		sched, err := parseCron(expr)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, err
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration must be positive")
+		}
+		return cronWindow{sched: sched, duration: duration}, nil
+	}
+
+	start, end, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("want start/end or \"cron for duration\", got %q", s)
+	}
+	var w rangeWindow
+	var err error
+	if w.start, err = time.Parse(time.RFC3339, start); err != nil {
+		return nil, err
+	}
+	if w.end, err = time.Parse(time.RFC3339, end); err != nil {
+		return nil, err
+	}
+	if !w.end.After(w.start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	return w, nil
+}
+
+// rangeWindow is a single maintenance window, end excluded.
+type rangeWindow struct {
+	start, end time.Time
+}
+
+func (w rangeWindow) Contains(t time.Time) bool {
+	return !t.Before(w.start) && t.Before(w.end)
+}
+
+// cronWindow is a recurring maintenance window opening on each activation
+// of sched for duration.
+type cronWindow struct {
+	sched    cronSchedule
+	duration time.Duration
+}
+
+// Contains look for an activation within duration before t.
+func (w cronWindow) Contains(t time.Time) bool {
+	t = t.Local()
+	latest := t.Truncate(time.Minute)
+	for start := latest; t.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.sched.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// cronSchedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week. Each field is a set of allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted day fields, a day matching when
+	// either restricted field matches as in cron.
+	domStar, dowStar bool
+}
+
+var cronFields = [...]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseCron parse a five fields cron expression. Fields accept *, values,
+// ranges a-b, lists and /step.
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("cron %q: want %d fields, got %d", expr, len(cronFields), len(fields))
+	}
+
+	var sets [len(cronFields)]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("cron %s: %w", cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return cronSchedule{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// matches report whether the schedule activates at the minute of t.
+func (s cronSchedule) matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<t.Month()) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
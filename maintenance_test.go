@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRangeWindow(t *testing.T) {
+	w, err := parseMaintenanceWindow("2026-01-01T00:00:00Z/2026-01-01T02:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2025-12-31T23:59:59Z", false},
+		{"2026-01-01T00:00:00Z", true},
+		{"2026-01-01T03:00:00+02:00", true},
+		{"2026-01-01T02:00:00Z", false},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := w.Contains(at); got != tt.want {
+			t.Errorf("Contains(%s): want %t; got %t", tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestCronWindow(t *testing.T) {
+	// Sundays from 02:00 to 04:00, local time.
+	w, err := parseMaintenanceWindow("0 2 * * 7 for 2h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sunday := time.Date(2026, time.October, 18, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{sunday.Add(time.Hour + 59*time.Minute), false},
+		{sunday.Add(2 * time.Hour), true},
+		{sunday.Add(3*time.Hour + 59*time.Minute + 59*time.Second), true},
+		{sunday.Add(4 * time.Hour), false},
+		{sunday.Add(24*time.Hour + 3*time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := w.Contains(tt.at); got != tt.want {
+			t.Errorf("Contains(%s): want %t; got %t", tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	sched, err := parseCron("*/15 9-17 1,15 * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		// Restricted day of month and day of week match when either does.
+		{time.Date(2026, time.October, 15, 9, 30, 0, 0, time.UTC), true}, // thursday 15th
+		{time.Date(2026, time.November, 1, 9, 45, 0, 0, time.UTC), true}, // sunday 1st
+		{time.Date(2026, time.October, 13, 17, 0, 0, 0, time.UTC), true}, // tuesday
+		{time.Date(2026, time.October, 13, 17, 5, 0, 0, time.UTC), false},
+		{time.Date(2026, time.October, 13, 18, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC), false}, // sunday 18th
+	}
+	for _, tt := range tests {
+		if got := sched.matches(tt.at); got != tt.want {
+			t.Errorf("matches(%s): want %t; got %t", tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestParseMaintenanceWindowErrors(t *testing.T) {
+	for _, s := range []string{
+		"tomorrow",
+		"2026-01-01T02:00:00Z/2026-01-01T00:00:00Z",
+		"0 2 * * for 2h",
+		"0 25 * * * for 2h",
+		"*/0 * * * * for 2h",
+		"0 2 * * * for -1h",
+	} {
+		if _, err := parseMaintenanceWindow(s); err == nil {
+			t.Errorf("parseMaintenanceWindow(%q): want an error", s)
+		}
+	}
+}
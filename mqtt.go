@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	mqttConnect    = 1 << 4
+	mqttConnAck    = 2 << 4
+	mqttDisconnect = 14 << 4
+	// mqttLevel is the protocol level of MQTT 3.1.1.
+	mqttLevel = 4
+	// mqttKeepAlive is the keep alive of the connections of checks, in
+	// seconds.
+	mqttKeepAlive = 30
+)
+
+// mqttReturnCodes describe the return codes of the CONNACK packets
+// refusing a connection.
+var mqttReturnCodes = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// MQTTError report a connection refused by an MQTT broker.
+type MQTTError struct {
+	Code byte
+}
+
+func (e *MQTTError) Error() string {
+	if reason, ok := mqttReturnCodes[e.Code]; ok {
+		return "mqtt connect: " + reason
+	}
+	return fmt.Sprintf("mqtt connect: return code %d", e.Code)
+}
+
+// checkMQTT check the MQTT broker of an mqtt://[user:password@]host:port
+// url, mqtts:// for TLS, mqtt+ws:// and mqtt+wss:// for WebSocket at the
+// path of the url, /mqtt without one: it sends CONNECT with a clean session
+// and waits for CONNACK, then disconnects. The client identifier is the
+// client-id parameter of the url, a random one without it. The latency of
+// the result is the round trip of CONNECT.
+func checkMQTT(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	var conn net.Conn
+	switch u.Scheme {
+	case "mqtt+ws", "mqtt+wss":
+		ws, err := c.dialWebSocket(ctx, u, result)
+		if err != nil {
+			return err
+		}
+		defer ws.CloseNow()
+		conn = websocket.NetConn(ctx, ws, websocket.MessageBinary)
+	default:
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+		var err error
+		if conn, err = c.dial(ctx, "tcp", hostPort(u, port)); err != nil {
+			return err
+		}
+		defer conn.Close()
+		if u.Scheme == "mqtts" {
+			tlsConn := tls.Client(conn, c.tlsConfig(u.Hostname()))
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return err
+			}
+			state := tlsConn.ConnectionState()
+			recordTLS(&state, result)
+			conn = tlsConn
+		}
+	}
+
+	clientID := u.Query().Get("client-id")
+	if clientID == "" {
+		clientID = fmt.Sprintf("healthcheck-%08x", rand.Uint32())
+	}
+	start := time.Now()
+	if _, err := conn.Write(mqttConnectPacket(clientID, u.User)); err != nil {
+		return err
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(bufio.NewReader(conn), ack); err != nil {
+		return fmt.Errorf("mqtt connack: %w", err)
+	}
+	result.Latency = time.Since(start)
+	if ack[0] != mqttConnAck || ack[1] != 2 {
+		return errors.New("mqtt connack: unexpected packet")
+	}
+	if ack[3] != 0 {
+		return &MQTTError{Code: ack[3]}
+	}
+	conn.Write([]byte{mqttDisconnect, 0})
+	return nil
+}
+
+// mqttConnectPacket return the CONNECT packet of an MQTT client with a
+// clean session, authenticated by user when set.
+func mqttConnectPacket(clientID string, user *url.Userinfo) []byte {
+	flags := byte(0x02)
+	payload := mqttAppendString(nil, clientID)
+	if user != nil {
+		flags |= 0x80
+		payload = mqttAppendString(payload, user.Username())
+		if password, ok := user.Password(); ok {
+			flags |= 0x40
+			payload = mqttAppendString(payload, password)
+		}
+	}
+	body := mqttAppendString(nil, "MQTT")
+	body = append(body, mqttLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+	body = append(body, payload...)
+
+	packet := []byte{mqttConnect}
+	// The remaining length is encoded in 7 bits per byte, least
+	// significant first.
+	for n := len(body); ; {
+		b := byte(n & 0x7f)
+		if n >>= 7; n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttAppendString append s to b as an MQTT string, prefixed by its length.
+func mqttAppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// dialWebSocket open a WebSocket with the mqtt subprotocol to the
+// mqtt+ws:// or mqtt+wss:// url u, from the source address of c.
+func (c *checker) dialWebSocket(ctx context.Context, u *url.URL, result *Result) (*websocket.Conn, error) {
+	ws := &url.URL{Scheme: "ws", Host: u.Host, Path: u.Path}
+	if u.Scheme == "mqtt+wss" {
+		ws.Scheme = "wss"
+	}
+	if ws.Path == "" {
+		ws.Path = "/mqtt"
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return c.dialContext(ctx, newDialer(c), network, addr)
+		},
+		TLSClientConfig: c.tlsConfig(u.Hostname()),
+	}
+	defer transport.CloseIdleConnections()
+	conn, resp, err := websocket.Dial(ctx, ws.String(), &websocket.DialOptions{
+		HTTPClient:   &http.Client{Transport: transport},
+		Subprotocols: []string{"mqtt"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordTLS(resp.TLS, result)
+	return conn, nil
+}
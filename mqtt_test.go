@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+// fakeMQTT answer the CONNECT packet read from conn as an MQTT broker
+// accepting the clients authenticated by user and password.
+func fakeMQTT(conn net.Conn, user, password string) {
+	defer conn.Close()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil || head[0] != mqttConnect {
+		return
+	}
+	body := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, body); err != nil || string(body[2:6]) != "MQTT" || body[6] != mqttLevel {
+		return
+	}
+	flags, payload := body[7], body[10:]
+	next := func() string {
+		n := binary.BigEndian.Uint16(payload)
+		s := string(payload[2 : 2+n])
+		payload = payload[2+n:]
+		return s
+	}
+	var gotUser, gotPassword string
+	if next() == "" {
+		return
+	}
+	if flags&0x80 != 0 {
+		gotUser = next()
+	}
+	if flags&0x40 != 0 {
+		gotPassword = next()
+	}
+	code := byte(0)
+	if gotUser != user || gotPassword != password {
+		code = 4
+	}
+	conn.Write([]byte{mqttConnAck, 2, 0, code})
+	io.ReadFull(conn, head)
+}
+
+func TestCheckMQTT(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go fakeMQTT(conn, "monitor", "s3cret")
+		}
+	}()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mqtt" {
+			http.NotFound(w, r)
+			return
+		}
+		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"mqtt"}})
+		if err != nil {
+			return
+		}
+		fakeMQTT(websocket.NetConn(r.Context(), ws, websocket.MessageBinary), "monitor", "s3cret")
+	}))
+	defer srv.Close()
+	ws := strings.TrimPrefix(srv.URL, "http://")
+
+	c := newChecker(WithRetries(0))
+	for _, url := range []string{
+		"mqtt://monitor:s3cret@" + l.Addr().String(),
+		"mqtt+ws://monitor:s3cret@" + ws,
+		"mqtt+ws://monitor:s3cret@" + ws + "/mqtt?client-id=probe",
+	} {
+		if res := c.check(context.Background(), Service{URL: url}); !res.Up() || res.Latency <= 0 {
+			t.Errorf("%s: want up; got %v, latency %s", url, res.Err, res.Latency)
+		}
+	}
+	var merr *MQTTError
+	res := c.check(context.Background(), Service{URL: "mqtt://monitor:wrong@" + l.Addr().String()})
+	if !errors.As(res.Err, &merr) || merr.Error() != "mqtt connect: bad user name or password" {
+		t.Errorf("wrong password: want an MQTTError; got %v", res.Err)
+	}
+	if res := c.check(context.Background(), Service{URL: "mqtt+ws://" + ws + "/ws"}); res.Up() {
+		t.Error("missing WebSocket endpoint: want down")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// DefaultMaxNTPOffset is the offset from the local clock beyond which the
+// time of an NTP server is reported as drifting, the threshold at which
+// ntpd steps the clock instead of slewing it.
+const DefaultMaxNTPOffset = 128 * time.Millisecond
+
+const (
+	// ntpEpoch is the number of seconds from the NTP epoch, 1900, to the
+	// Unix epoch.
+	ntpEpoch = 2208988800
+	// ntpRequest is the first byte of a request: no leap second warning,
+	// version 4, client mode.
+	ntpRequest = 0<<6 | 4<<3 | 3
+	// ntpServer is the mode of the replies of servers.
+	ntpServer = 4
+	// ntpUnsynchronized is the leap indicator and the least stratum of
+	// servers whose clock is not synchronized.
+	ntpUnsynchronized = 3
+	ntpMaxStratum     = 16
+)
+
+// OffsetError report an NTP server whose time is too far from the local
+// clock.
+type OffsetError struct {
+	Offset time.Duration
+	Max    time.Duration
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("ntp: offset %s exceeds %s", e.Offset, e.Max)
+}
+
+// ntpTime return the time of the NTP timestamp b, in the era of the Unix
+// epoch.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b)) - ntpEpoch
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// putNTPTime write t to b as an NTP timestamp.
+func putNTPTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()+ntpEpoch))
+	binary.BigEndian.PutUint32(b[4:], uint32(int64(t.Nanosecond())<<32/1e9))
+}
+
+// checkNTP query the NTP server of an ntp://host:port url, recording its
+// stratum and the offset of its time from the local clock in the result.
+// The server is down when its clock is not synchronized or when the offset
+// exceeds the max-offset duration of the url, DefaultMaxNTPOffset without
+// it. The latency of the result is the round trip delay of the query.
+func checkNTP(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	maxOffset := DefaultMaxNTPOffset
+	if s := u.Query().Get("max-offset"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("ntp max-offset: %w", err)
+		}
+		maxOffset = d
+	}
+	conn, err := c.dial(ctx, "udp", hostPort(u, "123"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req := make([]byte, 48)
+	req[0] = ntpRequest
+	sent := time.Now()
+	putNTPTime(req[40:], sent)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	resp := make([]byte, 48)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return err
+		}
+		// Ignore what is not the reply to this request.
+		if n == len(resp) && resp[0]&7 == ntpServer && string(resp[24:32]) == string(req[40:48]) {
+			break
+		}
+	}
+	received := time.Now()
+
+	leap, stratum := resp[0]>>6, int(resp[1])
+	if stratum == 0 {
+		return fmt.Errorf("ntp: kiss of death %q", resp[12:16])
+	}
+	result.Stratum = stratum
+	if leap == ntpUnsynchronized || stratum >= ntpMaxStratum {
+		return errors.New("ntp: server clock not synchronized")
+	}
+	// The local times are compared to those of the server on the wall
+	// clock, measured between them on the monotonic one.
+	serverReceived, serverSent := ntpTime(resp[32:]), ntpTime(resp[40:])
+	t1 := sent.Round(0)
+	t4 := t1.Add(received.Sub(sent))
+	result.Latency = t4.Sub(t1) - serverSent.Sub(serverReceived)
+	result.Offset = (serverReceived.Sub(t1) + serverSent.Sub(t4)) / 2
+	if result.Offset > maxOffset || result.Offset < -maxOffset {
+		return &OffsetError{Offset: result.Offset, Max: maxOffset}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeNTP answer the requests sent to conn as an NTP server of stratum
+// whose clock is ahead of the local one by skew.
+func fakeNTP(conn net.PacketConn, stratum byte, skew time.Duration) {
+	req := make([]byte, 48)
+	for {
+		n, addr, err := conn.ReadFrom(req)
+		if err != nil {
+			return
+		}
+		if n != len(req) {
+			continue
+		}
+		resp := make([]byte, 48)
+		resp[0] = 4<<3 | ntpServer
+		resp[1] = stratum
+		copy(resp[24:32], req[40:48])
+		putNTPTime(resp[32:], time.Now().Add(skew))
+		putNTPTime(resp[40:], time.Now().Add(skew))
+		conn.WriteTo(resp, addr)
+	}
+}
+
+func TestCheckNTP(t *testing.T) {
+	tests := []struct {
+		stratum byte
+		skew    time.Duration
+		query   string
+		up      bool
+	}{
+		{stratum: 2, up: true},
+		{stratum: 2, skew: -5 * time.Second},
+		{stratum: 2, skew: 5 * time.Second, query: "?max-offset=10s", up: true},
+		{stratum: 16},
+		{stratum: 0},
+	}
+	c := newChecker(WithRetries(0), WithTimeout(time.Second))
+	for _, tt := range tests {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		go fakeNTP(conn, tt.stratum, tt.skew)
+
+		res := c.check(context.Background(), Service{URL: "ntp://" + conn.LocalAddr().String() + tt.query})
+		if res.Up() != tt.up {
+			t.Errorf("stratum %d, skew %s%s: got up %v, %v; want %v", tt.stratum, tt.skew, tt.query, res.Up(), res.Err, tt.up)
+		}
+		if offset := res.Offset - tt.skew; tt.stratum == 2 && (offset > 50*time.Millisecond || offset < -50*time.Millisecond) {
+			t.Errorf("skew %s: got offset %s", tt.skew, res.Offset)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go fakeNTP(conn, 1, time.Minute)
+	var offset *OffsetError
+	res := c.check(context.Background(), Service{URL: "ntp://" + conn.LocalAddr().String()})
+	if !errors.As(res.Err, &offset) || offset.Max != DefaultMaxNTPOffset || res.Stratum != 1 {
+		t.Errorf("got %+v", res)
+	}
+}
+
+func TestCheckNTPSourceIP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go fakeNTP(conn, 2, 0)
+	c := newChecker(WithRetries(0), WithSourceIP(net.ParseIP("127.0.0.1")))
+	if res := c.check(context.Background(), Service{URL: "ntp://" + conn.LocalAddr().String()}); !res.Up() {
+		t.Errorf("want up; got %v", res.Err)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults of the Opsgenie alerts of serve mode.
+const (
+	DefaultOpsgenieURL   = "https://api.opsgenie.com"
+	DefaultOpsgenieAfter = 2
+)
+
+// severityPriorities map the severities of services to the priorities of
+// their alerts, major being the default priority of Opsgenie.
+var severityPriorities = map[string]string{
+	SeverityCritical: "P1",
+	SeverityMajor:    "P3",
+	SeverityMinor:    "P5",
+}
+
+// opsgenieTimeout bound the time spent on each call to the Opsgenie API, so
+// that an unresponsive API does not hold back the handling of results.
+const opsgenieTimeout = 10 * time.Second
+
+// opsgenieMessageLimit is the maximum length of the message of an alert.
+const opsgenieMessageLimit = 130
+
+// opsgenie create an Opsgenie alert when a service has been failing for
+// after runs in a row, and close it once the service has been up for after
+// runs in a row, so that flapping services do not page. Alerts are keyed by
+// an alias per service. Services in maintenance or down because of a
+// dependency leave their alert as it is.
+type opsgenie struct {
+	baseURL string
+	key     string
+	after   int
+	timeout time.Duration
+
+	services map[string]*alertState
+}
+
+// alertState is the state of the alert of a service: whether the service
+// was failing on its last run, for how many runs in a row, and whether the
+// alert is open.
+type alertState struct {
+	failing bool
+	runs    int
+	open    bool
+}
+
+// newOpsgenie return alerts sent to the Opsgenie API at baseURL with the
+// API key of an integration.
+func newOpsgenie(baseURL, key string, after int) *opsgenie {
+	return &opsgenie{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		key:      key,
+		after:    max(after, 1),
+		timeout:  opsgenieTimeout,
+		services: make(map[string]*alertState),
+	}
+}
+
+// update create and close alerts from the results of a run. Alerts that
+// could not be created or closed are tried again on the next run, the
+// returned error joining the failures.
+func (o *opsgenie) update(ctx context.Context, results []Result) error {
+	var errs []error
+	for _, res := range results {
+		if res.Maintenance || res.DependencyDown() {
+			continue
+		}
+		key := resultKey(res)
+		s := o.services[key]
+		if s == nil {
+			s = &alertState{}
+			o.services[key] = s
+		}
+		if failing := res.Failed(); failing == s.failing {
+			s.runs++
+		} else {
+			s.failing, s.runs = failing, 1
+		}
+		if s.runs < o.after || s.failing == s.open {
+			continue
+		}
+		var err error
+		if s.failing {
+			err = o.create(ctx, res)
+		} else {
+			err = o.close(ctx, res)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.open = s.failing
+	}
+	return errors.Join(errs...)
+}
+
+// opsgenieAlert is the body of the request creating an alert.
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Source      string            `json:"source"`
+}
+
+// alertAlias return the alias of the alert of res.
+func alertAlias(res Result) string {
+	return "healthcheck:" + resultKey(res)
+}
+
+func (o *opsgenie) create(ctx context.Context, res Result) error {
+	message := resultKey(res) + " is down"
+	if len(message) > opsgenieMessageLimit {
+		// cut on a rune boundary, a split rune would make the JSON invalid UTF-8.
+		cut := opsgenieMessageLimit
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+	}
+	alert := opsgenieAlert{
+		Message:     message,
+		Alias:       alertAlias(res),
+		Description: errorString(res.Err),
+		Priority:    severityPriorities[severityOf(res)],
+		Tags:        res.Tags,
+		Details:     map[string]string{"url": res.Url, "error_code": string(ErrorCodeOf(res.Err))},
+		Source:      "healthcheck",
+	}
+	if res.Status != 0 {
+		alert.Details["status"] = strconv.Itoa(res.Status)
+	}
+	return o.post(ctx, "/v2/alerts", alert)
+}
+
+func (o *opsgenie) close(ctx context.Context, res Result) error {
+	path := "/v2/alerts/" + url.PathEscape(alertAlias(res)) + "/close?identifierType=alias"
+	return o.post(ctx, path, struct {
+		Source string `json:"source"`
+		Note   string `json:"note"`
+	}{"healthcheck", resultKey(res) + " is up again"})
+}
+
+// post send v as JSON to path of the API, within opsgenieTimeout.
+func (o *opsgenie) post(ctx context.Context, path string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("opsgenie: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("opsgenie: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
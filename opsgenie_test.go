@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpsgenie(t *testing.T) {
+	var (
+		requests []string
+		created  opsgenieAlert
+		fail     bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		requests = append(requests, r.URL.RequestURI())
+		if r.URL.Path == "/v2/alerts" {
+			json.NewDecoder(r.Body).Decode(&created)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	svc, err := ParseService("name=checkout url=https://checkout.a.com severity=critical #payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := Result{Name: svc.Name, Url: svc.URL, Tags: svc.Tags, Severity: svc.Severity, Status: 503, Err: errors.New("unexpected status 503")}
+	up := Result{Name: svc.Name, Url: svc.URL, Status: 200}
+	flapping := Result{Url: "https://b.com", Err: errors.New("timeout")}
+	dependency := Result{Url: "https://c.com", Err: &DependencyError{Dependency: "checkout", Err: down.Err}}
+
+	o := newOpsgenie(srv.URL+"/", "secret", 2)
+	runs := []struct {
+		results []Result
+		fail    bool
+		want    []string
+	}{
+		{[]Result{down, flapping, dependency}, false, nil},
+		{[]Result{down, {Url: "https://b.com"}, dependency}, true, []string{"/v2/alerts"}},
+		{[]Result{down, flapping, dependency}, false, []string{"/v2/alerts"}},
+		{[]Result{down, {Url: "https://b.com"}, dependency}, false, nil},
+		{[]Result{up}, false, nil},
+		{[]Result{up}, false, []string{"/v2/alerts/healthcheck:checkout/close?identifierType=alias"}},
+		{[]Result{up}, false, nil},
+	}
+	for i, run := range runs {
+		requests, fail = nil, run.fail
+		err := o.update(context.Background(), run.results)
+		if (err != nil) != run.fail {
+			t.Errorf("run %d: got %v", i, err)
+		}
+		if run.fail {
+			continue
+		}
+		if len(requests) != len(run.want) || len(requests) > 0 && requests[0] != run.want[0] {
+			t.Errorf("run %d: want %v; got %v", i, run.want, requests)
+		}
+	}
+	if created.Alias != "healthcheck:checkout" || created.Priority != "P1" || created.Description != "unexpected status 503" ||
+		created.Details["status"] != "503" || len(created.Tags) != 1 || created.Tags[0] != "payments" {
+		t.Errorf("unexpected alert %+v", created)
+	}
+
+	// Calls to an unresponsive API time out.
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer hung.Close()
+	defer close(release)
+	o = newOpsgenie(hung.URL, "secret", 1)
+	o.timeout = 10 * time.Millisecond
+	if err := o.update(context.Background(), []Result{down}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want a deadline exceeded; got %v", err)
+	}
+
+	// Long messages are cut on a rune boundary.
+	var long opsgenieAlert
+	capture := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&long)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer capture.Close()
+	name := "a" + strings.Repeat("é", opsgenieMessageLimit)
+	if err := newOpsgenie(capture.URL, "secret", 1).create(context.Background(), Result{Name: name, Err: down.Err}); err != nil {
+		t.Fatal(err)
+	}
+	if want := name[:opsgenieMessageLimit-1]; long.Message != want {
+		t.Errorf("want message %q; got %q", want, long.Message)
+	}
+
+	if _, err := ParseService("https://a.com severity=urgent"); err == nil {
+		t.Error("want an error for an unknown severity")
+	}
+}
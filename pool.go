@@ -0,0 +1,60 @@
+package main
+
+import "sync"
+
+// DefaultWorkers is the default number of concurrent checks.
+const DefaultWorkers = 64
+
+// WithWorkers set how many checks run concurrently.
+func WithWorkers(n int) Option {
+	return func(c *checker) { c.workers = max(n, 1) }
+}
+
+// job is a service to check, i being its position in the input.
+type job struct {
+	i   int
+	svc Service
+}
+
+// pool run check for every job received from jobs using c.workers
+// goroutines and pass each result to done, which is called concurrently. It
+// returns once jobs is closed and every job is done. With adaptive
+// concurrency, fewer checks may run at once, see WithAdaptiveConcurrency.
+//
+// Checks in flight hold at most the response body of their service, bounded
+// by WithMaxBodySize, so that the memory used beside the services and their
+// results grows with c.workers rather than with the number of jobs.
+func (c *checker) pool(jobs <-chan job, check func(Service) Result, done func(job, Result)) {
+	if c.adaptive {
+		limiter, unlimited := newAIMD(c.workers), check
+		check = func(svc Service) Result {
+			epoch := limiter.acquire()
+			res := unlimited(svc)
+			limiter.release(epoch, overloaded(res))
+			return res
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(c.workers)
+	for w := 0; w < c.workers; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				done(j, checkReportingPanics(check, j.svc))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// checkReportingPanics return check(svc), reporting its panics to Sentry
+// before letting them crash the program.
+func checkReportingPanics(check func(Service) Result, svc Service) Result {
+	defer func() {
+		if v := recover(); v != nil {
+			reportPanic(v, map[string]string{"service": svc.Name, "url": redactURL(svc.URL)})
+			panic(v)
+		}
+	}()
+	return check(svc)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultBodyPreview is the default number of bytes of the response body
+// of failed HTTP checks kept in their result.
+const DefaultBodyPreview = 512
+
+// WithBodyPreview keep the first n bytes of the response body of HTTP
+// checks failing or answering a 5xx status in the BodyPreview of their
+// result, so that the error page or message shows in reports. 0 disables
+// previews.
+func WithBodyPreview(n int) Option {
+	return func(c *checker) { c.bodyPreview = n }
+}
+
+// recordedBody keep the first bytes read from a response body, whoever
+// reads it, for previews and dumps of failures.
+type recordedBody struct {
+	io.ReadCloser
+	kept  []byte
+	limit int
+	eof   bool
+}
+
+// recordBody make the first limit bytes read from resp.Body kept.
+func recordBody(resp *http.Response, limit int) *recordedBody {
+	b := &recordedBody{ReadCloser: resp.Body, limit: limit}
+	resp.Body = b
+	return b
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if keep := min(n, b.limit-len(b.kept)); keep > 0 {
+		b.kept = append(b.kept, p[:keep]...)
+	}
+	b.eof = b.eof || err == io.EOF
+	return n, err
+}
+
+// readRest read the bytes up to the limit the check left unread.
+func (b *recordedBody) readRest() {
+	if !b.eof && len(b.kept) < b.limit {
+		io.CopyN(io.Discard, b, int64(b.limit-len(b.kept)))
+	}
+}
+
+// preview return the sanitized first n bytes of the body. Bodies with a
+// content encoding, left encoded when the checker sets the accepted
+// encodings, have no preview.
+func (b *recordedBody) preview(header http.Header, n int) string {
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return ""
+	}
+	kept := b.kept[:min(n, len(b.kept))]
+	if len(kept) == n {
+		// Leave out a rune cut by the limit.
+		for i := 1; i <= min(len(kept), utf8.UTFMax-1); i++ {
+			if utf8.RuneStart(kept[len(kept)-i]) {
+				if !utf8.FullRune(kept[len(kept)-i:]) {
+					kept = kept[:len(kept)-i]
+				}
+				break
+			}
+		}
+	}
+	return sanitizePreview(kept)
+}
+
+// sanitizePreview return body as a single line of printable text: invalid
+// UTF-8 is replaced, control characters dropped and runs of white space
+// collapsed, so that previews cannot mangle the terminal or the reports
+// they are printed in.
+func sanitizePreview(body []byte) string {
+	s := string(bytes.ToValidUTF8(body, []byte("�")))
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case !unicode.IsPrint(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyPreview(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte("all good"))
+		case "/maintenance":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html>\n\t<h1>Down for\x1b[31m maintenance</h1>\r\n</html>\n"))
+		case "/expected":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("boom"))
+		case "/long":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(strings.Repeat("a", 9) + "é" + strings.Repeat("b", 100)))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{srv.URL + "/ok", ""},
+		{srv.URL + "/maintenance", "<html> <h"},
+		{srv.URL + "/expected expect=500", "boom"},
+		{srv.URL + "/long", "aaaaaaaaa"},
+	}
+	c := newChecker(WithRetries(0), WithBodyPreview(10))
+	for _, test := range tests {
+		svc, err := ParseService(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.check(context.Background(), svc).BodyPreview; got != test.want {
+			t.Errorf("%s: got preview %q; want %q", test.line, got, test.want)
+		}
+	}
+
+	svc := Service{URL: srv.URL + "/maintenance"}
+	res := newChecker(WithRetries(0), WithBodyPreview(0)).check(context.Background(), svc)
+	if res.BodyPreview != "" {
+		t.Errorf("previews disabled: got %q", res.BodyPreview)
+	}
+	res = newChecker(WithRetries(0)).check(context.Background(), svc)
+	var b bytes.Buffer
+	writeText(&b, []Result{res})
+	if !strings.Contains(b.String(), "\n  Body: <html> <h1>Down for[31m maintenance</h1> </html>\n") {
+		t.Errorf("preview not written: %q", b.String())
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between two updates of the progress
+// line.
+const progressInterval = 100 * time.Millisecond
+
+// WithProgress make HealthCheck write a progress line to w, a terminal,
+// updated as checks complete and cleared once they are all done.
+func WithProgress(w io.Writer) Option {
+	return func(c *checker) { c.progress = w }
+}
+
+// progress track the checks done by a run and report them on a terminal.
+type progress struct {
+	w        io.Writer
+	now      func() time.Time
+	start    time.Time
+	lastDraw time.Time
+
+	mu     sync.Mutex
+	total  int
+	done   int
+	failed int
+}
+
+func newProgress(w io.Writer, total int, now func() time.Time) *progress {
+	return &progress{w: w, now: now, start: now(), total: total}
+}
+
+// add count a completed check and redraw the line, at most every
+// progressInterval except for the last check.
+func (p *progress) add(res Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if res.Failed() {
+		p.failed++
+	}
+	now := p.now()
+	if p.done < p.total && now.Sub(p.lastDraw) < progressInterval {
+		return
+	}
+	p.lastDraw = now
+	eta := time.Duration(float64(now.Sub(p.start)) / float64(p.done) * float64(p.total-p.done))
+	fmt.Fprintf(p.w, "\r\033[K%d/%d checked, %d failed, ETA %s", p.done, p.total, p.failed, eta.Round(time.Second))
+}
+
+// clear erase the progress line.
+func (p *progress) clear() {
+	io.WriteString(p.w, "\r\033[K")
+}
+
+// isTerminal report whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	now := time.Unix(0, 0)
+	var b strings.Builder
+	p := newProgress(&b, 4, func() time.Time { return now })
+
+	now = now.Add(time.Second)
+	p.add(Result{Status: 200})
+	now = now.Add(50 * time.Millisecond)
+	p.add(Result{Err: errors.New("down")}) // too soon, not drawn
+	now = now.Add(950 * time.Millisecond)
+	p.add(Result{Status: 200})
+	p.add(Result{Status: 200}) // last, always drawn
+	p.clear()
+
+	want := "\r\033[K1/4 checked, 0 failed, ETA 3s" +
+		"\r\033[K3/4 checked, 1 failed, ETA 1s" +
+		"\r\033[K4/4 checked, 1 failed, ETA 0s" +
+		"\r\033[K"
+	if got := b.String(); got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestHealthCheckProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var b strings.Builder
+	HealthCheck([]Service{{URL: srv.URL}, {URL: srv.URL}}, WithProgress(&b), WithWorkers(1))
+	if got := b.String(); !strings.Contains(got, "2/2 checked, 0 failed") || !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("want the final count then the line cleared; got %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// protocolCheck check a service of a non-HTTP protocol at u, returning an
+// error when it is down. What it measures is recorded in result, the
+// latency being that of the whole check unless set.
+type protocolCheck func(ctx context.Context, c *checker, u *url.URL, result *Result) error
+
+// protocolChecks map the url schemes of services of non-HTTP protocols to
+// their check.
+var protocolChecks = map[string]protocolCheck{
+	"postgres":   checkPostgres,
+	"postgresql": checkPostgres,
+	"mysql":      checkMySQL,
+	"redis":      checkRedis,
+	"rediss":     checkRedis,
+	"kafka":      checkKafka,
+	"amqp":       checkAMQP,
+	"amqps":      checkAMQP,
+	"s3":         checkS3,
+	"smtp":       checkSMTP,
+	"smtps":      checkSMTP,
+	"ssh":        checkSSH,
+	"ftp":        checkFTP,
+	"sftp":       checkSFTP,
+	"ntp":        checkNTP,
+	"ldap":       checkLDAP,
+	"ldaps":      checkLDAP,
+	"mqtt":       checkMQTT,
+	"mqtts":      checkMQTT,
+	"mqtt+ws":    checkMQTT,
+	"mqtt+wss":   checkMQTT,
+}
+
+// ProtocolError report the failed check of a service of a non-HTTP
+// protocol, named by the scheme of its url. Its message is that of Err.
+type ProtocolError struct {
+	Protocol string
+	Err      error
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// protocolOf return the check of the scheme of rawURL, nil for HTTP and
+// unknown schemes.
+func protocolOf(rawURL string) protocolCheck {
+	scheme, _, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return nil
+	}
+	return protocolChecks[strings.ToLower(scheme)]
+}
+
+// checkProtocol check a service of a non-HTTP protocol, retrying failed
+// attempts like checkURL.
+func (c *checker) checkProtocol(ctx context.Context, svc Service) Result {
+	redacted := redactURL(svc.URL)
+	ctx, span := tracer.Start(ctx, "checkProtocol",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("url.full", redacted),
+			attribute.String("healthcheck.service", svc.Name),
+			attribute.StringSlice("healthcheck.tags", svc.Tags),
+		),
+	)
+	defer span.End()
+
+	if svc.inMaintenance(c.now()) {
+		result := Result{Name: svc.Name, Url: redacted, Tags: svc.Tags, Maintenance: true}
+		recordResult(ctx, span, result)
+		return result
+	}
+	result := c.retry(ctx, span, svc, c.protocolAttempt)
+	recordResult(ctx, span, result)
+	return result
+}
+
+// protocolAttempt perform a single check of a service of a non-HTTP
+// protocol.
+func (c *checker) protocolAttempt(ctx context.Context, svc Service) Result {
+	result := Result{Name: svc.Name, Url: redactURL(svc.URL), Tags: svc.Tags}
+	u, err := url.Parse(svc.URL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	scheme := strings.ToLower(u.Scheme)
+	check := protocolChecks[scheme]
+
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+	start := time.Now()
+	if err := check(ctx, c, u, &result); err != nil {
+		result.Err = &ProtocolError{Protocol: scheme, Err: err}
+	}
+	if result.Latency == 0 {
+		result.Latency = time.Since(start)
+	}
+	return result
+}
+
+// dial connect to addr from the source address of c, the connection
+// having the deadline of ctx so that protocols without context support are
+// bounded by the timeout of the check too.
+func (c *checker) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := newDialer(c)
+	if c.sourceIP != nil && strings.HasPrefix(network, "udp") {
+		d.LocalAddr = &net.UDPAddr{IP: c.sourceIP}
+	}
+	conn, err := c.dialContext(ctx, d, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// tlsConfig return the TLS configuration of connections to serverName, with
+// the settings of the transport of c.
+func (c *checker) tlsConfig(serverName string) *tls.Config {
+	cfg := new(tls.Config)
+	if c.transport.TLSClientConfig != nil {
+		cfg = c.transport.TLSClientConfig.Clone()
+	}
+	cfg.ServerName = serverName
+	return cfg
+}
+
+// hostPort return the address of u, with port when it has none.
+func hostPort(u *url.URL, port string) string {
+	if p := u.Port(); p != "" {
+		port = p
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// redactURL return rawURL with its password replaced by xxxxx.
+func redactURL(rawURL string) string {
+	if !strings.Contains(rawURL, "@") {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
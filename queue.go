@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultQueueSize is the default number of reports an agent queues.
+const DefaultQueueSize = 1000
+
+// queueExt is the extension of the files of queued reports.
+const queueExt = ".json"
+
+// reportQueue is a queue of JSON reports on disk, a file per report in dir
+// named by a sequence number so that they sort oldest first, surviving
+// restarts of the agent. At most max reports are kept, the oldest being
+// dropped. It is not safe for concurrent use.
+type reportQueue struct {
+	dir  string
+	max  int
+	next uint64
+}
+
+// openReportQueue open the queue in dir, creating it if needed.
+func openReportQueue(dir string, max int) (*reportQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	q := &reportQueue{dir: dir, max: max}
+	names, err := q.pending()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		last, _ := strconv.ParseUint(strings.TrimSuffix(names[len(names)-1], queueExt), 10, 64)
+		q.next = last + 1
+	}
+	return q, nil
+}
+
+// pending return the names of the queued reports, oldest first.
+func (q *reportQueue) pending() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		seq := strings.TrimSuffix(e.Name(), queueExt)
+		if _, err := strconv.ParseUint(seq, 10, 64); err == nil && seq != e.Name() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// push queue report and return the number of reports dropped to keep at
+// most q.max. The report is written to a temporary file first so that no
+// partial report is ever queued.
+func (q *reportQueue) push(report []byte) (dropped int, err error) {
+	name := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.next, queueExt))
+	if err := os.WriteFile(name+".tmp", report, 0o600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return 0, err
+	}
+	q.next++
+	names, err := q.pending()
+	if err != nil {
+		return 0, err
+	}
+	for _, old := range names[:max(len(names)-q.max, 0)] {
+		if err := os.Remove(filepath.Join(q.dir, old)); err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+	return dropped, nil
+}
+
+// flush send the queued reports, oldest first, in batches of at most
+// maxReportBody bytes, a JSON array of reports, and remove them once send
+// succeeds. Batches rejected by the aggregator, see rejectedReportError,
+// are sent again a report at a time and the reports rejected alone are
+// dropped, as they would never be accepted; their errors are returned once
+// the other reports are sent. flush stops at the first other error, the
+// remaining reports being sent by the next flush.
+func (q *reportQueue) flush(send func(batch []byte) error) error {
+	names, err := q.pending()
+	if err != nil {
+		return err
+	}
+	var (
+		rejected []error
+		alone    int // reports to send a report at a time
+	)
+	for len(names) > 0 {
+		var (
+			batch bytes.Buffer
+			n     int
+		)
+		batch.WriteByte('[')
+		for ; n < len(names); n++ {
+			report, err := os.ReadFile(filepath.Join(q.dir, names[n]))
+			if err != nil {
+				return err
+			}
+			if n > 0 && (alone > 0 || batch.Len()+len(",")+len(report)+len("]") > maxReportBody) {
+				break
+			}
+			if n > 0 {
+				batch.WriteByte(',')
+			}
+			batch.Write(report)
+		}
+		batch.WriteByte(']')
+		var rejection *rejectedReportError
+		switch err := send(batch.Bytes()); {
+		case errors.As(err, &rejection) && n > 1:
+			alone = n
+			continue
+		case errors.As(err, &rejection):
+			rejected = append(rejected, fmt.Errorf("%s: dropped: %w", names[0], err))
+		case err != nil:
+			return errors.Join(append(rejected, err)...)
+		}
+		alone = max(alone-n, 0)
+		for _, name := range names[:n] {
+			if err := os.Remove(filepath.Join(q.dir, name)); err != nil {
+				return err
+			}
+		}
+		names = names[n:]
+	}
+	return errors.Join(rejected...)
+}
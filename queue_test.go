@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := openReportQueue(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		body, _ := json.Marshal(newAgentReport("eu", []Result{{Url: "https://a.com", Status: 200 + i}}, at.Add(time.Duration(i)*time.Minute)))
+		dropped, err := q.push(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(i/3, 1); dropped != want {
+			t.Errorf("push %d: got %d dropped, want %d", i, dropped, want)
+		}
+	}
+
+	// Failed sends keep the reports queued, across restarts.
+	if err := q.flush(func([]byte) error { return errors.New("unreachable") }); err == nil {
+		t.Error("want the error of send")
+	}
+	if q, err = openReportQueue(dir, 3); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(newAgentReport("eu", nil, at.Add(time.Hour)))
+	if _, err := q.push(body); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := q.pending(); len(names) != 3 || names[2] != fmt.Sprintf("%020d.json", 4) {
+		t.Errorf("after a restart: got %v", names)
+	}
+
+	s := newServer(newChecker(), 10)
+	s.openReports = true
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+	var batches int
+	err = q.flush(func(batch []byte) error {
+		batches++
+		return postReport(context.Background(), srv.URL, "", batch)
+	})
+	if err != nil || batches != 1 {
+		t.Fatalf("got %d batches, %v", batches, err)
+	}
+	if names, _ := q.pending(); len(names) != 0 {
+		t.Errorf("want an empty queue; got %v", names)
+	}
+	// Reports are added oldest first, the last one being kept.
+	if got := s.agents.reports["eu"]; !got.Time.Equal(at.Add(time.Hour)) {
+		t.Errorf("want the last report; got %v", got.Time)
+	}
+	s.agents.add(agentReport{Agent: "eu", Time: at})
+	if got := s.agents.reports["eu"]; !got.Time.Equal(at.Add(time.Hour)) {
+		t.Errorf("want older reports not to replace the last one; got %v", got.Time)
+	}
+	// They are kept in the history of the agent, in the order of their time.
+	resp, err := http.Get(srv.URL + "/agents/eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history struct{ Reports []agentReport }
+	err = json.NewDecoder(resp.Body).Decode(&history)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times []time.Time
+	for _, r := range history.Reports {
+		times = append(times, r.Time)
+	}
+	if want := []time.Time{at, at.Add(2 * time.Minute), at.Add(3 * time.Minute), at.Add(time.Hour)}; !reflect.DeepEqual(times, want) {
+		t.Errorf("got the history %v; want %v", times, want)
+	}
+
+	// Reports rejected by the aggregator are dropped, the others sent.
+	for _, agent := range []string{"eu", "", "us"} {
+		body, _ := json.Marshal(newAgentReport(agent, nil, at.Add(2*time.Hour)))
+		if _, err := q.push(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batches = 0
+	err = q.flush(func(batch []byte) error {
+		batches++
+		return postReport(context.Background(), srv.URL, "", batch)
+	})
+	if err == nil || !strings.Contains(err.Error(), "report without agent") || batches != 4 {
+		t.Errorf("want the rejection of the report without agent after 4 batches; got %d, %v", batches, err)
+	}
+	if names, _ := q.pending(); len(names) != 0 {
+		t.Errorf("want an empty queue; got %v", names)
+	}
+	if _, ok := s.agents.reports["us"]; !ok {
+		t.Error("want the report after the rejected one received")
+	}
+
+	resp, err = http.Post(srv.URL+"/report", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("empty report: got %d", resp.StatusCode)
+	}
+}
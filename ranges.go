@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// byteRange is the single byte range requested to check a service: bytes
+// Start to End included, to the end of the content when End is -1, or the
+// last End bytes when Start is -1.
+type byteRange struct {
+	Start int64
+	End   int64
+}
+
+// parseByteRange parse a range like 0-1023, 1024- or -512.
+func parseByteRange(s string) (*byteRange, error) {
+	start, end, ok := strings.Cut(strings.TrimPrefix(s, "bytes="), "-")
+	if !ok || start == "" && end == "" {
+		return nil, fmt.Errorf("want start-end, start- or -suffix, got %q", s)
+	}
+	r := &byteRange{Start: -1, End: -1}
+	for _, f := range []struct {
+		s string
+		n *int64
+	}{{start, &r.Start}, {end, &r.End}} {
+		if f.s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(f.s, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid range %q", s)
+		}
+		*f.n = n
+	}
+	if r.Start >= 0 && r.End >= 0 && r.End < r.Start || r.Start < 0 && r.End == 0 {
+		return nil, fmt.Errorf("invalid range %q", s)
+	}
+	return r, nil
+}
+
+// String return the value of the Range header requesting r.
+func (r *byteRange) String() string {
+	switch {
+	case r.Start < 0:
+		return fmt.Sprintf("bytes=-%d", r.End)
+	case r.End < 0:
+		return fmt.Sprintf("bytes=%d-", r.Start)
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Start, r.End)
+}
+
+// RangeError report a response which is not the partial content of the
+// requested range.
+type RangeError struct {
+	Range  string
+	Reason string
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("range %s: %s", e.Range, e.Reason)
+}
+
+// check return an error unless resp has the 206 status, or one of expect,
+// and holds the part of the content r asks for, as described by its
+// Content-Range header.
+func (r *byteRange) check(resp *http.Response, expect []int) error {
+	if len(expect) == 0 && resp.StatusCode != http.StatusPartialContent {
+		return &StatusError{Status: resp.StatusCode, Expect: []int{http.StatusPartialContent}}
+	}
+	if len(expect) > 0 && !slices.Contains(expect, resp.StatusCode) {
+		return &StatusError{Status: resp.StatusCode, Expect: expect}
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil
+	}
+	invalid := func(format string, args ...any) error {
+		return &RangeError{Range: r.String(), Reason: fmt.Sprintf(format, args...)}
+	}
+
+	contentRange := resp.Header.Get("Content-Range")
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil || end < start {
+		return invalid("invalid Content-Range %q", contentRange)
+	}
+	size := int64(-1)
+	if total != "*" {
+		n, err := strconv.ParseInt(total, 10, 64)
+		if err != nil || n <= end {
+			return invalid("invalid Content-Range %q", contentRange)
+		}
+		size = n
+	}
+	// Ranges past the end of the content are cut to it.
+	last := size - 1
+	switch {
+	case r.Start < 0:
+		if size >= 0 && (end != last || end-start+1 != min(r.End, size)) {
+			return invalid("got Content-Range %q", contentRange)
+		}
+	case start != r.Start,
+		r.End >= 0 && end != r.End && (size < 0 || end != last || r.End < last),
+		r.End < 0 && size >= 0 && end != last:
+		return invalid("got Content-Range %q", contentRange)
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return err
+	}
+	if want := end - start + 1; n != want {
+		return invalid("got %d bytes, want %d", n, want)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 300)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ignored":
+			w.Write([]byte(content))
+		case "/short":
+			w.Header().Set("Content-Range", "bytes 0-1023/3000")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(content[:100]))
+		default:
+			http.ServeContent(w, r, "app.tar.gz", time.Time{}, strings.NewReader(content))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{line: "/ range=0-1023"},
+		{line: "/ range=2000-"},
+		{line: "/ range=-512"},
+		{line: "/ range=2500-9999"},
+		{line: "/ range=-5000"},
+		{line: "/ range=5000-6000", err: "unexpected status 416, want 206"},
+		{line: "/ignored range=0-1023", err: "unexpected status 200, want 206"},
+		{line: "/ignored range=0-1023 expect=200,206"},
+		{line: "/short range=0-1023", err: "range bytes=0-1023: got 100 bytes, want 1024"},
+		{line: "/short range=0-99", err: `range bytes=0-99: got Content-Range "bytes 0-1023/3000"`},
+	}
+	c := newChecker(WithRetries(0))
+	for _, tt := range tests {
+		svc, err := ParseService(srv.URL + tt.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := c.check(context.Background(), svc)
+		if tt.err == "" && !res.Up() || tt.err != "" && (res.Err == nil || res.Err.Error() != tt.err) {
+			t.Errorf("%s: want error %q; got %v", tt.line, tt.err, res.Err)
+		}
+	}
+	svc, _ := ParseService(srv.URL + "/short range=0-1023")
+	var rerr *RangeError
+	if res := c.check(context.Background(), svc); !errors.As(res.Err, &rerr) {
+		t.Errorf("want a RangeError; got %v", res.Err)
+	}
+
+	// Downloads keep only the first maxProbeBody bytes and would cut the
+	// larger ranges.
+	large := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "big.bin", time.Time{}, strings.NewReader(strings.Repeat("x", maxProbeBody+1024)))
+	}))
+	defer large.Close()
+	svc, _ = ParseService(large.URL + " range=0-")
+	if res := newChecker(WithRetries(0), WithDownload(1<<20)).check(context.Background(), svc); !res.Up() {
+		t.Errorf("range with downloads: %v", res.Err)
+	}
+
+	for _, value := range []string{"", "-", "a-b", "10-5", "-0", "1-2,5-6"} {
+		if _, err := ParseService("https://a.com range=" + value); err == nil {
+			t.Errorf("range=%s: want an error", value)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultRDAPURL is the RDAP service domains are looked up with, which
+// redirects to the registry of their top-level domain.
+const DefaultRDAPURL = "https://rdap.org/"
+
+// rdapTTL is how long the expiry of a domain is kept before being looked up
+// again, registrations changing rarely.
+const rdapTTL = 12 * time.Hour
+
+// WithDomainExpiry warn, with the results of the checks, of domains whose
+// registration expires within window, as found with RDAP. Warnings are
+// reported as audit findings and do not fail the checks.
+func WithDomainExpiry(window time.Duration) Option {
+	return func(c *checker) { c.domainExpiry = window }
+}
+
+// rdapDomain is the part of an RDAP domain object used: its events, one of
+// which is the expiration of the registration.
+type rdapDomain struct {
+	Events []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+}
+
+// rdapExpiry is the expiry of a domain as looked up at a time.
+type rdapExpiry struct {
+	expires time.Time
+	err     error
+	fetched time.Time
+}
+
+// rdapClient look up the expiry of domains, caching them for rdapTTL.
+type rdapClient struct {
+	base string
+
+	mu    sync.Mutex
+	cache map[string]rdapExpiry
+}
+
+func newRDAPClient(base string) *rdapClient {
+	return &rdapClient{base: base, cache: make(map[string]rdapExpiry)}
+}
+
+// expiry return the expiration date of the registration of domain.
+func (r *rdapClient) expiry(ctx context.Context, client *http.Client, domain string, now time.Time) (time.Time, error) {
+	r.mu.Lock()
+	e, ok := r.cache[domain]
+	r.mu.Unlock()
+	if ok && now.Sub(e.fetched) < rdapTTL {
+		return e.expires, e.err
+	}
+
+	e = rdapExpiry{fetched: now}
+	e.expires, e.err = r.lookup(ctx, client, domain)
+	r.mu.Lock()
+	r.cache[domain] = e
+	r.mu.Unlock()
+	return e.expires, e.err
+}
+
+func (r *rdapClient) lookup(ctx context.Context, client *http.Client, domain string) (time.Time, error) {
+	u := strings.TrimSuffix(r.base, "/") + "/domain/" + url.PathEscape(domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, &StatusError{Status: resp.StatusCode}
+	}
+
+	var d rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return time.Time{}, fmt.Errorf("rdap response: %w", err)
+	}
+	for _, e := range d.Events {
+		if e.Action == "expiration" {
+			return e.Date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no expiration event")
+}
+
+// registeredDomain return the domain registered for the host of rawURL,
+// such as a.co.uk for www.a.co.uk, empty for ip addresses.
+func registeredDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return ""
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimSuffix(u.Hostname(), "."))
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// checkDomainExpiry add to the findings of the result of svc a warning
+// when the registration of its domain expires within c.domainExpiry, or
+// could not be looked up.
+func (c *checker) checkDomainExpiry(ctx context.Context, svc Service, result Result) Result {
+	domain := registeredDomain(svc.URL)
+	if c.domainExpiry <= 0 || domain == "" {
+		return result
+	}
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+	now := c.now()
+	expires, err := c.rdap.expiry(ctx, c.client, domain, now)
+	switch left := expires.Sub(now); {
+	case err != nil:
+		result.Audit = append(result.Audit, fmt.Sprintf("domain %s: expiry unknown: %v", domain, err))
+	case left <= 0:
+		result.Audit = append(result.Audit, fmt.Sprintf("domain %s expired on %s", domain, expires.Format(time.DateOnly)))
+	case left < c.domainExpiry:
+		result.Audit = append(result.Audit, fmt.Sprintf("domain %s expires in %d days, on %s",
+			domain, int(left.Hours()/24), expires.Format(time.DateOnly)))
+	}
+	return result
+}
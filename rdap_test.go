@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDomainExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	expiries := map[string]string{
+		"soon.com":  "2026-10-13T00:00:00Z",
+		"later.com": "2027-10-01T00:00:00Z",
+		"gone.com":  "2026-09-01T00:00:00Z",
+	}
+	lookups := 0
+	rdap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		date, ok := expiries[r.URL.Path[len("/domain/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"events": [{"eventAction": "registration", "eventDate": "2020-01-01T00:00:00Z"}, {"eventAction": "expiration", "eventDate": %q}]}`, date)
+	}))
+	defer rdap.Close()
+
+	c := newChecker(WithDomainExpiry(30 * 24 * time.Hour))
+	c.now = func() time.Time { return now }
+	c.rdap = newRDAPClient(rdap.URL)
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{url: "https://www.soon.com/health", want: []string{"domain soon.com expires in 12 days, on 2026-10-13"}},
+		{url: "https://api.soon.com/", want: []string{"domain soon.com expires in 12 days, on 2026-10-13"}},
+		{url: "https://later.com/"},
+		{url: "https://gone.com/", want: []string{"domain gone.com expired on 2026-09-01"}},
+		{url: "https://unknown.com/", want: []string{"domain unknown.com: expiry unknown: unexpected status 404"}},
+		{url: "https://203.0.113.10/"},
+	}
+	for _, tt := range tests {
+		res := c.checkDomainExpiry(context.Background(), Service{URL: tt.url}, Result{})
+		if !reflect.DeepEqual(res.Audit, tt.want) {
+			t.Errorf("%s: got %q; want %q", tt.url, res.Audit, tt.want)
+		}
+	}
+	if lookups != 4 {
+		t.Errorf("got %d lookups; want 4, one per domain", lookups)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RedisError report an error reply of a Redis server.
+type RedisError struct {
+	Command string
+	Reply   string
+}
+
+func (e *RedisError) Error() string {
+	return fmt.Sprintf("redis %s: %s", e.Command, e.Reply)
+}
+
+// checkRedis check the Redis server of a redis://[user:password@]host:port
+// url, rediss:// for TLS: it authenticates when the url has a password,
+// then sends PING. The latency of the result is the round trip of PING.
+func checkRedis(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	conn, err := c.dial(ctx, "tcp", hostPort(u, "6379"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if u.Scheme == "rediss" {
+		tlsConn := tls.Client(conn, c.tlsConfig(u.Hostname()))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		conn = tlsConn
+	}
+
+	r := bufio.NewReader(conn)
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := redisCommand(conn, r, args...); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	reply, err := redisCommand(conn, r, "PING")
+	result.Latency = time.Since(start)
+	if err == nil && reply != "PONG" {
+		err = &RedisError{Command: "PING", Reply: reply}
+	}
+	return err
+}
+
+// redisCommand send a command with args to conn and return its simple
+// string reply, read from r.
+func redisCommand(conn net.Conn, r *bufio.Reader, args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch {
+	case strings.HasPrefix(line, "+"):
+		return line[1:], nil
+	case strings.HasPrefix(line, "-"):
+		return "", &RedisError{Command: args[0], Reply: line[1:]}
+	}
+	return "", fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRedis serve on l the commands of Redis clients, requiring AUTH with
+// password when set.
+func fakeRedis(l net.Listener, password string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			authenticated := password == ""
+			for {
+				var args []string
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				for i := 0; i < n; i++ {
+					r.ReadString('\n')
+					arg, _ := r.ReadString('\n')
+					args = append(args, strings.TrimSpace(arg))
+				}
+				switch {
+				case args[0] == "AUTH" && args[len(args)-1] == password:
+					authenticated = true
+					fmt.Fprint(conn, "+OK\r\n")
+				case args[0] == "AUTH":
+					fmt.Fprint(conn, "-WRONGPASS invalid username-password pair\r\n")
+				case !authenticated:
+					fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+				case args[0] == "PING":
+					fmt.Fprint(conn, "+PONG\r\n")
+				}
+			}
+		}()
+	}
+}
+
+func TestCheckRedis(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeRedis(l, "s3cret")
+
+	c := newChecker(WithRetries(0))
+	tests := []struct {
+		userinfo string
+		reply    string
+	}{
+		{userinfo: ":s3cret@"},
+		{userinfo: "default:s3cret@"},
+		{userinfo: ":wrong@", reply: "WRONGPASS invalid username-password pair"},
+		{userinfo: "", reply: "NOAUTH Authentication required."},
+	}
+	for _, tt := range tests {
+		res := c.check(context.Background(), Service{URL: "redis://" + tt.userinfo + l.Addr().String()})
+		var rerr *RedisError
+		switch {
+		case tt.reply == "" && (!res.Up() || res.Latency <= 0):
+			t.Errorf("%q: want up; got %v", tt.userinfo, res.Err)
+		case tt.reply != "" && (!errors.As(res.Err, &rerr) || rerr.Reply != tt.reply):
+			t.Errorf("%q: want %q; got %v", tt.userinfo, tt.reply, res.Err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// reloadOnHangup replace the services of listed by those returned by load
+// every time the process receives SIGHUP, until ctx is done. Changes are
+// logged to w; a failed reload is logged too and keeps the services as
+// they were. Runs in progress go on with the services they started with.
+func reloadOnHangup(ctx context.Context, w io.Writer, listed *atomic.Pointer[[]Service], load func() ([]Service, error)) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			reload(w, listed, load)
+		}
+	}
+}
+
+// reload replace the services of listed by those returned by load, logging
+// the services added and removed to w.
+func reload(w io.Writer, listed *atomic.Pointer[[]Service], load func() ([]Service, error)) {
+	services, err := load()
+	if err != nil {
+		fmt.Fprintf(w, "reload failed, keeping the current services: %s\n", err)
+		return
+	}
+	added, removed := diffServices(*listed.Load(), services)
+	listed.Store(&services)
+	fmt.Fprintf(w, "reloaded %d services: %d added, %d removed\n", len(services), len(added), len(removed))
+	for _, key := range added {
+		fmt.Fprintf(w, "+ %s\n", key)
+	}
+	for _, key := range removed {
+		fmt.Fprintf(w, "- %s\n", key)
+	}
+}
+
+// diffServices return the keys of the services of next missing from prev,
+// and those of prev missing from next, in order.
+func diffServices(prev, next []Service) (added, removed []string) {
+	keys := func(services []Service) map[string]bool {
+		m := make(map[string]bool, len(services))
+		for _, svc := range services {
+			m[svc.key()] = true
+		}
+		return m
+	}
+	prevKeys, nextKeys := keys(prev), keys(next)
+	for _, svc := range next {
+		if k := svc.key(); !prevKeys[k] {
+			added = append(added, k)
+			prevKeys[k] = true
+		}
+	}
+	for _, svc := range prev {
+		if k := svc.key(); !nextKeys[k] {
+			removed = append(removed, k)
+			nextKeys[k] = true
+		}
+	}
+	return added, removed
+}
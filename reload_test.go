@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestDiffServices(t *testing.T) {
+	prev := []Service{{URL: "https://a.com"}, {Name: "b", URL: "https://b.com"}, {URL: "https://c.com"}}
+	next := []Service{{URL: "https://a.com"}, {Name: "b", URL: "https://b2.com"}, {URL: "https://d.com"}, {URL: "https://d.com"}}
+	added, removed := diffServices(prev, next)
+	if !slices.Equal(added, []string{"https://d.com"}) {
+		t.Errorf("added: got %q", added)
+	}
+	if !slices.Equal(removed, []string{"https://c.com"}) {
+		t.Errorf("removed: got %q", removed)
+	}
+}
+
+func TestReload(t *testing.T) {
+	services := []Service{{URL: "https://a.com"}, {URL: "https://b.com"}}
+	var listed atomic.Pointer[[]Service]
+	listed.Store(&services)
+
+	var log bytes.Buffer
+	reload(&log, &listed, func() ([]Service, error) { return nil, errors.New("boom") })
+	if len(*listed.Load()) != 2 || !strings.Contains(log.String(), "boom") {
+		t.Errorf("failed reload: got %d services, log %q", len(*listed.Load()), log.String())
+	}
+
+	log.Reset()
+	reload(&log, &listed, func() ([]Service, error) {
+		return []Service{{URL: "https://a.com"}, {URL: "https://c.com"}}, nil
+	})
+	if got := *listed.Load(); len(got) != 2 || got[1].URL != "https://c.com" {
+		t.Errorf("got %v", got)
+	}
+	want := "reloaded 2 services: 1 added, 1 removed\n+ https://c.com\n- https://b.com\n"
+	if log.String() != want {
+		t.Errorf("log: want %q; got %q", want, log.String())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteTimeout bound the time spent pushing to the remote_write
+// endpoint so a slow Prometheus never delays the end of a run.
+const remoteWriteTimeout = 10 * time.Second
+
+// remoteWriteSeries are the series of a result pushed with Prometheus
+// remote_write, each with the labels name, when set, and url.
+//
+//	healthcheck_up               1 when up, 0 when down
+//	healthcheck_maintenance      1 for services in a maintenance window
+//	healthcheck_latency_seconds  latency of the check
+//	healthcheck_status_code      HTTP status of the response
+//
+// The series healthcheck_health_score, without labels, is the health score
+// of the run, see tally.
+type remoteWriteSeries struct {
+	labels [][2]string
+	value  float64
+}
+
+// remoteWriteMetrics return the series of results and of the members of
+// composite services, sorted labels first by name as Prometheus requires.
+func remoteWriteMetrics(results []Result) []remoteWriteSeries {
+	var series []remoteWriteSeries
+	add := func(metric string, res Result, value float64) {
+		labels := [][2]string{{"__name__", metric}}
+		if res.Name != "" {
+			labels = append(labels, [2]string{"name", res.Name})
+		}
+		if res.Url != "" {
+			labels = append(labels, [2]string{"url", res.Url})
+		}
+		series = append(series, remoteWriteSeries{labels: labels, value: value})
+	}
+	var addResult func(res Result)
+	addResult = func(res Result) {
+		if res.Maintenance {
+			add("healthcheck_maintenance", res, 1)
+		} else {
+			up := 0.
+			if res.Up() {
+				up = 1
+			}
+			add("healthcheck_up", res, up)
+		}
+		if res.Status != 0 || len(res.Members) > 0 && res.Err == nil {
+			add("healthcheck_latency_seconds", res, res.Latency.Seconds())
+		}
+		if res.Status != 0 {
+			add("healthcheck_status_code", res, float64(res.Status))
+		}
+		for _, member := range res.Members {
+			addResult(member)
+		}
+	}
+	for _, res := range results {
+		addResult(res)
+	}
+	if score := summarize(results, 0).Total.HealthScore; score != nil {
+		series = append(series, remoteWriteSeries{labels: [][2]string{{"__name__", "healthcheck_health_score"}}, value: *score})
+	}
+	return series
+}
+
+// encodeWriteRequest return the WriteRequest protobuf message of series
+// sampled at ts.
+func encodeWriteRequest(series []remoteWriteSeries, ts time.Time) []byte {
+	var b, timeSeries, msg []byte
+	for _, s := range series {
+		timeSeries = timeSeries[:0]
+		for _, label := range s.labels {
+			msg = msg[:0]
+			msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+			msg = protowire.AppendString(msg, label[0])
+			msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+			msg = protowire.AppendString(msg, label[1])
+			timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, msg)
+		}
+		msg = msg[:0]
+		msg = protowire.AppendTag(msg, 1, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, math.Float64bits(s.value))
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(ts.UnixMilli()))
+		timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, msg)
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, timeSeries)
+	}
+	return b
+}
+
+// snappyEncode return src in the snappy block format remote_write requires,
+// as literals only: the payloads are small and this avoids a dependency
+// for a compression that would save little.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		if n <= 60 {
+			dst = append(dst, byte(n-1)<<2)
+		} else {
+			// Tag 61: the length minus one follows on 2 bytes.
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
+
+// pushRemoteWrite send results sampled at ts to a Prometheus remote_write
+// endpoint such as https://prometheus.a.com/api/v1/write. The tenant, when
+// not empty, is sent in the X-Scope-OrgID header of Mimir and Cortex, and
+// credentials in the userinfo of endpoint with basic authentication, as
+// Grafana Cloud expects.
+func pushRemoteWrite(ctx context.Context, endpoint, tenant string, results []Result, ts time.Time) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	user := u.User
+	u.User = nil
+	body := snappyEncode(encodeWriteRequest(remoteWriteMetrics(results), ts))
+
+	ctx, cancel := context.WithTimeout(ctx, remoteWriteTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
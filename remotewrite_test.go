@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// snappyDecodeLiterals decode the snappy blocks of snappyEncode, made of
+// literals only.
+func snappyDecodeLiterals(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	src = src[n:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		var length int
+		switch {
+		case tag&3 != 0:
+			return nil, errors.New("not a literal")
+		case tag>>2 < 60:
+			length, src = int(tag>>2)+1, src[1:]
+		case tag>>2 == 61:
+			length, src = int(src[1])|int(src[2])<<8+1, src[3:]
+		default:
+			return nil, errors.New("unexpected literal tag")
+		}
+		dst, src = append(dst, src[:length]...), src[length:]
+	}
+	if len(dst) != int(size) {
+		return nil, errors.New("length mismatch")
+	}
+	return dst, nil
+}
+
+// fields return the fields of the protobuf message b by number, bytes for
+// length delimited ones and uint64 for the others.
+func fields(t *testing.T, b []byte) map[protowire.Number][]any {
+	t.Helper()
+	m := make(map[protowire.Number][]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m[num], b = append(m[num], v), b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			m[num], b = append(m[num], v), b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m[num], b = append(m[num], v), b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+	}
+	return m
+}
+
+func TestPushRemoteWrite(t *testing.T) {
+	var (
+		body   []byte
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	at := time.Unix(1700000000, 0)
+	results := []Result{
+		{Name: "api", Url: "https://api.a.com", Status: 200, Latency: 250 * time.Millisecond},
+		{Url: "https://b.com", Err: errors.New("timeout")},
+	}
+	endpoint := strings.Replace(srv.URL, "http://", "http://123:key@", 1) + "/api/v1/write"
+	if err := pushRemoteWrite(context.Background(), endpoint, "team-a", results, at); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Content-Encoding") != "snappy" || header.Get("X-Scope-OrgID") != "team-a" {
+		t.Errorf("unexpected headers %v", header)
+	}
+	if user, password, ok := (&http.Request{Header: header}).BasicAuth(); !ok || user != "123" || password != "key" {
+		t.Errorf("want the basic auth of the userinfo; got %q %q", user, password)
+	}
+
+	message, err := snappyDecodeLiterals(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, ts := range fields(t, message)[1] {
+		series := fields(t, ts.([]byte))
+		var labels []string
+		for _, l := range series[1] {
+			label := fields(t, l.([]byte))
+			labels = append(labels, string(label[1][0].([]byte))+"="+string(label[2][0].([]byte)))
+		}
+		sample := fields(t, series[2][0].([]byte))
+		if ms := sample[2][0].(uint64); ms != uint64(at.UnixMilli()) {
+			t.Errorf("%v: got timestamp %d", labels, ms)
+		}
+		got[strings.Join(labels, ",")] = math.Float64frombits(sample[1][0].(uint64))
+	}
+	want := map[string]float64{
+		"__name__=healthcheck_up,name=api,url=https://api.a.com":              1,
+		"__name__=healthcheck_latency_seconds,name=api,url=https://api.a.com": 0.25,
+		"__name__=healthcheck_status_code,name=api,url=https://api.a.com":     200,
+		"__name__=healthcheck_up,url=https://b.com":                           0,
+		"__name__=healthcheck_health_score":                                   50,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v", got)
+	}
+	for series, v := range want {
+		if got[series] != v {
+			t.Errorf("%s: got %v, want %v", series, got[series], v)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := pushRemoteWrite(context.Background(), failing.URL, "", results, at); err == nil || !strings.Contains(err.Error(), "out of order") {
+		t.Errorf("want the error of the endpoint; got %v", err)
+	}
+}
+
+func TestSnappyEncodeLong(t *testing.T) {
+	src := []byte(strings.Repeat("healthcheck", 10000))
+	got, err := snappyDecodeLiterals(snappyEncode(src))
+	if err != nil || string(got) != string(src) {
+		t.Errorf("round trip failed: %v", err)
+	}
+}
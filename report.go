@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the report subcommand.
+const (
+	DefaultReportSince = 30 * 24 * time.Hour
+	DefaultSLO         = 99.9
+	DefaultIncidents   = 5
+)
+
+// runReport implement the report subcommand, printing the uptime of the
+// services recorded in results files written by serve -out, their error
+// budget for an SLO and the worst incidents, and return the exit code:
+// exitFailed when a service exhausted its error budget.
+//
+// Rotated files of each results file are read too.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck report [flags] results.log...")
+		fs.PrintDefaults()
+	}
+	since := DefaultReportSince
+	fs.Func("since", "period reported, such as 30d or 12h (default 30d)", func(s string) error {
+		var err error
+		since, err = parseDays(s)
+		return err
+	})
+	slo := fs.Float64("slo", DefaultSLO, "availability objective in percent the error budgets are computed for")
+	incidents := fs.Int("incidents", DefaultIncidents, "number of worst incidents listed")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "missing results file argument")
+		return exitError
+	}
+	if *slo <= 0 || *slo >= 100 {
+		fmt.Fprintln(os.Stderr, "slo must be between 0 and 100 exclusive")
+		return exitError
+	}
+
+	from := time.Now().Add(-since)
+	var entries []historyEntry
+	for _, path := range fs.Args() {
+		e, err := readHistory(path, from)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		entries = append(entries, e...)
+	}
+	r := newSLOReport(entries, *slo)
+	fmt.Printf("Report since %s, SLO %g%%\n", from.Format(time.DateTime), *slo)
+	writeSLOReport(os.Stdout, r, *incidents)
+	for _, s := range r.services {
+		if s.budgetLeft() < 0 {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// parseDays parse a duration, accepting a number of days such as 30d.
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(n * 24 * float64(time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid duration %q", s)
+	}
+	return d, err
+}
+
+// historyEntry is a result read from a results file.
+type historyEntry struct {
+	Time  time.Time
+	Key   string
+	State string
+	Err   string
+}
+
+// readHistory read the results recorded at or after from in the results
+// file at path and in its rotated files. Malformed lines are skipped.
+func readHistory(path string, from time.Time) ([]historyEntry, error) {
+	paths, err := filepath.Glob(globEscape(path) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	for _, p := range append(paths, path) {
+		suffix := strings.TrimPrefix(p, path+".")
+		if _, err := time.Parse(rotateLayout, suffix); p != path && err != nil {
+			continue
+		}
+		e, err := readHistoryFile(p, from)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+func readHistoryFile(path string, from time.Time) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var j jsonResult
+		if err := json.Unmarshal(scanner.Bytes(), &j); err != nil || j.Time == nil || j.Time.Before(from) {
+			continue
+		}
+		key := j.Name
+		if key == "" {
+			key = j.URL
+		}
+		entries = append(entries, historyEntry{Time: *j.Time, Key: key, State: j.State, Err: j.Error})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func globEscape(path string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	return r.Replace(path)
+}
+
+// sloReport is the availability of services over a period.
+type sloReport struct {
+	services  []*serviceSLO
+	incidents []incident
+}
+
+// serviceSLO count the checks of a service, those in maintenance aside.
+type serviceSLO struct {
+	key  string
+	slo  float64
+	up   int
+	down int
+}
+
+// uptime return the percentage of checks that were up.
+func (s *serviceSLO) uptime() float64 {
+	if s.up+s.down == 0 {
+		return 100
+	}
+	return 100 * float64(s.up) / float64(s.up+s.down)
+}
+
+// budgetLeft return the percentage of the error budget of the SLO left,
+// negative once exceeded. It is all left without failed checks, services
+// without checks included.
+func (s *serviceSLO) budgetLeft() float64 {
+	if s.down == 0 {
+		return 100
+	}
+	allowed := (100 - s.slo) / 100 * float64(s.up+s.down)
+	return 100 * (allowed - float64(s.down)) / allowed
+}
+
+// incident is a period during which a service was down, from its first
+// failed check to its next successful one. Incidents still going on at
+// the end of the history have no End.
+type incident struct {
+	Key   string
+	Start time.Time
+	End   time.Time
+	Last  time.Time
+	Err   string
+}
+
+func (i incident) duration() time.Duration {
+	if i.End.IsZero() {
+		return i.Last.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// newSLOReport compute the report of entries, sorted by time. Services
+// down because of a dependency count as down.
+func newSLOReport(entries []historyEntry, slo float64) sloReport {
+	var r sloReport
+	services := make(map[string]*serviceSLO)
+	open := make(map[string]*incident)
+	for _, e := range entries {
+		s, ok := services[e.Key]
+		if !ok {
+			s = &serviceSLO{key: e.Key, slo: slo}
+			services[e.Key] = s
+			r.services = append(r.services, s)
+		}
+		switch e.State {
+		case "maintenance":
+		case "up":
+			s.up++
+			if i := open[e.Key]; i != nil {
+				i.End = e.Time
+				r.incidents = append(r.incidents, *i)
+				delete(open, e.Key)
+			}
+		default:
+			s.down++
+			if i := open[e.Key]; i != nil {
+				i.Last = e.Time
+			} else {
+				open[e.Key] = &incident{Key: e.Key, Start: e.Time, Last: e.Time, Err: e.Err}
+			}
+		}
+	}
+	for _, s := range r.services {
+		if i := open[s.key]; i != nil {
+			r.incidents = append(r.incidents, *i)
+		}
+	}
+	sort.SliceStable(r.incidents, func(i, j int) bool { return r.incidents[i].duration() > r.incidents[j].duration() })
+	return r
+}
+
+// writeSLOReport print the uptime and error budget of every service and
+// the n longest incidents.
+func writeSLOReport(w io.Writer, r sloReport, n int) {
+	for _, s := range r.services {
+		fmt.Fprintf(w, "Service: %s; Uptime: %.3f%% (%d/%d); Error budget: %.1f%% left\n",
+			s.key, s.uptime(), s.up, s.up+s.down, s.budgetLeft())
+	}
+	if len(r.incidents) == 0 || n <= 0 {
+		return
+	}
+	fmt.Fprintln(w, "Worst incidents:")
+	for _, i := range r.incidents[:min(n, len(r.incidents))] {
+		fmt.Fprintf(w, "  %s: %s for %s", i.Key, i.Start.UTC().Format(time.DateTime), i.duration().Round(time.Second))
+		if i.End.IsZero() {
+			io.WriteString(w, ", ongoing")
+		}
+		if i.Err != "" {
+			fmt.Fprintf(w, "; Error: %s", i.Err)
+		}
+		io.WriteString(w, "\n")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.log")
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	down := &StatusError{Status: 503}
+
+	// The older results are in a rotated file, the oldest is out of the
+	// reported period.
+	write := func(path string, recs ...record) {
+		t.Helper()
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		for _, rec := range recs {
+			if err := writeRecord(f, rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f.WriteString("not json\n")
+	}
+	at := func(minutes int, res Result) record {
+		return record{Time: start.Add(time.Duration(minutes) * time.Minute), Result: res}
+	}
+	a := Result{Name: "a", Url: "https://a.com", Status: 200}
+	aDown := Result{Name: "a", Url: "https://a.com", Status: 503, Err: down}
+	b := Result{Url: "https://b.com", Status: 200}
+	bDown := Result{Url: "https://b.com", Err: errors.New("timeout")}
+	write(path+"."+start.Format(rotateLayout),
+		at(-10, aDown),
+		at(0, a), at(0, b),
+		at(1, aDown), at(1, b),
+		at(2, aDown), at(2, b),
+	)
+	write(path,
+		at(3, a), at(3, Result{Url: "https://b.com", Maintenance: true}),
+		at(4, a), at(4, bDown),
+	)
+	write(path+".bak", at(5, aDown))
+
+	entries, err := readHistory(path, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	writeSLOReport(&out, newSLOReport(entries, 90), 5)
+	want := "Service: a; Uptime: 60.000% (3/5); Error budget: -300.0% left\n" +
+		"Service: https://b.com; Uptime: 75.000% (3/4); Error budget: -150.0% left\n" +
+		"Worst incidents:\n" +
+		"  a: 2026-10-01 00:01:00 for 2m0s; Error: unexpected status 503\n" +
+		"  https://b.com: 2026-10-01 00:04:00 for 0s, ongoing; Error: timeout\n"
+	if got := out.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestBudgetLeft(t *testing.T) {
+	for _, tt := range []struct {
+		s    serviceSLO
+		want float64
+	}{
+		{serviceSLO{slo: 99}, 100},
+		{serviceSLO{slo: 100, up: 10}, 100},
+		{serviceSLO{slo: 90, up: 19, down: 1}, 50},
+		{serviceSLO{slo: 90, up: 7, down: 3}, -200},
+	} {
+		if got := tt.s.budgetLeft(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v: want %g; got %g", tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	for s, want := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "1.5d": 36 * time.Hour, "12h": 12 * time.Hour} {
+		if got, err := parseDays(s); got != want || err != nil {
+			t.Errorf("%s: want %s; got %s, %v", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"d", "-1d", "0s", "soon"} {
+		if _, err := parseDays(s); err == nil {
+			t.Errorf("%s: want error", s)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header of the request IDs of checks.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestID send a unique ID in the header of the requests of each
+// check, recorded with the result so that failures can be found in the
+// logs of the service. Services whose headers set it keep their value. An
+// empty header disables request IDs.
+func WithRequestID(header string) Option {
+	return func(c *checker) { c.requestID = http.CanonicalHeaderKey(header) }
+}
+
+// newRequestID return a random UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+	var id [36]byte
+	hex.Encode(id[:8], b[:4])
+	hex.Encode(id[9:13], b[4:6])
+	hex.Encode(id[14:18], b[6:8])
+	hex.Encode(id[19:23], b[8:10])
+	hex.Encode(id[24:], b[10:])
+	id[8], id[13], id[18], id[23] = '-', '-', '-', '-'
+	return string(id[:])
+}
+
+// RequestIDError report a response which does not echo the request ID of
+// its request, for services with expect-request-id=true.
+type RequestIDError struct {
+	Header string
+	Want   string
+	Got    string
+}
+
+func (e *RequestIDError) Error() string {
+	if e.Want == "" {
+		return "expect-request-id: request IDs are disabled"
+	}
+	if e.Got == "" {
+		return fmt.Sprintf("request ID %s not echoed in %s", e.Want, e.Header)
+	}
+	return fmt.Sprintf("request ID %s echoed as %q in %s", e.Want, e.Got, e.Header)
+}
+
+// checkRequestID return an error unless h echoes id in header.
+func checkRequestID(h http.Header, header, id string) error {
+	if header == "" || id == "" {
+		return &RequestIDError{}
+	}
+	if got := h.Get(header); got != id {
+		return &RequestIDError{Header: header, Want: id, Got: got}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		got = append(got, id)
+		if r.URL.Path == "/echo" {
+			w.Header().Set("X-Request-ID", id)
+		}
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	c := newChecker(WithRetries(1))
+	c.retryDelay = 0
+	res := c.check(context.Background(), Service{URL: srv.URL + "/down"})
+	if len(got) != 2 || !uuid.MatchString(got[0]) || got[0] == got[1] || res.RequestID != got[1] {
+		t.Errorf("want a new UUID per request, the last in the result; got %q, %q", got, res.RequestID)
+	}
+	var b bytes.Buffer
+	writeTextResult(&b, "", res)
+	if !strings.Contains(b.String(), "; Request ID: "+res.RequestID+"; Code: HC201; Error: unexpected status 503") {
+		t.Errorf("got %q", b.String())
+	}
+
+	for _, tt := range []struct {
+		line string
+		up   bool
+	}{
+		{line: "/echo expect-request-id=true", up: true},
+		{line: "/ expect-request-id=true"},
+		{line: "/ header=X-Request-ID:abc", up: true},
+	} {
+		got = nil
+		svc, err := ParseService(srv.URL + tt.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := newChecker(WithRetries(0)).check(context.Background(), svc)
+		var idErr *RequestIDError
+		if res.Up() != tt.up || !tt.up && !errors.As(res.Err, &idErr) {
+			t.Errorf("%s: want up %v; got %v", tt.line, tt.up, res.Err)
+		}
+	}
+	if got[0] != "abc" {
+		t.Errorf("request ID of the service: got %q", got[0])
+	}
+
+	got = nil
+	res = newChecker(WithRequestID("")).check(context.Background(), Service{URL: srv.URL})
+	if got[0] != "" || res.RequestID != "" {
+		t.Errorf("disabled: got %q, %q", got[0], res.RequestID)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the rotation of the output file of serve mode.
+const (
+	DefaultOutMaxSize = 100 << 20
+	DefaultOutMaxAge  = 24 * time.Hour
+	DefaultOutKeep    = 7
+)
+
+// rotateLayout is the layout of the time suffix of rotated files.
+const rotateLayout = "20060102T150405.000000000"
+
+// rotatingFile is a file appended to, renamed with a timestamp suffix once
+// it grows beyond maxSize bytes or gets older than maxAge, a zero value
+// disabling the limit. Only the keep most recent renamed files are kept,
+// all of them when keep is zero. It may be written to concurrently.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	keep    int
+	now     func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// openRotating open the file at path for appending, rotating it as
+// described by rotatingFile.
+func openRotating(path string, maxSize int64, maxAge time.Duration, keep int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, keep: keep, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size, r.opened = f, info.Size(), r.now()
+	return nil
+}
+
+// Write append p to the file, rotating it first when p would exceed its
+// size or when it is too old. p is never split across files.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	full := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	old := r.maxAge > 0 && r.now().Sub(r.opened) >= r.maxAge
+	if full || old {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate rename the file with the current time as suffix, open a new one
+// and remove the oldest renamed files beyond keep.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	rotated := r.path + "." + r.now().UTC().Format(rotateLayout)
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.keep <= 0 {
+		return nil
+	}
+
+	dir, base := filepath.Split(r.path)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return err
+	}
+	var old []string
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), base+".")
+		if _, err := time.Parse(rotateLayout, suffix); ok && err == nil {
+			old = append(old, e.Name())
+		}
+	}
+	// Timestamp suffixes sort in time order.
+	sort.Strings(old)
+	for _, name := range old[:max(len(old)-r.keep, 0)] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close close the current file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
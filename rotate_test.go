@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.log")
+	// Unrelated files sharing the prefix are left alone.
+	if err := os.WriteFile(path+".bak", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := openRotating(path, 10, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.opened = now
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("123456\n")
+	write("abc\n") // fits exactly
+	now = now.Add(time.Second)
+	write("too long line\n") // rotated by size, not split
+	now = now.Add(time.Hour)
+	write("x\n") // rotated by age
+	now = now.Add(time.Second)
+	write("longer line\n") // rotated by size, the oldest rotated file is removed
+
+	if got, _ := os.ReadFile(path); string(got) != "longer line\n" {
+		t.Errorf("current file: got %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{
+		"results.log",
+		"results.log.20260101T010001.000000000",
+		"results.log.20260101T010002.000000000",
+		"results.log.bak",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("want %q; got %q", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("want %q; got %q", want, names)
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, want[1])); string(got) != "too long line\n" {
+		t.Errorf("rotated by age: got %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptySHA256 is the hex encoded SHA-256 of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// awsCredentials are the credentials requests to AWS are signed with.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// awsEnvCredentials return the credentials of the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func awsEnvCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, fmt.Errorf("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return creds, nil
+}
+
+// checkS3 check an object of an s3://bucket/key url with a HEAD request
+// signed with the credentials of the environment, validating both the
+// storage service and the credentials. The region defaults to AWS_REGION
+// and the endpoint, such as that of MinIO, to AWS_ENDPOINT_URL_S3 or
+// AWS_ENDPOINT_URL; a region= parameter overrides the region. The endpoint
+// is only taken from the environment, as the signed requests carry the
+// credentials, and must be https. Buckets of custom endpoints are addressed
+// in the path.
+func checkS3(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	creds, err := awsEnvCredentials()
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	if query.Has("endpoint") {
+		return fmt.Errorf("s3: endpoint= is not supported, set AWS_ENDPOINT_URL_S3")
+	}
+	region := cmp.Or(query.Get("region"), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	endpoint := cmp.Or(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"))
+	// Keys are sent encoded as they are signed.
+	object := "https://" + u.Host + ".s3." + region + ".amazonaws.com" + canonicalURI(u.Path)
+	if endpoint != "" {
+		if !strings.HasPrefix(strings.ToLower(endpoint), "https://") {
+			return fmt.Errorf("s3: endpoint %q is not https", endpoint)
+		}
+		object = strings.TrimSuffix(endpoint, "/") + "/" + u.Host + canonicalURI(u.Path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, object, nil)
+	if err != nil {
+		return err
+	}
+	signV4(req, creds, region, "s3", time.Now())
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	result.Status = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Status: resp.StatusCode, Expect: []int{http.StatusOK}}
+	}
+	return nil
+}
+
+// signV4 sign req for service in region with AWS Signature Version 4,
+// covering its host and headers and an empty payload.
+func signV4(req *http.Request, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	}
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + req.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalURI return the path of a canonical request: each segment of the
+// unescaped path encoded with uriEncode, / for an empty path.
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent encode every byte of s but the unreserved characters
+// of RFC 3986, as Signature Version 4 requires; url.PathEscape leaves
+// characters such as + = @ ! $ ' ( ) * as they are.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery return the query of a canonical request: sorted and
+// escaped with %20 for spaces.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
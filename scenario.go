@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxScenarioBody bound how much of a response body is read to extract
+// variables.
+const maxScenarioBody = 1 << 20
+
+// Scenario is a sequence of HTTP steps, later steps using variables
+// extracted from the responses of earlier ones as {{name}}. It is read from
+// a JSON file:
+//
+//	{"steps": [
+//	  {"name": "login", "method": "POST", "url": "https://a.com/login",
+//	   "body": "{\"user\": \"probe\"}", "extract": {"token": "json:access_token"}},
+//	  {"name": "orders", "url": "https://a.com/orders",
+//	   "headers": {"Authorization": "Bearer {{token}}"}, "expect": [200]}
+//	]}
+//
+// Variables are extracted with json:path.to.field from a JSON body,
+// header:Name from a response header or regexp:expr, the first submatch of
+// expr in the body.
+//
+// With "cookies": true, each run of the scenario gets a cookie jar of its
+// own, so that session cookies set by a step are sent by the next ones.
+type Scenario struct {
+	Cookies bool   `json:"cookies"`
+	Steps   []Step `json:"steps"`
+}
+
+// Step is a single request of a scenario. Method defaults to GET and Expect
+// to any status below 400.
+type Step struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Header  map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Expect  []int             `json:"expect"`
+	Extract map[string]string `json:"extract"`
+
+	extractors map[string]extractor
+}
+
+// StepResult is the outcome of a step of a scenario.
+type StepResult struct {
+	Name    string
+	Status  int
+	Latency time.Duration
+	Err     error
+}
+
+// extractor extract a variable from a response and its body.
+type extractor func(resp *http.Response, body []byte) (string, error)
+
+var scenarioVar = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// loadScenario read and validate a scenario file.
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+
+	for i := range scenario.Steps {
+		step := &scenario.Steps[i]
+		if step.Name == "" {
+			step.Name = "step " + strconv.Itoa(i+1)
+		}
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		if step.URL == "" {
+			return nil, fmt.Errorf("%s: %s: missing url", path, step.Name)
+		}
+		step.extractors = make(map[string]extractor, len(step.Extract))
+		for name, spec := range step.Extract {
+			ex, err := parseExtractor(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: extract %s: %w", path, step.Name, name, err)
+			}
+			step.extractors[name] = ex
+		}
+	}
+	return &scenario, nil
+}
+
+func parseExtractor(spec string) (extractor, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "json":
+		path := strings.Split(arg, ".")
+		return func(_ *http.Response, body []byte) (string, error) {
+			return extractJSON(body, path)
+		}, nil
+	case "header":
+		return func(resp *http.Response, _ []byte) (string, error) {
+			v := resp.Header.Get(arg)
+			if v == "" {
+				return "", fmt.Errorf("no header %s", arg)
+			}
+			return v, nil
+		}, nil
+	case "regexp":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("regexp %q has no submatch", arg)
+		}
+		return func(_ *http.Response, body []byte) (string, error) {
+			m := re.FindSubmatch(body)
+			if m == nil {
+				return "", fmt.Errorf("regexp %q does not match", arg)
+			}
+			return string(m[1]), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown extractor %q, want json:, header: or regexp:", spec)
+}
+
+// extractJSON walk path through a JSON document, numeric elements indexing
+// arrays.
+func extractJSON(body []byte, path []string) (string, error) {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", err
+	}
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("no index %s", key)
+			}
+			v = node[i]
+		default:
+			v = nil
+		}
+		if v == nil {
+			return "", fmt.Errorf("no field %s", strings.Join(path, "."))
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// expand replace {{name}} by the value of the variable name.
+func expand(s string, vars map[string]string) (string, error) {
+	var err error
+	s = scenarioVar.ReplaceAllStringFunc(s, func(m string) string {
+		name := scenarioVar.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("undefined variable %s", name)
+		}
+		return v
+	})
+	return s, err
+}
+
+// checkScenario run the steps of a scenario in order and report them as a
+// single result, whose latency is the sum of the steps latencies. The
+// scenario stops at the first failed step and is retried as a whole.
+func (c *checker) checkScenario(ctx context.Context, svc Service) Result {
+	ctx, span := tracer.Start(ctx, "checkScenario", trace.WithAttributes(
+		attribute.String("healthcheck.service", svc.Name),
+		attribute.StringSlice("healthcheck.tags", svc.Tags),
+	))
+	defer span.End()
+
+	if svc.inMaintenance(c.now()) {
+		result := Result{Name: svc.Name, Tags: svc.Tags, Maintenance: true}
+		recordResult(ctx, span, result)
+		return result
+	}
+
+	result := c.retry(ctx, span, svc, c.runScenario)
+	recordResult(ctx, span, result)
+	return result
+}
+
+func (c *checker) runScenario(ctx context.Context, svc Service) Result {
+	client, release := c.httpClient(svc)
+	defer release()
+	if svc.Scenario.Cookies {
+		client = withCookieJar(client)
+	}
+
+	result := Result{Name: svc.Name, Tags: svc.Tags}
+	vars := make(map[string]string)
+	for _, step := range svc.Scenario.Steps {
+		sr := c.runStep(ctx, client, svc, step, vars)
+		result.Steps = append(result.Steps, sr)
+		result.Status = sr.Status
+		result.Latency += sr.Latency
+		if sr.Err != nil {
+			result.Err = fmt.Errorf("step %s: %w", step.Name, sr.Err)
+			break
+		}
+	}
+	return result
+}
+
+// runStep perform a step, adding the variables it extracts to vars.
+func (c *checker) runStep(ctx context.Context, client *http.Client, svc Service, step Step, vars map[string]string) StepResult {
+	ctx, span := tracer.Start(ctx, step.Name, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	sr := StepResult{Name: step.Name}
+	req, err := c.newStepRequest(ctx, svc, step, vars)
+	if err != nil {
+		sr.Err = err
+		return sr
+	}
+
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		sr.Latency = time.Since(start)
+		sr.Err = err
+		return sr
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if len(step.extractors) > 0 {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxScenarioBody))
+	}
+	sr.Latency = time.Since(start)
+	sr.Status = resp.StatusCode
+	span.SetAttributes(attribute.Int("http.response.status_code", sr.Status))
+	if err != nil {
+		sr.Err = err
+		return sr
+	}
+
+	if !expectedStatus(resp.StatusCode, step.Expect) {
+		sr.Err = &StatusError{Status: resp.StatusCode, Expect: step.Expect}
+		return sr
+	}
+	for name, ex := range step.extractors {
+		v, err := ex(resp, body)
+		if err != nil {
+			sr.Err = fmt.Errorf("extract %s: %w", name, err)
+			return sr
+		}
+		vars[name] = v
+	}
+	return sr
+}
+
+// newStepRequest build the request of a step, expanding variables in its
+// url, headers and body. Headers of the service apply to every step.
+func (c *checker) newStepRequest(ctx context.Context, svc Service, step Step, vars map[string]string) (*http.Request, error) {
+	url, err := expand(step.URL, vars)
+	if err != nil {
+		return nil, err
+	}
+	body, err := expand(step.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, step.Method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req, svc)
+	for name, value := range step.Header {
+		v, err := expand(value, vars)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, v)
+	}
+	if err := c.authorize(req, svc); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
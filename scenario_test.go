@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScenario(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckScenario(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("X-Session", "s1")
+			w.Write([]byte(`{"data": {"tokens": ["t1"]}}`))
+		case "/orders/s1":
+			if r.Header.Get("Authorization") != "Bearer t1" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	path := writeScenario(t, `{"steps": [
+		{"name": "login", "method": "POST", "url": "`+srv.URL+`/login",
+		 "extract": {"token": "json:data.tokens.0", "session": "header:X-Session"}},
+		{"url": "`+srv.URL+`/orders/{{session}}", "headers": {"Authorization": "Bearer {{ token }}"}},
+		{"name": "missing", "url": "`+srv.URL+`/missing", "expect": [404]}
+	]}`)
+	svc, err := ParseService("name=checkout scenario=" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := newChecker().check(context.Background(), svc)
+	if !res.Up() {
+		t.Fatalf("want the scenario up; got %v", res.Err)
+	}
+	if len(res.Steps) != 3 || res.Steps[1].Name != "step 2" || res.Status != http.StatusNotFound {
+		t.Errorf("unexpected steps %+v", res.Steps)
+	}
+	if res.Latency < res.Steps[0].Latency+res.Steps[1].Latency {
+		t.Errorf("want the sum of the steps latencies; got %s", res.Latency)
+	}
+
+	path = writeScenario(t, `{"steps": [
+		{"url": "`+srv.URL+`/orders/{{session}}"},
+		{"url": "`+srv.URL+`/login"}
+	]}`)
+	svc, _ = ParseService("name=broken scenario=" + path)
+	res = newChecker().check(context.Background(), svc)
+	if res.Up() || len(res.Steps) != 1 || !strings.Contains(res.Err.Error(), "undefined variable session") {
+		t.Errorf("want the scenario to stop on the undefined variable; got %v", res.Err)
+	}
+}
+
+func TestScenarioRelativeToServicesFile(t *testing.T) {
+	path := writeScenario(t, `{"steps": [{"url": "https://a.com"}]}`)
+	dir := filepath.Dir(path)
+	services, err := ParseServices(strings.NewReader("name=flow scenario="+filepath.Base(path)), filepath.Join(dir, "services.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Scenario == nil || len(services[0].Scenario.Steps) != 1 {
+		t.Errorf("want the scenario next to the services file; got %+v", services)
+	}
+	if _, err := ParseService("name=flow scenario=" + filepath.Base(path)); err == nil {
+		t.Error("want an error for a scenario relative to no services file")
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	for _, content := range []string{
+		`{"steps": []}`,
+		`{"steps": [{"name": "no url"}]}`,
+		`{"steps": [{"url": "https://a.com", "extract": {"x": "xpath://a"}}]}`,
+		`{"steps": [{"url": "https://a.com", "extract": {"x": "regexp:no-submatch"}}]}`,
+		`{"steps": `,
+	} {
+		if _, err := loadScenario(writeScenario(t, content)); err == nil {
+			t.Errorf("loadScenario(%s): want an error", content)
+		}
+	}
+}
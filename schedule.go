@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"time"
+)
+
+// schedule track when each service is next due to be checked in serve
+// mode, every interval unless it has an interval of its own.
+type schedule struct {
+	interval time.Duration
+	spread   bool
+	seed     uint64
+	next     map[string]time.Time
+}
+
+func newSchedule(interval time.Duration) *schedule {
+	return &schedule{interval: interval, next: make(map[string]time.Time)}
+}
+
+// spreadChecks make services first due at an offset of their own within
+// the spread window of their interval, see spreadWindow, rather than right
+// away, so that checking them every interval does not hit them in
+// synchronized bursts. Offsets differ from one process to another, so that
+// instances do not synchronize either.
+func (s *schedule) spreadChecks() {
+	s.spread, s.seed = true, rand.Uint64()
+}
+
+// due return the services to check at now, those whose time has come,
+// scheduling their next check, and the time the next of services is due.
+// Services never checked are due right away unless checks are spread.
+// Services missing from services are forgotten.
+func (s *schedule) due(services []Service, now time.Time) (batch []Service, next time.Time) {
+	next = now.Add(s.interval)
+	seen := make(map[string]bool, len(services))
+	for _, svc := range services {
+		key := svc.key()
+		seen[key] = true
+		interval := cmp.Or(svc.Interval, s.interval)
+		t, ok := s.next[key]
+		if !ok {
+			t = now
+			if s.spread {
+				t = now.Add(spreadOffset(key, s.seed, spreadWindow(interval)))
+			}
+		}
+		if !now.Before(t) {
+			batch = append(batch, svc)
+			t = now.Add(interval)
+		}
+		s.next[key] = t
+		if t.Before(next) {
+			next = t
+		}
+	}
+	for key := range s.next {
+		if !seen[key] {
+			delete(s.next, key)
+		}
+	}
+	return batch, next
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"cmp"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	services := []Service{
+		{Name: "api", URL: "https://api.a.com", Interval: 10 * time.Second},
+		{URL: "https://a.com"},
+		{URL: "https://a.com/blog", Interval: 10 * time.Minute},
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newSchedule(time.Minute)
+	urls := func(batch []Service) []string {
+		var urls []string
+		for _, svc := range batch {
+			urls = append(urls, svc.URL)
+		}
+		return urls
+	}
+
+	tests := []struct {
+		at   time.Duration
+		want int
+		next time.Duration
+	}{
+		{0, 3, 10 * time.Second},
+		{5 * time.Second, 0, 10 * time.Second},
+		{10 * time.Second, 1, 20 * time.Second},
+		{60 * time.Second, 2, 70 * time.Second},
+		{10 * time.Minute, 3, 10*time.Minute + 10*time.Second},
+	}
+	for _, tt := range tests {
+		batch, next := s.due(services, start.Add(tt.at))
+		if len(batch) != tt.want || !next.Equal(start.Add(tt.next)) {
+			t.Errorf("at %s: got %v, next at %s", tt.at, urls(batch), next.Sub(start))
+		}
+	}
+
+	if s.due(services[1:], start.Add(11*time.Minute)); len(s.next) != 2 {
+		t.Errorf("want removed services forgotten; got %v", s.next)
+	}
+	// Spread services are first due within the spread window of their own
+	// interval.
+	s = newSchedule(time.Minute)
+	s.spreadChecks()
+	s.due(services, start)
+	for _, svc := range services {
+		window := spreadWindow(cmp.Or(svc.Interval, time.Minute))
+		if offset := s.next[svc.key()].Sub(start); offset < 0 || offset >= window {
+			t.Errorf("%s: first due at %s, want within %s", svc.URL, offset, window)
+		}
+	}
+
+	if _, err := ParseService("https://a.com interval=0s"); err == nil {
+		t.Error("want an error for a zero interval")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sentryTimeout bound the time spent sending an event to Sentry.
+const sentryTimeout = 5 * time.Second
+
+// sentry is the client internal errors of the checker are reported with,
+// so that a broken monitoring gets noticed. It is installed by setupSentry
+// and nil when reporting is disabled.
+var sentry *sentryClient
+
+// setupSentry report internal errors to the Sentry project of dsn, like
+// https://key@o1.ingest.sentry.io/42. Reporting stays disabled when dsn is
+// empty.
+func setupSentry(dsn string) error {
+	if dsn == "" {
+		return nil
+	}
+	s, err := newSentryClient(dsn)
+	if err != nil {
+		return err
+	}
+	sentry = s
+	return nil
+}
+
+// sentryClient send events to the envelope endpoint of a Sentry project.
+type sentryClient struct {
+	dsn      string
+	endpoint string
+	key      string
+}
+
+// newSentryClient return the client of the project of dsn.
+func newSentryClient(dsn string) (*sentryClient, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sentry dsn: %w", err)
+	}
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return nil, fmt.Errorf("sentry dsn: want scheme://key@host/project, got %s", u.Redacted())
+	}
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: path.Join(dir, "api", project, "envelope") + "/"}
+	return &sentryClient{dsn: dsn, endpoint: endpoint.String(), key: u.User.Username()}, nil
+}
+
+// sentryEvent is the subset of the Sentry event payload sent.
+type sentryEvent struct {
+	EventID    string            `json:"event_id"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Platform   string            `json:"platform"`
+	Level      string            `json:"level"`
+	Logger     string            `json:"logger"`
+	ServerName string            `json:"server_name,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Exception  struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+}
+
+// reportError report err to Sentry, when set up, tagged with tags. Failures
+// to report are printed on stderr.
+func reportError(err error, tags map[string]string) {
+	if sentry == nil {
+		return
+	}
+	ev := newSentryEvent("error", fmt.Sprintf("%T", err), err.Error(), tags)
+	if err := sentry.send(ev); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// scrubParseErrors return err, an error of ParseServices, with each of its
+// errors reduced to its position and code, such as services.txt:12: HC002,
+// for Sentry: lines of services files hold urls, tokens and passwords.
+func scrubParseErrors(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		scrubbed := make([]error, len(errs))
+		for i, err := range errs {
+			scrubbed[i] = scrubParseErrors(err)
+		}
+		return errors.Join(scrubbed...)
+	}
+	if perr, ok := err.(*ParseError); ok {
+		return fmt.Errorf("%s:%d: %s", perr.Source, perr.Line, perr.ErrorCode())
+	}
+	return errors.New(string(ErrorCodeOf(err)))
+}
+
+// reportPanic report the value v recovered from a panic, with the stack of
+// the panicking goroutine, to Sentry when set up. It is to be called from
+// the deferred function recovering v.
+func reportPanic(v any, tags map[string]string) {
+	if sentry == nil {
+		return
+	}
+	ev := newSentryEvent("fatal", fmt.Sprintf("panic: %T", v), fmt.Sprint(v), tags)
+	pc := make([]uintptr, 64)
+	// Skip runtime.Callers, reportPanic and the deferred function.
+	frames := runtime.CallersFrames(pc[:runtime.Callers(3, pc)])
+	var st sentryStacktrace
+	for {
+		f, more := frames.Next()
+		// Leave out the frames of the runtime panicking.
+		if len(st.Frames) > 0 || !strings.HasPrefix(f.Function, "runtime.") {
+			st.Frames = append(st.Frames, sentryFrame{Function: f.Function, AbsPath: f.File, Lineno: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	// Sentry wants the frames oldest first.
+	for i, j := 0, len(st.Frames)-1; i < j; i, j = i+1, j-1 {
+		st.Frames[i], st.Frames[j] = st.Frames[j], st.Frames[i]
+	}
+	ev.Exception.Values[0].Stacktrace = &st
+	if err := sentry.send(ev); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// newSentryEvent return an event of an exception of type typ.
+func newSentryEvent(level, typ, value string, tags map[string]string) sentryEvent {
+	var id [16]byte
+	rand.Read(id[:])
+	hostname, _ := os.Hostname()
+	ev := sentryEvent{
+		EventID:    hex.EncodeToString(id[:]),
+		Timestamp:  time.Now().UTC(),
+		Platform:   "go",
+		Level:      level,
+		Logger:     "healthcheck",
+		ServerName: hostname,
+		Tags:       tags,
+	}
+	ev.Exception.Values = []sentryException{{Type: typ, Value: value}}
+	return ev
+}
+
+// send post ev to Sentry in an envelope.
+func (s *sentryClient) send(ev sentryEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(struct {
+		EventID string    `json:"event_id"`
+		SentAt  time.Time `json:"sent_at"`
+		DSN     string    `json:"dsn"`
+	}{ev.EventID, ev.Timestamp, s.dsn})
+	var body bytes.Buffer
+	body.Write(header)
+	fmt.Fprintf(&body, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=healthcheck/1.0, sentry_key="+s.key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sentry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sentry: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// DefaultInterval is the default time between two runs in serve mode.
+const DefaultInterval = time.Minute
+
+// runServe implement the serve subcommand, checking the services
+// periodically and serving the results over HTTP until interrupted, and
+// return the exit code.
+//
+// Besides the dashboard, the server exposes a JSON API:
+//
+//	POST /check          check {"services": ["https://a.com", ...]}, lines of a services file, see below
+//	GET  /status         last result of every service
+//	GET  /history/{key}  recorded results of a service, by name or path escaped url
+//	GET  /events         server-sent events, a "result" event per result as it completes
+//	POST /report         report of an agent, see runAgent
+//	GET  /regions        last result of every service from every agent
+//	GET  /agents/{agent} recorded reports of an agent, queued ones included
+//	GET  /incidents      incidents, from a service going down to it being up again
+//	GET  /incidents.atom incidents as an Atom feed
+//
+// POST /check, which makes the server send requests on behalf of its
+// clients, is only served with -api-token, which clients must send as a
+// bearer token, or with -open-api for anyone reaching the server.
+//
+// POST /report is served with -agent-token, which agents must send, on a
+// loopback -addr, or with -open-reports for anyone reaching the server.
+//
+// The services file may be a sitemap:url, whose urls are read again before
+// every run.
+//
+// With -out, results are also appended to a file as JSON lines, the file
+// being rotated by size and age without external tooling.
+//
+// On SIGHUP, the services file and the blackbox_exporter configuration are
+// read again; checks in progress complete with the services they started
+// with and the next run uses the new ones.
+//
+// With -grpc-addr, the Checker service of checkpb/checker.proto is served
+// too, streaming results of the services streamed by clients, with the
+// same restrictions and token as POST /check.
+//
+// With -opsgenie-key, an Opsgenie alert is created for each service failing
+// for -opsgenie-after runs in a row and closed once it is up as long, see
+// opsgenie.
+//
+// With -admin-addr, profiles and runtime metrics are served on a loopback
+// address, see adminHandler.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: healthcheck serve [flags] [services.txt]")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", ":8080", "address the dashboard and API listen on")
+	grpcAddr := fs.String("grpc-addr", "", "address the gRPC API listens on, disabled when empty")
+	opsgenieKey := fs.String("opsgenie-key", "", "API key of the Opsgenie integration alerts are created with, disabled when empty")
+	opsgenieURL := fs.String("opsgenie-url", DefaultOpsgenieURL, "base URL of the Opsgenie API, https://api.eu.opsgenie.com for the EU instance")
+	opsgenieAfter := fs.Int("opsgenie-after", DefaultOpsgenieAfter, "number of runs in a row a service must fail, or be up again, before its alert is created or closed")
+	sentryDSN := fs.String("sentry-dsn", os.Getenv("SENTRY_DSN"), "Sentry DSN internal errors are reported to, such as panics, invalid services files and failed writes; defaults to SENTRY_DSN")
+	adminAddr := fs.String("admin-addr", "", "loopback address serving pprof profiles and runtime metrics under /debug/, like localhost:6060, disabled when empty")
+	apiToken := fs.String("api-token", "", "token clients of POST /check and of the gRPC API must send as a bearer token")
+	openAPI := fs.Bool("open-api", false, "serve POST /check and the gRPC API without -api-token, to anyone reaching them")
+	agentToken := fs.String("agent-token", "", "token agents must send with their reports, see the agent subcommand")
+	openReports := fs.Bool("open-reports", false, "accept the reports of agents without -agent-token from anyone reaching a non-loopback -addr")
+	interval := fs.Duration("interval", DefaultInterval, "time between two checks of the services")
+	spread := fs.Bool("spread", false, "spread the checks of the services over their interval, each at an offset of its own, rather than starting them all at once")
+	history := fs.Int("history", DefaultHistory, "number of results kept per service")
+	timeout := fs.Duration("timeout", DefaultTimeout, "time allowed for each request, services may override it with timeout=")
+	retries := fs.Int("retries", DefaultRetries, "number of retries of a failed check, services may override it with retries=")
+	workers := fs.Int("workers", DefaultWorkers, "number of concurrent checks")
+	userAgent := fs.String("user-agent", DefaultUserAgent, "User-Agent header sent with checks")
+	out := fs.String("out", "", "file every result is appended to as a JSON line, rotated by size and age")
+	outMaxSize := fs.Int64("out-max-size", DefaultOutMaxSize, "size in bytes beyond which the -out file is rotated, 0 for no limit")
+	outMaxAge := fs.Duration("out-max-age", DefaultOutMaxAge, "age beyond which the -out file is rotated, 0 for no limit")
+	outKeep := fs.Int("out-keep", DefaultOutKeep, "number of rotated -out files kept, 0 to keep them all")
+	gcpCreds := fs.String("gcp-credentials", "", "service account key file for services with auth=gcp-id-token, defaulting to GOOGLE_APPLICATION_CREDENTIALS then the metadata server")
+	blackbox := fs.String("blackbox-config", "", "blackbox_exporter configuration file whose http modules services may use with module=")
+	sourceIP := fs.String("source-ip", "", "local address checks are sent from, on multi-homed hosts")
+	iface := fs.String("interface", "", "network interface checks are sent from, by its first address")
+	varsFile := fs.String("vars-file", "", "YAML or JSON file of the variables the lines of the services file containing {{ are rendered against, a service per combination of their values, read again on SIGHUP")
+	dumpDir := fs.String("dump-failures", "", "directory the response headers and body of each failed check are written to, a file per failure")
+	maxDumps := fs.Int("dump-max-files", DefaultMaxDumps, "number of failures -dump-failures writes, the next ones being left out, 0 for no limit")
+	maxLine := fs.Int("max-line-length", DefaultMaxLineLength, "length in bytes beyond which lines of the services file are invalid, 0 for no limit")
+	var vault vaultOptions
+	vault.register(fs)
+	var disc discovery
+	disc.register(fs)
+	location := time.UTC
+	fs.Func("timezone", "time zone of the start of checks in the results, such as Europe/Paris or Local (default UTC)", func(s string) (err error) {
+		location, err = time.LoadLocation(s)
+		return err
+	})
+	var tags []string
+	fs.Func("tags", "comma separated list of tags, only services with one of them are checked", func(s string) error {
+		tags = append(tags, strings.Split(s, ",")...)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 && !disc.enabled() {
+		fmt.Fprintln(os.Stderr, "missing file argument")
+		return exitError
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "interval must be positive")
+		return exitError
+	}
+	if *grpcAddr != "" && *apiToken == "" && !*openAPI {
+		fmt.Fprintln(os.Stderr, "-grpc-addr requires -api-token or -open-api")
+		return exitError
+	}
+	if err := setupSentry(*sentryDSN); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *adminAddr != "" {
+		if err := checkLoopback(*adminAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	var discover discoverFunc
+	if disc.enabled() {
+		var err error
+		if discover, err = disc.discoverer(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+
+	// The services file and its secrets are read at startup and on SIGHUP,
+	// discovered services are refreshed before every run.
+	path, discover := disc.sitemap.input(fs.Arg(0), discover)
+	load := func() ([]Service, error) {
+		services, err := loadServices(context.Background(), path, *maxLine, *varsFile, nil, tags)
+		if err != nil {
+			return nil, err
+		}
+		return services, prepareServices(context.Background(), services, *blackbox, vault.client())
+	}
+	services, err := load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	var listed atomic.Pointer[[]Service]
+	listed.Store(&services)
+	secrets := vault.client()
+	list := func(ctx context.Context) []Service {
+		discovered, _ := loadServices(ctx, "", 0, "", discover, tags)
+		if err := prepareServices(ctx, discovered, *blackbox, secrets); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return append(slices.Clip(*listed.Load()), discovered...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go reloadOnHangup(ctx, os.Stderr, &listed, load)
+
+	opts := []Option{WithTimeout(*timeout), WithRetries(*retries), WithWorkers(*workers), WithUserAgent(*userAgent), WithDumpFailures(*dumpDir), WithMaxDumps(*maxDumps), WithTimezone(location)}
+	if *gcpCreds != "" {
+		opts = append(opts, WithGCPCredentials(*gcpCreds))
+	}
+	source, err := sourceIPOf(*sourceIP, *iface)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if source != nil {
+		opts = append(opts, WithSourceIP(source))
+	}
+	c := newChecker(opts...)
+	s := newServer(c, *history)
+	s.agentToken = *agentToken
+	s.openReports = *openReports || checkLoopback(*addr) == nil
+	if s.agentToken == "" && !s.openReports {
+		fmt.Fprintf(os.Stderr, "POST /report is disabled: %s is not a loopback address, set -agent-token or -open-reports\n", *addr)
+	}
+	s.spread = *spread
+	s.apiToken, s.openAPI = *apiToken, *openAPI
+	if *opsgenieKey != "" {
+		s.alerts = newOpsgenie(*opsgenieURL, *opsgenieKey, *opsgenieAfter)
+	}
+	if *out != "" {
+		f, err := openRotating(*out, *outMaxSize, *outMaxAge, *outKeep)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer f.Close()
+		s.out = f
+	}
+	srv := &http.Server{Addr: *addr, Handler: s.handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	go s.run(ctx, list, *interval)
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		grpcSrv := newGRPCServer(c, *apiToken)
+		go grpcSrv.Serve(lis)
+		defer grpcSrv.Stop()
+		fmt.Fprintf(os.Stderr, "serving the gRPC API on %s\n", *grpcAddr)
+	}
+
+	if *adminAddr != "" {
+		lis, err := net.Listen("tcp", *adminAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		adminSrv := &http.Server{Handler: adminHandler()}
+		go adminSrv.Serve(lis)
+		defer adminSrv.Close()
+		fmt.Fprintf(os.Stderr, "serving the admin endpoints on %s\n", *adminAddr)
+	}
+
+	fmt.Fprintf(os.Stderr, "serving on %s\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// server hold the state of serve mode.
+type server struct {
+	checker     *checker
+	store       *store
+	events      *broker
+	agents      *aggregator
+	incidents   *incidentLog
+	agentToken  string
+	openReports bool
+	spread      bool
+	apiToken    string
+	openAPI     bool
+	out         io.Writer
+	alerts      *opsgenie
+}
+
+// newServer return a server checking services with c and keeping history
+// results per service.
+func newServer(c *checker, history int) *server {
+	return &server{
+		checker:   c,
+		store:     newStore(history),
+		events:    newBroker(),
+		agents:    newAggregator(history),
+		incidents: newIncidentLog(DefaultIncidentLog),
+	}
+}
+
+// run check the services returned by list until ctx is done, each every
+// interval unless it has an interval of its own, calling list again every
+// interval. Services are fed to the workers of the checker one at a time as
+// they fall due, a service still being checked waiting for its next turn,
+// and each result is handled as it completes, see handle. The first checks
+// start right away unless they are spread, see schedule.spreadChecks.
+func (s *server) run(ctx context.Context, list func(context.Context) []Service, interval time.Duration) {
+	c := s.checker
+	var (
+		mu       sync.Mutex // guards inFlight
+		inFlight = make(map[string]bool)
+		handleMu sync.Mutex // serializes handle
+		down     = make(map[string]bool)
+		jobs     = make(chan job)
+		pooled   = make(chan struct{})
+	)
+	go func() {
+		defer close(pooled)
+		check := func(svc Service) Result { return c.check(ctx, svc) }
+		c.pool(jobs, check, func(j job, res Result) {
+			// Checks interrupted by ctx say nothing of their service.
+			if ctx.Err() == nil {
+				handleMu.Lock()
+				s.handle(ctx, j.svc, res, down)
+				handleMu.Unlock()
+			}
+			mu.Lock()
+			delete(inFlight, j.svc.key())
+			mu.Unlock()
+		})
+	}()
+	defer func() {
+		close(jobs)
+		<-pooled
+	}()
+
+	var (
+		services []Service
+		listed   time.Time
+		sched    = newSchedule(interval)
+	)
+	if s.spread {
+		sched.spreadChecks()
+	}
+	for {
+		if now := c.now(); services == nil || !now.Before(listed.Add(interval)) {
+			services, listed = list(ctx), now
+			s.forget(services, down, &handleMu)
+		}
+		batch, next := sched.due(services, c.now())
+		if relist := listed.Add(interval); relist.Before(next) {
+			next = relist
+		}
+		for _, svc := range batch {
+			mu.Lock()
+			busy := inFlight[svc.key()]
+			inFlight[svc.key()] = true
+			mu.Unlock()
+			if busy {
+				continue
+			}
+			select {
+			case jobs <- job{svc: svc}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !sleep(ctx, next.Sub(c.now())) {
+			return
+		}
+	}
+}
+
+// forget drop the results of the services missing from services, those
+// removed from the services file, from the store and from down, guarded by
+// handleMu.
+func (s *server) forget(services []Service, down map[string]bool, handleMu *sync.Mutex) {
+	results := make(map[string]bool, len(services))
+	known := make(map[string]bool, 2*len(services))
+	for _, svc := range services {
+		results[resultKey(Result{Name: svc.Name, Url: redactURL(svc.URL)})] = true
+		known[svc.key()], known[svc.URL] = true, true
+	}
+	s.store.retain(results)
+	handleMu.Lock()
+	defer handleMu.Unlock()
+	for key := range down {
+		if !known[key] {
+			delete(down, key)
+		}
+	}
+}
+
+// handle publish the result res of svc, appending it to out as a JSON line
+// when set, and add it to the store, the incidents and the alerts. Failures
+// of services with a dependency down, by its last result in down, are
+// reported as DependencyErrors, see markDependencies. Calls must not be
+// concurrent.
+func (s *server) handle(ctx context.Context, svc Service, res Result, down map[string]bool) {
+	down[svc.key()], down[svc.URL] = !res.Up(), !res.Up()
+	if !res.Up() {
+		for _, dep := range svc.Depends {
+			if down[dep] {
+				res.Err = &DependencyError{Dependency: dep, Err: res.Err}
+				break
+			}
+		}
+	}
+	now := s.checker.now()
+	rec := record{Time: now, Result: res}
+	s.events.publish(rec)
+	if s.out != nil {
+		if err := writeRecord(s.out, rec); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "out"})
+		}
+	}
+	results := []Result{res}
+	s.store.add(results, now)
+	s.incidents.update(results, now)
+	if s.alerts != nil {
+		if err := s.alerts.update(ctx, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			reportError(err, map[string]string{"sink": "opsgenie"})
+		}
+	}
+}
+
+// sleep wait for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// writeRecord write rec to w as a single JSON line.
+func writeRecord(w io.Writer, rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// handler return the handler of serve mode: the dashboard and the API.
+func (s *server) handler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", dashboardHandler(s.store))
+	if s.apiToken != "" || s.openAPI {
+		mux.Handle("POST /check", checkHandler(s.checker, s.apiToken))
+	}
+	mux.Handle("GET /status", statusHandler(s.store))
+	mux.Handle("GET /history/{key...}", historyHandler(s.store))
+	mux.Handle("GET /events", eventsHandler(s.events))
+	if s.agentToken != "" || s.openReports {
+		mux.Handle("POST /report", reportHandler(s.agents, s.agentToken))
+	}
+	mux.Handle("GET /regions", regionsHandler(s.agents))
+	mux.Handle("GET /agents/{agent}", agentReportsHandler(s.agents))
+	mux.Handle("GET /incidents", incidentsHandler(s.incidents))
+	mux.Handle("GET /incidents.atom", incidentsFeedHandler(s.incidents))
+	return mux
+}
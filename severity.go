@@ -0,0 +1,66 @@
+package main
+
+import "sort"
+
+// Severities of services, from the least to the most serious. Services
+// without severity are major.
+const (
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+// severities list the severities from the most to the least serious.
+var severities = []string{SeverityCritical, SeverityMajor, SeverityMinor}
+
+// severityRanks rank the severities, the most serious being the highest.
+var severityRanks = map[string]int{
+	SeverityMinor:    0,
+	SeverityMajor:    1,
+	SeverityCritical: 2,
+}
+
+// severityWeights weight services by severity in the health score, see
+// tally.
+var severityWeights = map[string]float64{
+	SeverityMinor:    1,
+	SeverityMajor:    3,
+	SeverityCritical: 5,
+}
+
+// severityOf return the severity of res, major when it has none.
+func severityOf(res Result) string {
+	if res.Severity == "" {
+		return SeverityMajor
+	}
+	return res.Severity
+}
+
+// dispatchOrder return the indexes of services in the order they are to be
+// checked: the most severe first, so that failures of critical services
+// are detected early in runs of many services, then in input order.
+func dispatchOrder(services []Service) []int {
+	order := make([]int, len(services))
+	for i := range order {
+		order[i] = i
+	}
+	rank := func(svc Service) int {
+		if svc.Severity == "" {
+			return severityRanks[SeverityMajor]
+		}
+		return severityRanks[svc.Severity]
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rank(services[order[a]]) > rank(services[order[b]])
+	})
+	return order
+}
+
+// failsRun report whether res makes the run fail: its service failed for a
+// reason of its own and is at least as serious as failOn, major when empty.
+func failsRun(res Result, failOn string) bool {
+	if failOn == "" {
+		failOn = SeverityMajor
+	}
+	return res.Failed() && severityRanks[severityOf(res)] >= severityRanks[failOn]
+}
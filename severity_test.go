@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestFailsRun(t *testing.T) {
+	down := errors.New("timeout")
+	tests := []struct {
+		res    Result
+		failOn string
+		want   bool
+	}{
+		{Result{Err: down}, "", true},
+		{Result{Err: down, Severity: SeverityMinor}, "", false},
+		{Result{Err: down, Severity: SeverityMinor}, SeverityMinor, true},
+		{Result{Err: down, Severity: SeverityMajor}, SeverityCritical, false},
+		{Result{Err: down, Severity: SeverityCritical}, SeverityCritical, true},
+		{Result{Severity: SeverityCritical}, SeverityMinor, false},
+		{Result{Err: &DependencyError{Dependency: "db", Err: down}, Severity: SeverityCritical}, "", false},
+	}
+	for _, tt := range tests {
+		if got := failsRun(tt.res, tt.failOn); got != tt.want {
+			t.Errorf("failsRun(%+v, %q): got %t", tt.res, tt.failOn, got)
+		}
+	}
+}
+
+func TestTallySeverities(t *testing.T) {
+	down := errors.New("timeout")
+	var tl tally
+	tl.add(Result{Err: down})
+	if tl.DownBySeverity != nil || tl.String() != "0 up; 1 down" {
+		t.Errorf("without severities: got %q", tl)
+	}
+	tl.add(Result{Err: down, Severity: SeverityMinor})
+	tl.add(Result{Err: down, Severity: SeverityCritical})
+	tl.add(Result{Severity: SeverityCritical})
+	if got, want := tl.String(), "1 up; 3 down (1 critical, 1 major, 1 minor)"; got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestHealthScore(t *testing.T) {
+	down := errors.New("timeout")
+	s := summarize([]Result{
+		{Url: "https://a.com", Severity: SeverityCritical},
+		{Url: "https://b.com", Err: down},
+		{Url: "https://c.com", Err: down, Severity: SeverityMinor},
+		{Url: "https://d.com", Maintenance: true, Severity: SeverityCritical},
+	}, 0)
+	if score := s.Total.HealthScore; score == nil || *score != 100*5./9 {
+		t.Errorf("want 55.6; got %v", score)
+	}
+	if score := summarize([]Result{{Url: "https://d.com", Maintenance: true}}, 0).Total.HealthScore; score != nil {
+		t.Errorf("without checked services: want no score; got %v", *score)
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	services := []Service{
+		{URL: "https://a.com", Severity: SeverityMinor},
+		{URL: "https://b.com"},
+		{URL: "https://c.com", Severity: SeverityCritical},
+		{URL: "https://d.com", Severity: SeverityMinor},
+		{URL: "https://e.com", Severity: SeverityCritical},
+		{URL: "https://f.com", Severity: SeverityMajor},
+	}
+	if got := dispatchOrder(services); slices.Compare(got, []int{2, 4, 1, 5, 0, 3}) != 0 {
+		t.Errorf("got %v", got)
+	}
+
+	// With a single worker, critical services are checked first.
+	var checked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checked = append(checked, r.URL.Path)
+	}))
+	defer srv.Close()
+	for i := range services {
+		services[i].URL = srv.URL + "/" + services[i].URL[len("https://"):]
+	}
+	results := HealthCheck(services, WithWorkers(1))
+	if want := []string{"/c.com", "/e.com", "/b.com", "/f.com", "/a.com", "/d.com"}; slices.Compare(checked, want) != 0 {
+		t.Errorf("want %v; got %v", want, checked)
+	}
+	for i, res := range results {
+		if res.Url != services[i].URL {
+			t.Errorf("result %d: want the order of services; got %s", i, res.Url)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sitemapPrefix is the prefix of the services file argument naming a
+// sitemap, such as sitemap:https://a.com/sitemap.xml.
+const sitemapPrefix = "sitemap:"
+
+// sitemapTimeout bound the time spent reading a sitemap, with the sitemaps
+// of a sitemap index.
+const sitemapTimeout = 30 * time.Second
+
+// DefaultSitemapMax is the default number of urls read from a sitemap.
+const DefaultSitemapMax = 1000
+
+// sitemapOptions hold the options of sitemap inputs.
+type sitemapOptions struct {
+	max    int
+	sample int
+}
+
+func (o *sitemapOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.max, "sitemap-max", DefaultSitemapMax, "number of urls read from a sitemap: input")
+	fs.IntVar(&o.sample, "sitemap-sample", 0, "number of urls of a sitemap: input checked per run, picked at random; all of them when 0")
+}
+
+// input return the services file and the discovery of the services file
+// argument path: a sitemap: argument is not a file, its urls are discovered
+// in addition to those of discover, the sample being drawn again on every
+// discovery.
+func (o *sitemapOptions) input(path string, discover discoverFunc) (string, discoverFunc) {
+	sitemapURL, ok := strings.CutPrefix(path, sitemapPrefix)
+	if !ok {
+		return path, discover
+	}
+	return "", func(ctx context.Context) ([]Service, error) {
+		var services []Service
+		if discover != nil {
+			discovered, err := discover(ctx)
+			if err != nil {
+				return discovered, err
+			}
+			services = discovered
+		}
+		ctx, cancel := context.WithTimeout(ctx, sitemapTimeout)
+		defer cancel()
+		urls, err := readSitemap(ctx, http.DefaultClient, sitemapURL, o.max)
+		if err != nil {
+			return services, fmt.Errorf("sitemap %s: %w", sitemapURL, err)
+		}
+		for _, u := range sampleURLs(urls, o.sample) {
+			services = append(services, Service{URL: u, Tags: []string{"sitemap"}, Source: sitemapURL})
+		}
+		return services, nil
+	}
+}
+
+// sitemap is a sitemap or a sitemap index, whose urls are those of
+// sitemaps.
+type sitemap struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// readSitemap return the first max page urls of the sitemap at rawURL,
+// following the sitemaps of a sitemap index. Sitemaps ending in .gz are
+// gunzipped. Locations which are not http or https urls are skipped.
+func readSitemap(ctx context.Context, client *http.Client, rawURL string, max int) ([]string, error) {
+	var urls []string
+	var read func(rawURL string, index bool) error
+	read = func(rawURL string, index bool) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return &StatusError{Status: resp.StatusCode}
+		}
+		var body io.Reader = resp.Body
+		if strings.HasSuffix(req.URL.Path, ".gz") {
+			zr, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			body = zr
+		}
+
+		var sm sitemap
+		if err := xml.NewDecoder(body).Decode(&sm); err != nil {
+			return fmt.Errorf("%s: %w", rawURL, err)
+		}
+		for _, u := range sm.URLs {
+			if len(urls) >= max {
+				return nil
+			}
+			if loc := strings.TrimSpace(u.Loc); httpLoc(loc) {
+				urls = append(urls, loc)
+			}
+		}
+		// Sitemap indexes list sitemaps, not other indexes.
+		if !index {
+			return nil
+		}
+		for _, s := range sm.Sitemaps {
+			if len(urls) >= max {
+				return nil
+			}
+			loc := strings.TrimSpace(s.Loc)
+			if !httpLoc(loc) {
+				continue
+			}
+			if err := read(loc, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return urls, read(rawURL, true)
+}
+
+// httpLoc report whether loc, a location of a sitemap, is an http or https
+// url.
+func httpLoc(loc string) bool {
+	u, err := url.Parse(loc)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// sampleURLs return n of urls picked at random, in the order of urls, or
+// all of them when n is 0 or not lower than their number.
+func sampleURLs(urls []string, n int) []string {
+	if n <= 0 || n >= len(urls) {
+		return urls
+	}
+	picked := rand.Perm(len(urls))[:n]
+	sort.Ints(picked)
+	sample := make([]string, n)
+	for i, j := range picked {
+		sample[i] = urls[j]
+	}
+	return sample
+}
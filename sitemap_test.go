@@ -0,0 +1,94 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadSitemap(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/docs.xml.gz</loc></sitemap>
+  <sitemap><loc>file:///etc/passwd</loc></sitemap>
+</sitemapindex>`, srv.URL)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://a.com/</loc><lastmod>2026-01-01</lastmod></url>
+  <url><loc> https://a.com/about </loc></url>
+  <url><loc>gopher://a.com/</loc></url>
+  <url><loc>/relative</loc></url>
+</urlset>`)
+	})
+	mux.HandleFunc("/docs.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		zw := gzip.NewWriter(w)
+		fmt.Fprint(zw, `<urlset><url><loc>https://a.com/docs/1</loc></url><url><loc>https://a.com/docs/2</loc></url></urlset>`)
+		zw.Close()
+	})
+
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		{max: 10, want: []string{"https://a.com/", "https://a.com/about", "https://a.com/docs/1", "https://a.com/docs/2"}},
+		{max: 3, want: []string{"https://a.com/", "https://a.com/about", "https://a.com/docs/1"}},
+	}
+	for _, tt := range tests {
+		got, err := readSitemap(context.Background(), srv.Client(), srv.URL+"/sitemap.xml", tt.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("max %d: got %q; want %q", tt.max, got, tt.want)
+		}
+	}
+
+	if _, err := readSitemap(context.Background(), srv.Client(), srv.URL+"/missing.xml", 10); err == nil {
+		t.Error("missing sitemap: want an error")
+	}
+}
+
+func TestSampleURLs(t *testing.T) {
+	urls := []string{"a", "b", "c", "d", "e"}
+	if got := sampleURLs(urls, 0); len(got) != 5 {
+		t.Errorf("no sampling: got %q", got)
+	}
+	got := sampleURLs(urls, 3)
+	if len(got) != 3 {
+		t.Fatalf("got %q; want 3 urls", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1] >= got[i] {
+			t.Errorf("got %q; want the order of the sitemap", got)
+		}
+	}
+}
+
+func TestSitemapInput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset><url><loc>https://a.com/</loc></url><url><loc>https://a.com/b</loc></url></urlset>`)
+	}))
+	defer srv.Close()
+
+	o := sitemapOptions{max: DefaultSitemapMax}
+	if path, discover := o.input("services.txt", nil); path != "services.txt" || discover != nil {
+		t.Errorf("services file: got %q, %v", path, discover)
+	}
+	path, discover := o.input("sitemap:"+srv.URL, nil)
+	if path != "" || discover == nil {
+		t.Fatalf("sitemap: got %q, %v", path, discover)
+	}
+	services, err := discover(context.Background())
+	if err != nil || len(services) != 2 || services[1].URL != "https://a.com/b" || !services[1].hasAnyTag([]string{"sitemap"}) {
+		t.Errorf("got %+v, %v", services, err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// checkSMTP check the mail server of an smtp://host:port url, smtps:// for
+// implicit TLS: it reads the banner, whose delay is the latency of the
+// result, and sends EHLO, followed by STARTTLS with a starttls=true
+// parameter. The expiry of the certificate of TLS connections is recorded.
+func checkSMTP(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	port := "25"
+	if u.Scheme == "smtps" {
+		port = "465"
+	}
+	start := time.Now()
+	conn, err := c.dial(ctx, "tcp", hostPort(u, port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tlsConfig := c.tlsConfig(u.Hostname())
+	if u.Scheme == "smtps" {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		state := tlsConn.ConnectionState()
+		recordTLS(&state, result)
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, u.Hostname())
+	if err != nil {
+		return err
+	}
+	result.Latency = time.Since(start)
+	defer client.Close()
+	name, err := os.Hostname()
+	if err != nil {
+		name = "localhost"
+	}
+	if err := client.Hello(name); err != nil {
+		return err
+	}
+	if starttls, _ := strconv.ParseBool(u.Query().Get("starttls")); starttls {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+		state, _ := client.TLSConnectionState()
+		recordTLS(&state, result)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP serve on l the greeting, EHLO, STARTTLS with cert and QUIT of
+// SMTP clients.
+func fakeSMTP(l net.Listener, cert tls.Certificate) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			tp := textproto.NewConn(conn)
+			tp.PrintfLine("220 mx.a.com ESMTP")
+			for {
+				line, err := tp.ReadLine()
+				if err != nil {
+					return
+				}
+				switch cmd, _, _ := strings.Cut(line, " "); cmd {
+				case "EHLO":
+					tp.PrintfLine("250-mx.a.com")
+					tp.PrintfLine("250 STARTTLS")
+				case "STARTTLS":
+					tp.PrintfLine("220 ready")
+					tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+					if tlsConn.Handshake() != nil {
+						return
+					}
+					tp = textproto.NewConn(tlsConn)
+				case "QUIT":
+					tp.PrintfLine("221 bye")
+					return
+				default:
+					tp.PrintfLine("502 unknown command")
+				}
+			}
+		}()
+	}
+}
+
+func TestCheckSMTP(t *testing.T) {
+	https := httptest.NewTLSServer(http.NotFoundHandler())
+	defer https.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go fakeSMTP(l, https.TLS.Certificates[0])
+
+	c := newChecker(WithRetries(0))
+	roots := x509.NewCertPool()
+	roots.AddCert(https.Certificate())
+	c.transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+
+	res := c.check(context.Background(), Service{URL: "smtp://" + l.Addr().String()})
+	if !res.Up() || res.Latency <= 0 || res.TLSVersion != "" {
+		t.Errorf("plain: want up without TLS; got %v, %s", res.Err, res.TLSVersion)
+	}
+	res = c.check(context.Background(), Service{URL: "smtp://" + l.Addr().String() + "?starttls=true"})
+	if !res.Up() || res.TLSVersion == "" || !res.CertExpiry.Equal(https.Certificate().NotAfter) {
+		t.Errorf("starttls: want up with the certificate expiry; got %v, %s", res.Err, res.CertExpiry)
+	}
+
+	// A server closing the connection right away is down.
+	closing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closing.Close()
+	go func() {
+		for {
+			conn, err := closing.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	if res := c.check(context.Background(), Service{URL: "smtp://" + closing.Addr().String()}); res.Up() {
+		t.Error("closed connection: want down")
+	}
+}
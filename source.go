@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// WithSourceIP set the local address connections of the checks are opened
+// from, so that a multi-homed host checks the services through the network
+// of that address. Only remote addresses of the same family are dialed.
+func WithSourceIP(ip net.IP) Option {
+	return func(c *checker) { c.sourceIP = ip }
+}
+
+// newDialer return the dialer of the transports, with the settings of
+// http.DefaultTransport and the source address of c.
+func newDialer(c *checker) *net.Dialer {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if c.sourceIP != nil {
+		d.LocalAddr = &net.TCPAddr{IP: c.sourceIP}
+	}
+	return d
+}
+
+// sourceIPOf return the source address set by the -source-ip or -interface
+// flags, nil when neither is set. The address of an interface is its first
+// global unicast one, IPv4 first.
+func sourceIPOf(addr, iface string) (net.IP, error) {
+	switch {
+	case addr != "" && iface != "":
+		return nil, errors.New("-source-ip and -interface are exclusive")
+	case addr != "":
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source ip %q", addr)
+		}
+		return ip, nil
+	case iface == "":
+		return nil, nil
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	return interfaceIP(iface, addrs)
+}
+
+func interfaceIP(iface string, addrs []net.Addr) (net.IP, error) {
+	var v6 net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || !(ipnet.IP.IsGlobalUnicast() || ipnet.IP.IsLoopback()) {
+			continue
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		if v6 == nil {
+			v6 = ipnet.IP
+		}
+	}
+	if v6 == nil {
+		return nil, fmt.Errorf("interface %s has no usable address", iface)
+	}
+	return v6, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSourceIP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.RemoteAddr); host != "127.0.0.2" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	svc := Service{URL: srv.URL}
+	if res := newChecker(WithRetries(0)).checkURL(context.Background(), svc); res.Up() {
+		t.Error("default source: want down")
+	}
+	c := newChecker(WithRetries(0), WithSourceIP(net.ParseIP("127.0.0.2")))
+	if res := c.checkURL(context.Background(), svc); !res.Up() {
+		t.Errorf("127.0.0.2: want up; got %d %v", res.Status, res.Err)
+	}
+}
+
+func TestSourceIPOf(t *testing.T) {
+	if ip, err := sourceIPOf("", ""); ip != nil || err != nil {
+		t.Errorf("none: got %v, %v", ip, err)
+	}
+	if ip, err := sourceIPOf("192.0.2.1", ""); !ip.Equal(net.ParseIP("192.0.2.1")) || err != nil {
+		t.Errorf("ip: got %v, %v", ip, err)
+	}
+	for _, tt := range [][2]string{{"nope", ""}, {"192.0.2.1", "eth0"}, {"", "no-such-interface0"}} {
+		if _, err := sourceIPOf(tt[0], tt[1]); err == nil {
+			t.Errorf("%q: want error", tt)
+		}
+	}
+
+	addrs := func(cidrs ...string) []net.Addr {
+		var a []net.Addr
+		for _, cidr := range cidrs {
+			ip, ipnet, _ := net.ParseCIDR(cidr)
+			ipnet.IP = ip
+			a = append(a, ipnet)
+		}
+		return a
+	}
+	ip, err := interfaceIP("eth0", addrs("fe80::1/64", "2001:db8::1/64", "192.0.2.7/24"))
+	if !ip.Equal(net.ParseIP("192.0.2.7")) || err != nil {
+		t.Errorf("want the IPv4 address; got %v, %v", ip, err)
+	}
+	ip, err = interfaceIP("eth0", addrs("fe80::1/64", "2001:db8::1/64"))
+	if !ip.Equal(net.ParseIP("2001:db8::1")) || err != nil {
+		t.Errorf("want the global IPv6 address; got %v, %v", ip, err)
+	}
+	if _, err := interfaceIP("eth0", addrs("fe80::1/64")); err == nil {
+		t.Error("link-local only: want error")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// WithSpread spread the checks of a run over d instead of starting them
+// all at once, so that periodic runs of many services do not hit them in
+// synchronized bursts. Each service is checked at an offset of its own
+// within d, the same every run so that it is checked at a steady period,
+// but different from one process to another so that instances do not
+// synchronize either.
+func WithSpread(d time.Duration) Option {
+	return func(c *checker) {
+		c.spread = d
+		c.spreadSeed = rand.Uint64()
+	}
+}
+
+// spreadWindow return the time the checks of runs every interval are spread
+// over: most of the interval, leaving the last checks time to complete
+// before the next run.
+func spreadWindow(interval time.Duration) time.Duration {
+	return interval * 9 / 10
+}
+
+// spreadOrder return the indexes of services ordered by the offset at which
+// they are to be checked within c.spread, and these offsets.
+func (c *checker) spreadOrder(services []Service) ([]int, []time.Duration) {
+	offsets := make([]time.Duration, len(services))
+	order := make([]int, len(services))
+	for i, svc := range services {
+		offsets[i] = spreadOffset(svc.key(), c.spreadSeed, c.spread)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return offsets[order[a]] < offsets[order[b]] })
+	sorted := make([]time.Duration, len(order))
+	for k, i := range order {
+		sorted[k] = offsets[i]
+	}
+	return order, sorted
+}
+
+// spreadOffset return the offset of the service key within window, derived
+// from its key and seed.
+func spreadOffset(key string, seed uint64, window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration((h.Sum64() ^ seed) % uint64(window))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestSpreadOrder(t *testing.T) {
+	var services []Service
+	for i := range 100 {
+		services = append(services, Service{URL: "https://a.com/" + strconv.Itoa(i)})
+	}
+	c := newChecker(WithSpread(time.Minute))
+	order, offsets := c.spreadOrder(services)
+	again, _ := c.spreadOrder(services)
+	var early int
+	for k := range order {
+		if offsets[k] < 0 || offsets[k] >= time.Minute || k > 0 && offsets[k] < offsets[k-1] {
+			t.Fatalf("offset %d: got %s", k, offsets[k])
+		}
+		if order[k] != again[k] {
+			t.Fatal("want the same offsets every run")
+		}
+		if offsets[k] < 30*time.Second {
+			early++
+		}
+	}
+	if early < 25 || early > 75 {
+		t.Errorf("want offsets spread over the window; got %d of 100 in its first half", early)
+	}
+	if other, _ := newChecker(WithSpread(time.Minute)).spreadOrder(services); slices.Equal(order, other) {
+		t.Error("want offsets different from one process to another")
+	}
+}
+
+func TestHealthCheckSpread(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	services := []Service{{URL: srv.URL + "/a"}, {URL: srv.URL + "/b"}, {URL: srv.URL + "/c"}}
+	c := newChecker(WithSpread(200 * time.Millisecond))
+	_, offsets := c.spreadOrder(services)
+
+	start := time.Now()
+	results := c.healthCheck(context.Background(), services, nil)
+	if elapsed := time.Since(start); elapsed < offsets[len(offsets)-1] {
+		t.Errorf("want the last check started after %s; took %s", offsets[len(offsets)-1], elapsed)
+	}
+	for i, res := range results {
+		if !res.Up() || res.Url != services[i].URL {
+			t.Errorf("result %d: got %+v", i, res)
+		}
+	}
+	if w := spreadWindow(time.Minute); w != 54*time.Second {
+		t.Errorf("spreadWindow: got %s", w)
+	}
+
+	// Canceling stops the feeding of services waiting for their offset.
+	c = newChecker(WithSpread(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	results = c.healthCheck(ctx, services, nil)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("want the run canceled; took %s", elapsed)
+	}
+	for i, res := range results {
+		if res.Url != services[i].URL || res.Err == nil {
+			t.Errorf("result %d: want canceled; got %+v", i, res)
+		}
+	}
+}
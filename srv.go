@@ -0,0 +1,65 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// srvScheme is the scheme of the urls of SRV services.
+const srvScheme = "srv"
+
+// parseSRVURL split an url such as srv://_https._tcp.a.com/healthz into the
+// name of the SRV record and the scheme and path of the urls checked on its
+// targets: https when the service of the record is _https, http otherwise.
+func parseSRVURL(rawURL string) (name, scheme, path string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != srvScheme {
+		return "", "", "", &URLError{URL: rawURL, Reason: "not an srv url"}
+	}
+	if u.Port() != "" {
+		return "", "", "", &URLError{URL: rawURL, Reason: "srv url with a port, ports come from the record"}
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) < 3 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+		return "", "", "", &URLError{URL: rawURL, Reason: "want a record name like _https._tcp.example.com"}
+	}
+	scheme = "http"
+	if labels[0] == "_https" {
+		scheme = "https"
+	}
+	return u.Hostname(), scheme, u.RequestURI(), nil
+}
+
+// checkSRV check the targets of the SRV record of svc, resolved on every
+// check, as the members of a composite service. It is up when svc.Quorum
+// targets are up, or every target when not set.
+func (c *checker) checkSRV(ctx context.Context, svc Service) Result {
+	name, scheme, path, err := parseSRVURL(svc.URL)
+	if err != nil {
+		return Result{Name: svc.Name, Url: svc.URL, Tags: svc.Tags, Err: err}
+	}
+	_, records, err := c.lookupSRV(ctx, "", "", name)
+	if err == nil && len(records) == 0 {
+		err = fmt.Errorf("no target for %s", name)
+	}
+	if err != nil {
+		return Result{Name: svc.Name, Url: svc.URL, Tags: svc.Tags, Err: fmt.Errorf("srv lookup: %w", err)}
+	}
+
+	composite := svc
+	composite.URL = ""
+	composite.Members = make([]string, len(records))
+	for i, rec := range records {
+		host := net.JoinHostPort(strings.TrimSuffix(rec.Target, "."), strconv.Itoa(int(rec.Port)))
+		composite.Members[i] = scheme + "://" + host + path
+	}
+	composite.Quorum = cmp.Or(svc.Quorum, len(records))
+	res := c.checkQuorum(ctx, composite)
+	res.Url = svc.URL
+	return res
+}
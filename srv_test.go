@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestParseSRVURL(t *testing.T) {
+	name, scheme, path, err := parseSRVURL("srv://_https._tcp.a.com/healthz?full=1")
+	if err != nil || name != "_https._tcp.a.com" || scheme != "https" || path != "/healthz?full=1" {
+		t.Errorf("want _https._tcp.a.com https /healthz?full=1; got %s %s %s (%v)", name, scheme, path, err)
+	}
+	if _, scheme, path, _ := parseSRVURL("srv://_api._tcp.a.com"); scheme != "http" || path != "/" {
+		t.Errorf("want http and /; got %s %s", scheme, path)
+	}
+	for _, line := range []string{"srv://a.com", "srv://_https._tcp.a.com:443", "srv://_https.a.com"} {
+		if _, err := ParseService(line); err == nil {
+			t.Errorf("ParseService(%q): want an error", line)
+		}
+	}
+}
+
+func TestCheckSRV(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	target := func(srv *httptest.Server) *net.SRV {
+		u, _ := url.Parse(srv.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return &net.SRV{Target: u.Hostname() + ".", Port: uint16(port)}
+	}
+	c := newChecker()
+	c.lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_http._tcp.a.com" {
+			return "", nil, errors.New("no such host")
+		}
+		return name, []*net.SRV{target(up), target(down)}, nil
+	}
+
+	svc, err := ParseService("srv://_http._tcp.a.com/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := c.check(context.Background(), svc)
+	var qerr *QuorumError
+	if !errors.As(res.Err, &qerr) || qerr.Up != 1 || qerr.Total != 2 || res.Url != svc.URL {
+		t.Errorf("want 1 of 2 targets up; got %+v", res)
+	}
+	if len(res.Members) != 2 || res.Members[0].Url != up.URL+"/healthz" || !res.Members[0].Up() {
+		t.Errorf("want the targets checked as members; got %+v", res.Members)
+	}
+
+	svc.Quorum = 1
+	if res := c.check(context.Background(), svc); !res.Up() {
+		t.Errorf("want up with a quorum of 1; got %v", res.Err)
+	}
+
+	svc.URL = "srv://_http._tcp.b.com/"
+	if res := c.check(context.Background(), svc); res.Err == nil {
+		t.Error("want an error when the lookup fails")
+	}
+}
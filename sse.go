@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SSEError report a response which is not an event stream, or a stream
+// which ended, or failed with Err, before its first event.
+type SSEError struct {
+	Reason string
+	Err    error
+}
+
+func (e *SSEError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("sse: %s: %s", e.Reason, e.Err)
+	}
+	return "sse: " + e.Reason
+}
+
+func (e *SSEError) Unwrap() error {
+	return e.Err
+}
+
+// checkSSE return an error unless resp has an expected status and is an
+// event stream whose first event arrives before the request is canceled.
+// Comments, such as keep-alives, and fields without data are not events.
+func checkSSE(resp *http.Response, expect []int) error {
+	if !expectedStatus(resp.StatusCode, expect) {
+		return &StatusError{Status: resp.StatusCode, Expect: expect}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return &SSEError{Reason: fmt.Sprintf("unexpected content type %q", resp.Header.Get("Content-Type"))}
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	data := false
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			if data {
+				return nil
+			}
+		case line == "data" || strings.HasPrefix(line, "data:"):
+			data = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &SSEError{Reason: "no event", Err: err}
+	}
+	return &SSEError{Reason: "stream ended without an event"}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "want an event stream", http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": keep-alive\r\n\r\nevent: ping\r\n\r\n")
+		w.(http.Flusher).Flush()
+		switch r.URL.Path {
+		case "/hang":
+			<-r.Context().Done()
+		case "/events":
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprint(w, "id: 1\ndata: {\"status\":\"ok\"}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	c := newChecker(WithRetries(0), WithTimeout(200*time.Millisecond), WithAcceptEncoding("gzip"))
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{path: "/events"},
+		{path: "/hang", err: "sse: no event: context deadline exceeded"},
+		{path: "/closed", err: "sse: stream ended without an event"},
+		{path: "/json", err: `sse: unexpected content type "application/json"`},
+	}
+	for _, tt := range tests {
+		svc, err := ParseService(srv.URL + tt.path + " sse=true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := c.check(context.Background(), svc)
+		if tt.err == "" {
+			if !res.Up() || res.Latency < 10*time.Millisecond {
+				t.Errorf("%s: want up with the latency of the event; got %v, %s", tt.path, res.Err, res.Latency)
+			}
+			continue
+		}
+		if res.Err == nil || res.Err.Error() != tt.err {
+			t.Errorf("%s: want %q; got %v", tt.path, tt.err, res.Err)
+		}
+	}
+
+	for _, line := range []string{"https://a.com sse=true drift=true", "https://a.com sse=true graphql=true"} {
+		if _, err := ParseService(line); err == nil || !strings.Contains(err.Error(), "sse is exclusive") {
+			t.Errorf("%s: got %v", line, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sshMsgKexInit is the message starting the key exchange of SSH.
+const sshMsgKexInit = 20
+
+// checkSSH check the SSH server of an ssh://host:port url, without
+// authenticating: it reads the banner of the server, recorded in the
+// result, and the key exchange init message following it. The latency of
+// the result is the time until that message, the key exchange itself not
+// being performed.
+func checkSSH(ctx context.Context, c *checker, u *url.URL, result *Result) error {
+	start := time.Now()
+	conn, err := c.dial(ctx, "tcp", hostPort(u, "22"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintf(conn, "SSH-2.0-%s\r\n", strings.ReplaceAll(DefaultUserAgent, " ", "_")); err != nil {
+		return err
+	}
+
+	// Servers may send other lines before their version.
+	r := bufio.NewReader(conn)
+	for lines := 0; ; lines++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("ssh banner: %w", err)
+		}
+		if line = strings.TrimRight(line, "\r\n"); strings.HasPrefix(line, "SSH-") {
+			result.Banner = line
+			break
+		}
+		if lines >= 10 {
+			return fmt.Errorf("ssh banner: no version line")
+		}
+	}
+	if !strings.HasPrefix(result.Banner, "SSH-2.0-") && !strings.HasPrefix(result.Banner, "SSH-1.99-") {
+		return fmt.Errorf("ssh: unsupported protocol version %q", result.Banner)
+	}
+
+	var head struct {
+		Length  uint32
+		Padding uint8
+		Type    uint8
+	}
+	if err := binary.Read(r, binary.BigEndian, &head); err != nil {
+		return fmt.Errorf("ssh key exchange: %w", err)
+	}
+	if head.Type != sshMsgKexInit || head.Length < 2 || head.Length > 35000 {
+		return fmt.Errorf("ssh key exchange: unexpected message %d", head.Type)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(head.Length)-2); err != nil {
+		return fmt.Errorf("ssh key exchange: %w", err)
+	}
+	result.Latency = time.Since(start)
+	return nil
+}
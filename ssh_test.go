@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSSH serve on l the version exchange of an SSH server, preceded by
+// banner lines, followed by a key exchange init message.
+func fakeSSH(l net.Listener, lines ...string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+				return
+			}
+			conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+			payload := append([]byte{sshMsgKexInit}, make([]byte, 16)...)
+			packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1+4))
+			packet = append(append(append(packet, 4), payload...), 0, 0, 0, 0)
+			conn.Write(packet)
+			conn.Read(make([]byte, 1))
+		}()
+	}
+}
+
+func TestCheckSSH(t *testing.T) {
+	tests := []struct {
+		lines  []string
+		banner string
+		up     bool
+	}{
+		{lines: []string{"SSH-2.0-OpenSSH_9.6"}, banner: "SSH-2.0-OpenSSH_9.6", up: true},
+		{lines: []string{"Authorized use only", "SSH-2.0-OpenSSH_9.6 Ubuntu"}, banner: "SSH-2.0-OpenSSH_9.6 Ubuntu", up: true},
+		{lines: []string{"SSH-1.5-old"}, banner: "SSH-1.5-old"},
+		{lines: []string{"220 mx.a.com ESMTP"}},
+	}
+	c := newChecker(WithRetries(0), WithTimeout(500*time.Millisecond))
+	for _, tt := range tests {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		go fakeSSH(l, tt.lines...)
+
+		res := c.check(context.Background(), Service{URL: "ssh://" + l.Addr().String()})
+		if res.Up() != tt.up || res.Banner != tt.banner {
+			t.Errorf("%q: got up %v, banner %q, %v; want %v, %q", tt.lines, res.Up(), res.Banner, res.Err, tt.up, tt.banner)
+		}
+	}
+}
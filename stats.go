@@ -0,0 +1,71 @@
+package main
+
+import (
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// SampleStats describe repeated samples of a service.
+type SampleStats struct {
+	Samples   int
+	Successes int
+	Min       time.Duration
+	Avg       time.Duration
+	P95       time.Duration
+	Max       time.Duration
+
+	// Latencies are those of the samples that got a response, sorted.
+	Latencies []time.Duration
+}
+
+// SuccessRate is the ratio of samples that were up, between 0 and 1.
+func (s SampleStats) SuccessRate() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Samples)
+}
+
+// sampleStats compute statistics over the latencies of the samples that got
+// a response.
+func sampleStats(samples []Result) SampleStats {
+	stats := SampleStats{Samples: len(samples)}
+	latencies := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if s.Up() {
+			stats.Successes++
+		}
+		if s.Status != 0 {
+			latencies = append(latencies, s.Latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	slices.Sort(latencies)
+	stats.Latencies = latencies
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	stats.Min = latencies[0]
+	stats.Max = latencies[len(latencies)-1]
+	stats.Avg = sum / time.Duration(len(latencies))
+	stats.P95 = percentile(latencies, 95)
+	return stats
+}
+
+// percentile return the p-th percentile of sorted using the nearest-rank
+// method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.999999999)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[min(rank, len(sorted))-1]
+}
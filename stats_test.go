@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 20)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 10 * time.Millisecond},
+		{95, 19 * time.Millisecond},
+		{99, 20 * time.Millisecond},
+		{100, 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v): want %s; got %s", tt.p, tt.want, got)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("want 0 without samples; got %s", got)
+	}
+}
+
+func TestSampleStats(t *testing.T) {
+	samples := []Result{
+		{Status: 200, Latency: 30 * time.Millisecond},
+		{Status: 200, Latency: 10 * time.Millisecond},
+		{Status: 503, Latency: 20 * time.Millisecond, Err: &StatusError{Status: 503}},
+		{Err: errors.New("timeout"), Latency: time.Second},
+	}
+	got := sampleStats(samples)
+	want := SampleStats{
+		Samples:   4,
+		Successes: 2,
+		Min:       10 * time.Millisecond,
+		Avg:       20 * time.Millisecond,
+		P95:       30 * time.Millisecond,
+		Max:       30 * time.Millisecond,
+		Latencies: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v; got %+v", want, got)
+	}
+	if got.SuccessRate() != 0.5 {
+		t.Errorf("want a success rate of 0.5; got %v", got.SuccessRate())
+	}
+}
+
+func TestCheckURLSamples(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	res := newChecker(WithSamples(5), WithRetries(3)).checkURL(context.Background(), Service{URL: srv.URL})
+	if calls.Load() != 5 {
+		t.Errorf("want 5 requests; got %d", calls.Load())
+	}
+	if res.Stats == nil || res.Stats.Samples != 5 || res.Stats.Successes != 4 {
+		t.Fatalf("want 4 of 5 samples up; got %+v", res.Stats)
+	}
+	if res.Up() || res.Status != http.StatusInternalServerError {
+		t.Errorf("want the failed sample reported; got %d (%v)", res.Status, res.Err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultHistory is the default number of results kept per service.
+const DefaultHistory = 120
+
+// record is a result stored with the time of its check.
+type record struct {
+	Time time.Time
+	Result
+}
+
+// store keep the recent results of each service in memory, in the order of
+// the services.
+type store struct {
+	limit int
+
+	mu      sync.RWMutex
+	keys    []string
+	history map[string][]record
+	updated time.Time
+}
+
+func newStore(limit int) *store {
+	return &store{limit: max(limit, 1), history: make(map[string][]record)}
+}
+
+// resultKey identify the service of a result, by name or else by url.
+func resultKey(res Result) string {
+	if res.Name != "" {
+		return res.Name
+	}
+	return res.Url
+}
+
+// add record the results of a run at t, dropping the oldest results beyond
+// the limit of the store.
+func (s *store) add(results []Result, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, res := range results {
+		key := resultKey(res)
+		h, known := s.history[key]
+		if !known {
+			s.keys = append(s.keys, key)
+		}
+		if len(h) == s.limit {
+			h = append(h[:0], h[1:]...)
+		}
+		s.history[key] = append(h, record{Time: t, Result: res})
+	}
+	s.updated = t
+}
+
+// retain forget the services of the store whose key is not in keys, such
+// as those removed from the services file.
+func (s *store) retain(keys map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.keys[:0]
+	for _, key := range s.keys {
+		if keys[key] {
+			kept = append(kept, key)
+		} else {
+			delete(s.history, key)
+		}
+	}
+	s.keys = kept
+}
+
+// latest return the last record of every service and the time of the last
+// run.
+func (s *store) latest() ([]record, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	latest := make([]record, 0, len(s.keys))
+	for _, key := range s.keys {
+		h := s.history[key]
+		latest = append(latest, h[len(h)-1])
+	}
+	return latest, s.updated
+}
+
+// records return a copy of the records of the service key, oldest first.
+func (s *store) records(key string) []record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]record(nil), s.history[key]...)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	st := newStore(2)
+	t0 := time.Unix(0, 0)
+	for i := 0; i < 3; i++ {
+		st.add([]Result{
+			{Url: "https://a.com", Status: 200 + i},
+			{Name: "b", Url: "https://b.com", Err: errors.New("down")},
+		}, t0.Add(time.Duration(i)*time.Minute))
+	}
+
+	latest, updated := st.latest()
+	if len(latest) != 2 || latest[0].Url != "https://a.com" || latest[1].Name != "b" {
+		t.Fatalf("want a.com then b; got %+v", latest)
+	}
+	if latest[0].Status != 202 || !updated.Equal(t0.Add(2*time.Minute)) {
+		t.Errorf("want the last run; got status %d at %s", latest[0].Status, updated)
+	}
+
+	h := st.records("https://a.com")
+	if len(h) != 2 || h[0].Status != 201 || !h[0].Time.Equal(t0.Add(time.Minute)) {
+		t.Errorf("want the 2 most recent records; got %+v", h)
+	}
+	if len(st.records("b")) != 2 || st.records("https://b.com") != nil {
+		t.Error("want named services stored by name")
+	}
+}
+
+func TestStoreRetain(t *testing.T) {
+	st := newStore(2)
+	st.add([]Result{{Url: "https://a.com"}, {Name: "b", Url: "https://b.com"}, {Url: "https://c.com"}}, time.Unix(0, 0))
+	st.retain(map[string]bool{"https://a.com": true, "https://c.com": true})
+	latest, _ := st.latest()
+	if len(latest) != 2 || latest[0].Url != "https://a.com" || latest[1].Url != "https://c.com" || st.records("b") != nil {
+		t.Errorf("want b forgotten; got %+v", latest)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tally count up and down services, and services in maintenance. Services
+// down because of a dependency are counted apart from the down ones. Down
+// services are counted by severity too, once a service has one. Apdex,
+// when not nil, counts them by Apdex zone too.
+//
+// HealthScore, from 0 to 100, is the share of the services checked that
+// are up, each weighted by its severity, see severityWeights. Services down
+// because of a dependency count as down, being unavailable all the same.
+// It is nil until a service is checked.
+type tally struct {
+	Up             int            `json:"up"`
+	Down           int            `json:"down"`
+	DownBySeverity map[string]int `json:"down_by_severity,omitempty"`
+	DependencyDown int            `json:"dependency_down"`
+	Maintenance    int            `json:"maintenance"`
+	HealthScore    *float64       `json:"health_score,omitempty"`
+	Apdex          *apdex         `json:"apdex,omitempty"`
+
+	weightUp, weight float64
+}
+
+func (t *tally) add(res Result) {
+	if t.Apdex != nil {
+		t.Apdex.add(res)
+	}
+	if !res.Maintenance {
+		w := severityWeights[severityOf(res)]
+		t.weight += w
+		if res.Up() {
+			t.weightUp += w
+		}
+		score := 100 * t.weightUp / t.weight
+		t.HealthScore = &score
+	}
+	switch {
+	case res.Maintenance:
+		t.Maintenance++
+	case res.Up():
+		t.Up++
+	case res.DependencyDown():
+		t.DependencyDown++
+	default:
+		if res.Severity != "" && t.DownBySeverity == nil {
+			t.DownBySeverity = make(map[string]int)
+			if t.Down > 0 {
+				// Down services counted so far had no severity.
+				t.DownBySeverity[SeverityMajor] = t.Down
+			}
+		}
+		t.Down++
+		if t.DownBySeverity != nil {
+			t.DownBySeverity[severityOf(res)]++
+		}
+	}
+}
+
+func (t tally) String() string {
+	s := fmt.Sprintf("%d up; %d down", t.Up, t.Down)
+	if len(t.DownBySeverity) > 0 {
+		var counts []string
+		for _, severity := range severities {
+			if n := t.DownBySeverity[severity]; n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", n, severity))
+			}
+		}
+		s += " (" + strings.Join(counts, ", ") + ")"
+	}
+	if t.DependencyDown > 0 {
+		s += fmt.Sprintf("; %d dependency down", t.DependencyDown)
+	}
+	if t.Maintenance > 0 {
+		s += fmt.Sprintf("; %d maintenance", t.Maintenance)
+	}
+	if t.Apdex != nil {
+		s += "; " + t.Apdex.String()
+	}
+	return s
+}
+
+// summary aggregate results overall and per tag, and the latencies of
+// every result or sample that got an answer.
+type summary struct {
+	Total   tally
+	Tags    map[string]*tally
+	Latency histogram
+
+	// Excluded is the number of services not checked, see excludeServices.
+	Excluded int
+}
+
+// summarize aggregate results, computing Apdex scores for the threshold
+// apdexT unless zero.
+func summarize(results []Result, apdexT time.Duration) summary {
+	newTally := func() *tally {
+		t := new(tally)
+		if apdexT > 0 {
+			t.Apdex = &apdex{T: apdexT}
+		}
+		return t
+	}
+	s := summary{Total: *newTally(), Tags: make(map[string]*tally)}
+	for _, res := range results {
+		s.Total.add(res)
+		switch {
+		case res.Stats != nil:
+			for _, l := range res.Stats.Latencies {
+				s.Latency.record(l)
+			}
+		case res.Status != 0 || res.Up() && !res.Maintenance:
+			s.Latency.record(res.Latency)
+		}
+		for _, tag := range res.Tags {
+			t, ok := s.Tags[tag]
+			if !ok {
+				t = newTally()
+				s.Tags[tag] = t
+			}
+			t.add(res)
+		}
+	}
+	return s
+}
+
+// writeSummary print the summary, tags being sorted by name.
+func writeSummary(w io.Writer, s summary) {
+	fmt.Fprintf(w, "Summary: %s", s.Total)
+	if s.Excluded > 0 {
+		fmt.Fprintf(w, "; %d excluded", s.Excluded)
+	}
+	fmt.Fprintln(w)
+	if score := s.Total.HealthScore; score != nil {
+		fmt.Fprintf(w, "Health score: %s/100\n", strconv.FormatFloat(*score, 'f', 1, 64))
+	}
+
+	tags := make([]string, 0, len(s.Tags))
+	for tag := range s.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		fmt.Fprintf(w, "  #%s: %s\n", tag, s.Tags[tag])
+	}
+	if s.Latency.total > 0 {
+		writeHistogram(w, &s.Latency)
+	}
+}
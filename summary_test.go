@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	results := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 12 * time.Millisecond, Tags: []string{"payments", "prod"}},
+		{Url: "https://b.com", Status: 503, Latency: 40 * time.Millisecond, Err: &StatusError{Status: 503}, Tags: []string{"prod"}},
+		{Url: "https://c.com", Err: errors.New("timeout")},
+		{Url: "https://d.com", Maintenance: true, Tags: []string{"prod"}},
+		{Url: "https://e.com", Err: &DependencyError{Dependency: "https://c.com", Err: errors.New("timeout")}},
+	}
+
+	var b strings.Builder
+	writeSummary(&b, summarize(results, 0))
+
+	want := "Summary: 1 up; 2 down; 1 dependency down; 1 maintenance\n" +
+		"Health score: 25.0/100\n" +
+		"  #payments: 1 up; 0 down\n" +
+		"  #prod: 1 up; 1 down; 1 maintenance\n" +
+		"Latency: 2 samples; Min: 12ms; P50: 12.3ms; P90: 40ms; P99: 40ms; P99.9: 40ms; Max: 40ms\n" +
+		"    8.192ms - 16.384ms       1 ########################################\n" +
+		"   16.384ms - 32.768ms       0\n" +
+		"   32.768ms - 65.536ms       1 ########################################\n"
+	if got := b.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	results := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 12 * time.Millisecond, Tags: []string{"prod"}},
+		{Url: "https://b.com", Err: errors.New("timeout")},
+	}
+	var b strings.Builder
+	if err := writeJSONReport(&b, results, summarize(results, 0), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Results []struct{ URL, State string }
+		Summary struct {
+			Up, Down int
+			Tags     map[string]tally
+			Latency  jsonHistogram
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 || report.Results[1].State != "down" {
+		t.Errorf("results: got %+v", report.Results)
+	}
+	s := report.Summary
+	if s.Up != 1 || s.Down != 1 || s.Tags["prod"].Up != 1 {
+		t.Errorf("summary: got %+v", s)
+	}
+	if s.Latency.Samples != 1 || s.Latency.MinMS != 12 || len(s.Latency.Buckets) != 1 {
+		t.Errorf("latency: got %+v", s.Latency)
+	}
+}
+
+func TestApdex(t *testing.T) {
+	ms := time.Millisecond
+	results := []Result{
+		{Url: "https://a.com", Status: 200, Latency: 100 * ms, Tags: []string{"web"}},
+		{Url: "https://b.com", Status: 200, Latency: 500 * ms, Tags: []string{"web"}},
+		{Url: "https://c.com", Status: 200, Latency: 900 * ms, Tags: []string{"web"}},
+		{Url: "https://d.com", Status: 200, Latency: 3 * time.Second},
+		{Url: "https://e.com", Err: errors.New("timeout")},
+		{Url: "https://f.com", Maintenance: true, Tags: []string{"web"}},
+	}
+	s := summarize(results, 500*ms)
+	want := apdex{T: 500 * ms, Satisfied: 2, Tolerating: 1, Frustrated: 2}
+	if *s.Total.Apdex != want {
+		t.Errorf("want %+v; got %+v", want, *s.Total.Apdex)
+	}
+	if got := s.Total.Apdex.Score(); got != 0.5 {
+		t.Errorf("want a score of 0.5; got %v", got)
+	}
+	if got := s.Tags["web"].Apdex.Score(); got != 2.5/3 {
+		t.Errorf("#web: want a score of 0.83; got %v", got)
+	}
+
+	var b strings.Builder
+	writeSummary(&b, s)
+	if !strings.HasPrefix(b.String(), "Summary: 4 up; 1 down; 1 maintenance; Apdex(500ms): 0.50\nHealth score: 80.0/100\n  #web: 3 up; 0 down; 1 maintenance; Apdex(500ms): 0.83\n") {
+		t.Errorf("got:\n%s", b.String())
+	}
+}
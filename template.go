@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// TemplateVars are the variables lines of services files are rendered
+// against, see ParseServicesTemplate, each with its values.
+type TemplateVars map[string][]string
+
+// readVarsFile read the variables of the YAML or JSON file at path, a
+// mapping of names to a value or a list of values:
+//
+//	region: [eu-west-1, us-east-1]
+//	env: prod
+func readVarsFile(path string) (TemplateVars, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	vars := make(TemplateVars, len(raw))
+	for name, v := range raw {
+		values, ok := v.([]any)
+		if !ok {
+			values = []any{v}
+		}
+		for _, value := range values {
+			switch value.(type) {
+			case []any, map[string]any, nil:
+				return nil, fmt.Errorf("%s: %s: want a value or a list of values", path, name)
+			}
+			vars[name] = append(vars[name], fmt.Sprint(value))
+		}
+		if len(vars[name]) == 0 {
+			return nil, fmt.Errorf("%s: %s: no values", path, name)
+		}
+	}
+	return vars, nil
+}
+
+// combinations return every combination of the values of vars, ordered by
+// the names of the variables, or an error when there are more than
+// maxExpansion of them.
+func (vars TemplateVars) combinations() ([]map[string]string, error) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	combinations := []map[string]string{{}}
+	for _, name := range names {
+		if len(combinations)*len(vars[name]) > maxExpansion {
+			return nil, fmt.Errorf("variables have more than %d combinations", maxExpansion)
+		}
+		next := make([]map[string]string, 0, len(combinations)*len(vars[name]))
+		for _, c := range combinations {
+			for _, value := range vars[name] {
+				m := make(map[string]string, len(c)+1)
+				for k, v := range c {
+					m[k] = v
+				}
+				m[name] = value
+				next = append(next, m)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// renderLine return line rendered as a text/template against each
+// combination of the values of vars, such as
+// https://{{.region}}.api.a.com/health for each region. Lines rendering
+// identically, because they leave some variables out, are returned once.
+// Variables missing from vars are errors.
+func renderLine(line string, vars TemplateVars) ([]string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	combinations, err := vars.combinations()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		lines    []string
+		rendered = make(map[string]bool)
+		b        strings.Builder
+	)
+	for _, c := range combinations {
+		b.Reset()
+		if err := tmpl.Execute(&b, c); err != nil {
+			return nil, err
+		}
+		if !rendered[b.String()] {
+			rendered[b.String()] = true
+			lines = append(lines, b.String())
+		}
+	}
+	return lines, nil
+}
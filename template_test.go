@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestParseServicesTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("region: [eu, us]\nenv: [prod, staging]\nport: 8443\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := readVarsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := ParseServicesTemplate(strings.NewReader(""+
+		"https://{{.region}}.api.a.com/health #{{.region}}\n"+
+		"name=web-{{.env}}-{{.region}} url=https://{{.env}}.{{.region}}.a.com:{{.port}}\n"+
+		"https://a.com/{{.zone}}\n"+
+		"https://b.com graphql-query=\"{ health { ok } }\"\n",
+	), "services.txt", 0, vars)
+	var got []string
+	for _, svc := range services {
+		got = append(got, svc.Name+" "+svc.URL+" "+strings.Join(svc.Tags, ","))
+	}
+	want := []string{
+		" https://eu.api.a.com/health eu",
+		" https://us.api.a.com/health us",
+		"web-prod-eu https://prod.eu.a.com:8443 ",
+		"web-prod-us https://prod.us.a.com:8443 ",
+		"web-staging-eu https://staging.eu.a.com:8443 ",
+		"web-staging-us https://staging.us.a.com:8443 ",
+		" https://b.com ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got services %q; want %q", got, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "services.txt:3:") || !strings.Contains(err.Error(), `"zone"`) {
+		t.Errorf("want an error on the missing variable of line 3; got %v", err)
+	}
+
+	// Without variables, lines are parsed as is.
+	services, err = ParseServices(strings.NewReader("https://a.com graphql-query=\"{{ health }}\"\n"), "services.txt")
+	if err != nil || len(services) != 1 || services[0].GraphQL != "{{ health }}" {
+		t.Errorf("want the line parsed as is; got %+v, %v", services, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"region": [["eu"]]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readVarsFile(path); err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("want an error on nested lists; got %v", err)
+	}
+}
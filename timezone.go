@@ -0,0 +1,17 @@
+package main
+
+import (
+	"time"
+	// Embedded so that -timezone works on hosts and images without a time
+	// zone database.
+	_ "time/tzdata"
+)
+
+// startFormat is the RFC 3339 layout of the start of checks in text and
+// InfluxDB outputs, to the millisecond.
+const startFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// WithTimezone set the time zone of the Start of results, UTC by default.
+func WithTimezone(loc *time.Location) Option {
+	return func(c *checker) { c.location = loc }
+}
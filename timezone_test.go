@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultStart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 30, 0, 250e6, time.UTC)
+
+	c := newChecker(WithTimezone(tokyo))
+	c.now = func() time.Time { return now }
+	res := c.check(context.Background(), Service{URL: srv.URL})
+	if !res.Start.Equal(now) || res.Start.Location() != tokyo {
+		t.Fatalf("got start %s; want %s in Asia/Tokyo", res.Start, now)
+	}
+
+	var b bytes.Buffer
+	writeTextResult(&b, "", res)
+	if !strings.Contains(b.String(), "; Start: 2026-03-01T21:30:00.250+09:00;") {
+		t.Errorf("text: got %q", b.String())
+	}
+	j, err := json.Marshal(res)
+	if err != nil || !strings.Contains(string(j), `"start":"2026-03-01T21:30:00.25+09:00"`) {
+		t.Errorf("json: got %s, %v", j, err)
+	}
+	if line := influxLine(res, time.Time{}); !strings.HasSuffix(line, " 1772368200250000000\n") || strings.Contains(line, "start=") {
+		t.Errorf("influx: got %q", line)
+	}
+
+	composite, err := ParseService("name=web quorum=1 member=" + srv.URL + " member=" + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = newChecker().check(context.Background(), composite)
+	if res.Start.IsZero() || res.Start.Location() != time.UTC || !res.Members[1].Start.Equal(res.Start) {
+		t.Errorf("composite: got start %s, members %s and %s", res.Start, res.Members[0].Start, res.Members[1].Start)
+	}
+}
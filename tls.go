@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithMinTLS fail the checks of https services which negotiate a TLS
+// version below min, or still accept one, see checkMinTLS.
+func WithMinTLS(min uint16) Option {
+	return func(c *checker) { c.minTLS = min }
+}
+
+// tlsVersions map the versions accepted by -min-tls to their value.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion return the TLS version named like 1.2.
+func parseTLSVersion(s string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimPrefix(s, "TLS ")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q, want 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return v, nil
+}
+
+// TLSError report an https service negotiating or accepting a TLS version
+// below the minimum.
+type TLSError struct {
+	Version string
+	Min     string
+	// Accepted is set when the version was not negotiated by the check but
+	// accepted when offered alone.
+	Accepted bool
+}
+
+func (e *TLSError) Error() string {
+	verb := "negotiated"
+	if e.Accepted {
+		verb = "accepts"
+	}
+	return fmt.Sprintf("%s %s, below %s", verb, e.Version, e.Min)
+}
+
+// recordTLS record the negotiated TLS version and cipher suite of state in
+// result, with the expiry of the certificate of the server.
+func recordTLS(state *tls.ConnectionState, result *Result) {
+	if state == nil {
+		return
+	}
+	result.TLSVersion = tls.VersionName(state.Version)
+	result.TLSCipher = tls.CipherSuiteName(state.CipherSuite)
+	if len(state.PeerCertificates) > 0 {
+		result.CertExpiry = state.PeerCertificates[0].NotAfter
+	}
+}
+
+// checkMinTLS fail the result of svc when it negotiated a TLS version below
+// c.minTLS or, when the check succeeded, if a request offering only the
+// versions below it succeeds too. The request goes through the transport
+// of svc, with its proxy and server name.
+func (c *checker) checkMinTLS(ctx context.Context, svc Service, result Result) Result {
+	if c.minTLS == 0 || result.TLSVersion == "" || result.Err != nil {
+		return result
+	}
+	min := tls.VersionName(c.minTLS)
+	if negotiated, _ := parseTLSVersion(result.TLSVersion); negotiated < c.minTLS {
+		result.Err = &TLSError{Version: result.TLSVersion, Min: min}
+		return result
+	}
+	if c.minTLS == tls.VersionTLS10 {
+		return result
+	}
+
+	t := c.sharedClient(svc).Transport.(*http.Transport).Clone()
+	t.DisableKeepAlives = true
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = new(tls.Config)
+	}
+	t.TLSClientConfig.MinVersion, t.TLSClientConfig.MaxVersion = tls.VersionTLS10, c.minTLS-1
+	// Only TLS 1.2 and below can be offered, HTTP/2 requires TLS 1.2.
+	t.ForceAttemptHTTP2 = false
+	t.TLSClientConfig.NextProtos = nil
+
+	ctx, cancel := c.withTimeout(ctx, svc)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, svc.URL, nil)
+	if err != nil {
+		return result
+	}
+	c.setHeaders(req, svc)
+	client := &http.Client{
+		Transport:     t,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return result
+	}
+	resp.Body.Close()
+	if resp.TLS != nil {
+		result.Err = &TLSError{Version: tls.VersionName(resp.TLS.Version), Min: min, Accepted: true}
+	}
+	return result
+}
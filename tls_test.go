@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMinTLS(t *testing.T) {
+	tlsServer := func(min, max uint16) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		srv.TLS = &tls.Config{MinVersion: min, MaxVersion: max}
+		srv.StartTLS()
+		return srv
+	}
+	tests := []struct {
+		name     string
+		min, max uint16
+		version  string
+		err      *TLSError
+	}{
+		{name: "modern", min: tls.VersionTLS12, max: tls.VersionTLS13, version: "TLS 1.3"},
+		{
+			name: "negotiates weak", min: tls.VersionTLS10, max: tls.VersionTLS11, version: "TLS 1.1",
+			err: &TLSError{Version: "TLS 1.1", Min: "TLS 1.2"},
+		},
+		{
+			name: "accepts weak", min: tls.VersionTLS10, max: tls.VersionTLS13, version: "TLS 1.3",
+			err: &TLSError{Version: "TLS 1.1", Min: "TLS 1.2", Accepted: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tlsServer(tt.min, tt.max)
+			defer srv.Close()
+
+			c := newChecker(WithRetries(0), WithMinTLS(tls.VersionTLS12))
+			c.transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10}
+			res := c.checkURL(context.Background(), Service{URL: srv.URL})
+			if res.TLSVersion != tt.version || res.TLSCipher == "" {
+				t.Errorf("got %s %s; want %s", res.TLSVersion, res.TLSCipher, tt.version)
+			}
+			var terr *TLSError
+			switch {
+			case tt.err == nil && res.Err != nil:
+				t.Errorf("want up; got %v", res.Err)
+			case tt.err != nil && (!errors.As(res.Err, &terr) || *terr != *tt.err):
+				t.Errorf("want %v; got %v", tt.err, res.Err)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	if v, err := parseTLSVersion("1.2"); err != nil || v != tls.VersionTLS12 {
+		t.Errorf("1.2: got %#x, %v", v, err)
+	}
+	if _, err := parseTLSVersion("1.4"); err == nil {
+		t.Error("1.4: want an error")
+	}
+}
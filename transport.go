@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// WithKeepAlive enable or disable HTTP keep-alive. Without keep-alive every
+// request opens a new connection, closed once the response is read.
+func WithKeepAlive(enabled bool) Option {
+	return func(c *checker) { c.keepAlive = enabled }
+}
+
+// WithFreshConnections make every check open its own connections instead of
+// reusing those of previous checks, so that latencies include connection
+// setup. Requests of a single check, such as redirects or scenario steps,
+// still share connections.
+func WithFreshConnections(enabled bool) Option {
+	return func(c *checker) { c.freshConns = enabled }
+}
+
+// newTransport build the transport shared by the checks from the settings
+// of c.
+func newTransport(c *checker) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return c.dialContext(ctx, newDialer(c), network, addr)
+	}
+	t.DisableKeepAlives = !c.keepAlive
+	// Checks of many services on the same host would otherwise open and
+	// close connections beyond the default of 2 idle ones.
+	t.MaxIdleConnsPerHost = max(c.workers, 2)
+	return t
+}
+
+// proxyDirect is the proxy= value of services reached without proxy.
+const proxyDirect = "direct"
+
+// parseProxy check the value of a proxy= option: the url of an http, https
+// or socks5 proxy, or direct.
+func parseProxy(value string) error {
+	if value == proxyDirect {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q, want http, https, socks5 or %s", u.Scheme, proxyDirect)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy %q has no host", value)
+	}
+	return nil
+}
+
+// transportKey are the settings of a service requiring a transport of its
+// own: a TLS server name and a proxy.
+type transportKey struct {
+	serverName string
+	proxy      string
+}
+
+// sharedClient return the client whose connections are shared by the
+// checks of svc: the client of c unless svc needs its own TLS server name
+// or proxy.
+func (c *checker) sharedClient(svc Service) *http.Client {
+	key := transportKey{serverName: c.serverNameOf(svc), proxy: svc.Proxy}
+	if key == (transportKey{}) {
+		return c.client
+	}
+	client := *c.client
+	client.Transport = c.keyedTransport(key)
+	return &client
+}
+
+// keyedTransport return the transport shared by the services with the
+// settings of key, creating it on first use. The proxy of key replaces
+// that of the environment.
+func (c *checker) keyedTransport(key transportKey) *http.Transport {
+	if t, ok := c.transports.Load(key); ok {
+		return t.(*http.Transport)
+	}
+	t := c.transport.Clone()
+	if key.serverName != "" {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = new(tls.Config)
+		}
+		t.TLSClientConfig.ServerName = key.serverName
+	}
+	switch key.proxy {
+	case "":
+	case proxyDirect:
+		t.Proxy = nil
+	default:
+		// Checked by parseProxy.
+		u, _ := url.Parse(key.proxy)
+		t.Proxy = http.ProxyURL(u)
+	}
+	actual, _ := c.transports.LoadOrStore(key, t)
+	return actual.(*http.Transport)
+}
+
+// httpClient return the client of a check of svc and a function releasing
+// it once the check is over.
+// Requests are recorded in the HAR of c, if any.
+func (c *checker) httpClient(svc Service) (*http.Client, func()) {
+	client := *c.sharedClient(svc)
+	release := func() {}
+	if c.freshConns {
+		t := client.Transport.(*http.Transport).Clone()
+		client.Transport = t
+		release = t.CloseIdleConnections
+	}
+	if c.har != nil {
+		client.Transport = &harTransport{next: client.Transport, har: c.har, service: svc.key()}
+	}
+	return &client, release
+}
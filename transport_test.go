@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		conns int32
+	}{
+		{"default", nil, 1},
+		{"no keep-alive", []Option{WithKeepAlive(false)}, 3},
+		{"fresh connections", []Option{WithFreshConnections(true)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns.Store(0)
+			c := newChecker(tt.opts...)
+			defer c.transport.CloseIdleConnections()
+			for i := 0; i < 3; i++ {
+				if res := c.checkURL(context.Background(), Service{URL: srv.URL}); !res.Up() {
+					t.Fatal(res.Err)
+				}
+			}
+			if got := conns.Load(); got != tt.conns {
+				t.Errorf("want %d connections; got %d", tt.conns, got)
+			}
+		})
+	}
+}
+
+func TestProxyOverride(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests through a proxy carry the absolute url of the target.
+		if r.URL.Host == "internal.example:8080" {
+			proxied.Add(1)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer direct.Close()
+
+	c := newChecker(WithRetries(0))
+	via, err := ParseService("http://internal.example:8080/healthz proxy=" + proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := c.checkURL(context.Background(), via); !res.Up() {
+		t.Errorf("via proxy: want up; got %d %v", res.Status, res.Err)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("want the request sent to the proxy; got %d", proxied.Load())
+	}
+
+	svc, err := ParseService(direct.URL + " proxy=direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := c.checkURL(context.Background(), svc); !res.Up() {
+		t.Errorf("direct: want up; got %d %v", res.Status, res.Err)
+	}
+	if tr := c.sharedClient(svc).Transport.(*http.Transport); tr.Proxy != nil {
+		t.Error("direct: want no proxy")
+	}
+
+	for _, line := range []string{"https://a.com proxy=ftp://p.a.com", "https://a.com proxy=http://"} {
+		if _, err := ParseService(line); err == nil {
+			t.Errorf("%s: want error", line)
+		}
+	}
+}
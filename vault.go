@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultPrefix start the header values read from Vault.
+const vaultPrefix = "vault:"
+
+// vaultRefPattern match the ${vault:path#key} references embedded in header
+// values.
+var vaultRefPattern = regexp.MustCompile(`\$\{vault:([^}]+)\}`)
+
+// vaultOptions are the flags of the Vault secrets of header values.
+type vaultOptions struct {
+	addr  string
+	token string
+}
+
+func (o *vaultOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "vault-addr", os.Getenv("VAULT_ADDR"), "Vault address header values of the form vault:path#key are read from")
+	fs.StringVar(&o.token, "vault-token", os.Getenv("VAULT_TOKEN"), "Vault token, defaulting to VAULT_TOKEN then ~/.vault-token")
+}
+
+// client return the Vault client of the options, nil without address.
+func (o *vaultOptions) client() *vaultClient {
+	if o.addr == "" {
+		return nil
+	}
+	token := o.token
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	return &vaultClient{
+		addr:    strings.TrimSuffix(o.addr, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		secrets: make(map[string]map[string]any),
+	}
+}
+
+// vaultClient read secrets from Vault, caching them by path.
+type vaultClient struct {
+	addr   string
+	token  string
+	client *http.Client
+
+	mu      sync.Mutex
+	secrets map[string]map[string]any
+}
+
+// read return the key of the secret at path, of a KV version 1 or 2
+// engine: secret/data/api#token reads the token key of the api secret of a
+// KV 2 engine mounted on secret.
+func (v *vaultClient) read(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference %q: want path#key", ref)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	secret, ok := v.secrets[path]
+	if !ok {
+		var err error
+		if secret, err = v.fetch(ctx, path); err != nil {
+			return "", fmt.Errorf("vault %s: %w", path, err)
+		}
+		v.secrets[path] = secret
+	}
+	value, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("vault %s: no key %q", path, key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault %s: key %q is not a string", path, key)
+	}
+	return s, nil
+}
+
+func (v *vaultClient) fetch(ctx context.Context, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	// KV 2 engines nest the secret in data with its metadata.
+	if data, ok := body.Data["data"].(map[string]any); ok {
+		if _, versioned := body.Data["metadata"]; versioned {
+			return data, nil
+		}
+	}
+	return body.Data, nil
+}
+
+// resolve return s with its Vault references replaced by their secrets: s
+// is either a whole vault:path#key reference or a value embedding
+// ${vault:path#key} references.
+func (v *vaultClient) resolve(ctx context.Context, s string) (string, error) {
+	if !strings.HasPrefix(s, vaultPrefix) && !vaultRefPattern.MatchString(s) {
+		return s, nil
+	}
+	if v == nil {
+		return "", errors.New("vault reference without -vault-addr")
+	}
+	if ref, ok := strings.CutPrefix(s, vaultPrefix); ok {
+		return v.read(ctx, ref)
+	}
+	var err error
+	resolved := vaultRefPattern.ReplaceAllStringFunc(s, func(m string) string {
+		value, rerr := v.read(ctx, vaultRefPattern.FindStringSubmatch(m)[1])
+		if rerr != nil && err == nil {
+			err = rerr
+		}
+		return value
+	})
+	return resolved, err
+}
+
+// resolveSecrets replace the Vault references of the header values of
+// services, and of the steps of their scenarios, by their secrets.
+func resolveSecrets(ctx context.Context, services []Service, v *vaultClient) error {
+	var errs []error
+	for i := range services {
+		svc := &services[i]
+		for name, values := range svc.Header {
+			for j, value := range values {
+				resolved, err := v.resolve(ctx, value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: header %s: %w", svc.key(), name, err))
+				}
+				values[j] = resolved
+			}
+		}
+		if svc.Scenario == nil {
+			continue
+		}
+		for _, step := range svc.Scenario.Steps {
+			for name, value := range step.Header {
+				resolved, err := v.resolve(ctx, value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: step %s: header %s: %w", svc.key(), step.Name, name, err))
+				}
+				step.Header[name] = resolved
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	var fetches atomic.Int32
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.Header.Get("X-Vault-Token") != "s.root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/api":
+			w.Write([]byte(`{"data": {"data": {"token": "t0k3n"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/legacy":
+			w.Write([]byte(`{"data": {"key": "k3y"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	var services []Service
+	for _, line := range []string{
+		`https://a.com header="Authorization: Bearer ${vault:secret/data/api#token}"`,
+		`https://b.com header=X-Key:vault:kv/legacy#key header=X-Token:vault:secret/data/api#token`,
+	} {
+		svc, err := ParseService(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		services = append(services, svc)
+	}
+	opts := vaultOptions{addr: vault.URL, token: "s.root"}
+	if err := resolveSecrets(context.Background(), services, opts.client()); err != nil {
+		t.Fatal(err)
+	}
+	if got := services[0].Header.Get("Authorization"); got != "Bearer t0k3n" {
+		t.Errorf("embedded reference: got %q", got)
+	}
+	if got := services[1].Header.Get("X-Key"); got != "k3y" {
+		t.Errorf("kv 1 reference: got %q", got)
+	}
+	if got := services[1].Header.Get("X-Token"); got != "t0k3n" {
+		t.Errorf("kv 2 reference: got %q", got)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("want secrets read once per path; got %d reads", n)
+	}
+
+	for _, svc := range []Service{
+		{URL: "https://c.com", Header: http.Header{"X-Key": {"vault:kv/missing#key"}}},
+		{URL: "https://c.com", Header: http.Header{"X-Key": {"vault:kv/legacy#other"}}},
+		{URL: "https://c.com", Header: http.Header{"X-Key": {"vault:kv/legacy"}}},
+	} {
+		if err := resolveSecrets(context.Background(), []Service{svc}, opts.client()); err == nil {
+			t.Errorf("%s: want error", svc.Header.Get("X-Key"))
+		}
+	}
+	svc := Service{URL: "https://c.com", Header: http.Header{"X-Key": {"vault:kv/legacy#key"}}}
+	if err := resolveSecrets(context.Background(), []Service{svc}, nil); err == nil {
+		t.Error("want error without vault address")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+// WithHostHeader set the Host header sent with checks, so that a virtual
+// host can be checked through the address of a load balancer, e.g. before
+// DNS changes. A host-header= option of a service takes precedence.
+func WithHostHeader(host string) Option {
+	return func(c *checker) { c.hostHeader = host }
+}
+
+// WithSNI set the server name sent in the TLS handshake and expected in
+// the certificate. It defaults to the Host header when one is set. A sni=
+// option of a service takes precedence.
+func WithSNI(name string) Option {
+	return func(c *checker) { c.sni = name }
+}
+
+// hostHeaderOf return the Host header sent with the requests of svc, empty
+// for that of the url.
+func (c *checker) hostHeaderOf(svc Service) string {
+	if svc.HostHeader != "" {
+		return svc.HostHeader
+	}
+	return c.hostHeader
+}
+
+// serverNameOf return the TLS server name used for svc, empty for the host
+// of the url.
+func (c *checker) serverNameOf(svc Service) string {
+	switch {
+	case svc.SNI != "":
+		return svc.SNI
+	case c.sni != "":
+		return c.sni
+	}
+	return c.hostHeaderOf(svc)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostHeaderAndSNI(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "www.a.com" || r.TLS.ServerName != "www.a.com" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+		}
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		svc  Service
+		up   bool
+	}{
+		{"none", nil, Service{URL: srv.URL}, false},
+		{"host header implies sni", []Option{WithHostHeader("www.a.com")}, Service{URL: srv.URL}, true},
+		{"service", nil, Service{URL: srv.URL, HostHeader: "www.a.com"}, true},
+		{"service sni", []Option{WithHostHeader("www.a.com")}, Service{URL: srv.URL, SNI: "b.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChecker(append(tt.opts, WithFreshConnections(true))...)
+			// Trust the test certificate whatever the server name.
+			c.transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+			res := c.checkURL(context.Background(), tt.svc)
+			if res.Up() != tt.up {
+				t.Errorf("want up %t; got %d %v", tt.up, res.Status, res.Err)
+			}
+		})
+	}
+}
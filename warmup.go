@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// maxWarmupBody bound how much of the warmup response is drained so that its
+// connection can be reused by the measured request.
+const maxWarmupBody = 1 << 20
+
+// WithWarmup make an untimed request to each host before its first measured
+// one, so that DNS resolution and connection setup do not skew latencies.
+func WithWarmup(enabled bool) Option {
+	return func(c *checker) { c.warmup = enabled }
+}
+
+// warmups remember the hosts already warmed up during a run.
+type warmups struct {
+	hosts sync.Map // host -> *sync.Once
+}
+
+// warm send a single untimed request per host, concurrent callers for the
+// same host waiting for it to complete. Its outcome is ignored.
+func (c *checker) warm(ctx context.Context, svc Service) {
+	u, err := url.Parse(svc.URL)
+	if err != nil {
+		return
+	}
+	once, _ := c.warmed.hosts.LoadOrStore(u.Scheme+"://"+u.Host, new(sync.Once))
+	once.(*sync.Once).Do(func() {
+		_, span := tracer.Start(ctx, "warmup")
+		defer span.End()
+
+		ctx, cancel := c.withTimeout(ctx, svc)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, svc.URL, nil)
+		if err != nil {
+			return
+		}
+		c.setHeaders(req, svc)
+		resp, err := c.sharedClient(svc).Do(req)
+		if err != nil {
+			return
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxWarmupBody))
+		resp.Body.Close()
+	})
+}
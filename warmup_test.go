@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWarmup(t *testing.T) {
+	var requests, conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("ok"))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c := newChecker(WithWarmup(true))
+	c.client = srv.Client()
+	services := []Service{{URL: srv.URL + "/a"}, {URL: srv.URL + "/b"}}
+	for _, svc := range services {
+		if res := c.checkURL(context.Background(), svc); !res.Up() {
+			t.Fatalf("want %s up; got %v", svc.URL, res.Err)
+		}
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("want a single warmup request for the host; got %d requests", requests.Load())
+	}
+	if conns.Load() != 1 {
+		t.Errorf("want the warmup connection reused; got %d connections", conns.Load())
+	}
+}